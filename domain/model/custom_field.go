@@ -0,0 +1,53 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type CustomFieldType string
+
+const (
+	CustomFieldText   CustomFieldType = "text"
+	CustomFieldNumber CustomFieldType = "number"
+	CustomFieldDate   CustomFieldType = "date"
+	CustomFieldEnum   CustomFieldType = "enum"
+	CustomFieldBool   CustomFieldType = "bool"
+)
+
+func ToCustomFieldType(v string) (CustomFieldType, error) {
+	lv := strings.ToLower(v)
+	switch lv {
+	case "text":
+		return CustomFieldText, nil
+	case "number":
+		return CustomFieldNumber, nil
+	case "date":
+		return CustomFieldDate, nil
+	case "enum":
+		return CustomFieldEnum, nil
+	case "bool":
+		return CustomFieldBool, nil
+	default:
+		return "", fmt.Errorf("type must be text, number, date, enum or bool, but %s", v)
+	}
+}
+
+// CustomField is the definition of an extra attribute of todos, defined by a user.
+type CustomField struct {
+	ID        int             `gorm:"primaryKey"`
+	UserID    string          `gorm:"not null"`
+	Name      string          `gorm:"not null"`
+	Type      CustomFieldType `gorm:"not null"`
+	Options   []string        `gorm:"serializer:json"` // choices of enum
+	CreatedAt time.Time       `gorm:"not null"`
+}
+
+func (CustomField) TableName() string {
+	return "custom_fields"
+}
+
+// CustomFieldValues is the values of custom fields of a todo, keyed by the field name.
+// Values are float64 for number, bool for bool, and string for the others (date is formatted as 2006-01-02).
+type CustomFieldValues map[string]interface{}
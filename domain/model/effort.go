@@ -0,0 +1,52 @@
+package model
+
+// Effort is the total of estimates of todos.
+type Effort struct {
+	Count           int
+	EstimatePoints  int
+	EstimateMinutes int
+}
+
+func (e *Effort) Add(other Effort) {
+	e.Count += other.Count
+	e.EstimatePoints += other.EstimatePoints
+	e.EstimateMinutes += other.EstimateMinutes
+}
+
+// EffortSummary is the effort of todos grouped by status and priority.
+type EffortSummary struct {
+	Status   Status
+	Priority Priority
+	Effort
+}
+
+// EffortReport is the effort of todos of a user, aggregated for planning.
+type EffortReport struct {
+	ByStatus   map[Status]Effort
+	ByPriority map[Priority]Effort
+	Remaining  Effort // todos not done yet
+	Completed  Effort // done todos
+}
+
+func NewEffortReport(summaries []*EffortSummary) *EffortReport {
+	ret := &EffortReport{
+		ByStatus:   make(map[Status]Effort),
+		ByPriority: make(map[Priority]Effort),
+	}
+	for _, s := range summaries {
+		byStatus := ret.ByStatus[s.Status]
+		byStatus.Add(s.Effort)
+		ret.ByStatus[s.Status] = byStatus
+
+		byPriority := ret.ByPriority[s.Priority]
+		byPriority.Add(s.Effort)
+		ret.ByPriority[s.Priority] = byPriority
+
+		if s.Status == StatusDone {
+			ret.Completed.Add(s.Effort)
+		} else {
+			ret.Remaining.Add(s.Effort)
+		}
+	}
+	return ret
+}
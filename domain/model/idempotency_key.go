@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// IdempotencyKey is a request with Idempotency-Key header, whose response is replayed on retries with the same key.
+type IdempotencyKey struct {
+	UserID      string `gorm:"primaryKey"`
+	Key         string `gorm:"primaryKey"`
+	RequestHash string `gorm:"not null"` // hash of the request, to find the key reused for another request
+	StatusCode  int    `gorm:"not null"` // status of the response, 0 while the request is processed
+	Body        []byte // body of the response
+	// Header is the headers of the response replayed with the body, such as ETag.
+	Header    map[string][]string `gorm:"serializer:json"`
+	CreatedAt time.Time           `gorm:"not null"`
+	ExpiresAt time.Time           `gorm:"not null"`
+}
+
+// Completed reports whether the response of the request is saved.
+func (k IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
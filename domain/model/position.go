@@ -0,0 +1,79 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// positionDigits are the digits of the base-36 fractions used as manual positions.
+// Positions are compared lexicographically, so a todo can be moved between two
+// others by generating a new position, without renumbering the rest.
+const positionDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+type Placement int
+
+const (
+	PlaceBefore Placement = iota + 1
+	PlaceAfter
+)
+
+// PositionBetween returns a position that sorts strictly between prev and next.
+// An empty prev or next means that side is unbounded.
+func PositionBetween(prev, next string) (string, error) {
+	if next == "" {
+		return positionAfter(prev), nil
+	}
+	if prev >= next {
+		return "", fmt.Errorf("position %q must be less than %q", prev, next)
+	}
+	return positionMidpoint(prev, next)
+}
+
+// positionAfter increments the first digit that can be incremented, which keeps
+// positions short when todos are appended one after another.
+func positionAfter(prev string) string {
+	for i := 0; i < len(prev); i++ {
+		if d := positionDigit(prev, i); d < len(positionDigits)-1 {
+			return prev[:i] + string(positionDigits[d+1])
+		}
+	}
+	return prev + string(positionDigits[1])
+}
+
+func positionMidpoint(prev, next string) (string, error) {
+	ret := make([]byte, 0, len(next)+1)
+	bounded := true
+	for i := 0; ; i++ {
+		lo := positionDigit(prev, i)
+		hi := len(positionDigits)
+		if bounded {
+			if i >= len(prev) && i >= len(next) {
+				return "", fmt.Errorf("no position between %q and %q", prev, next)
+			}
+			hi = positionDigit(next, i)
+		}
+
+		switch {
+		case hi-lo > 1:
+			return string(append(ret, positionDigits[(lo+hi)/2])), nil
+		case hi-lo == 1:
+			// anything starting with the lower digit is less than next
+			ret = append(ret, positionDigits[lo])
+			bounded = false
+		case hi == lo:
+			ret = append(ret, positionDigits[lo])
+		default:
+			return "", fmt.Errorf("position %q must be less than %q", prev, next)
+		}
+	}
+}
+
+func positionDigit(position string, i int) int {
+	if i >= len(position) {
+		return 0
+	}
+	if d := strings.IndexByte(positionDigits, position[i]); d >= 0 {
+		return d
+	}
+	return 0
+}
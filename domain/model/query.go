@@ -0,0 +1,114 @@
+package model
+
+import "time"
+
+// Query is a node of the abstract syntax tree of a todo search query, such as
+// `status:ready,doing -priority:low "release notes"`.
+type Query interface {
+	isQuery()
+}
+
+// AndQuery matches todos matching all of the operands.
+type AndQuery struct {
+	Operands []Query
+}
+
+// OrQuery matches todos matching any of the operands.
+type OrQuery struct {
+	Operands []Query
+}
+
+// NotQuery matches todos not matching the operand.
+type NotQuery struct {
+	Operand Query
+}
+
+// StatusQuery matches todos in any of the statuses.
+type StatusQuery struct {
+	Statuses []Status
+}
+
+// PriorityQuery matches todos in any of the priorities.
+type PriorityQuery struct {
+	Priorities []Priority
+}
+
+// AssigneeQuery matches todos assigned to the user. Nil AssigneeID matches todos assigned to nobody.
+type AssigneeQuery struct {
+	AssigneeID *string
+}
+
+// TextQuery matches todos which contain the text ignoring case in any of the fields.
+type TextQuery struct {
+	Fields []QueryField
+	Text   string
+}
+
+// TimeQuery matches todos whose time field satisfies the comparison with Time.
+type TimeQuery struct {
+	Field      QueryField
+	Comparison Comparison
+	Time       time.Time
+}
+
+func (AndQuery) isQuery()      {}
+func (OrQuery) isQuery()       {}
+func (NotQuery) isQuery()      {}
+func (StatusQuery) isQuery()   {}
+func (PriorityQuery) isQuery() {}
+func (AssigneeQuery) isQuery() {}
+func (TextQuery) isQuery()     {}
+func (TimeQuery) isQuery()     {}
+
+// QueryField is a field of todos compared in a query. The value is the column name.
+type QueryField string
+
+const (
+	QueryFieldTitle       QueryField = "title"
+	QueryFieldDescription QueryField = "description"
+	QueryFieldCreatedAt   QueryField = "created_at"
+	QueryFieldUpdatedAt   QueryField = "updated_at"
+)
+
+// Text returns the value of the text field of the todo.
+func (f QueryField) Text(t Todo) string {
+	if f == QueryFieldDescription {
+		return t.Description
+	}
+	return t.Title
+}
+
+// Time returns the value of the time field of the todo.
+func (f QueryField) Time(t Todo) time.Time {
+	if f == QueryFieldUpdatedAt {
+		return t.UpdatedAt
+	}
+	return t.CreatedAt
+}
+
+// Comparison is an operator comparing a value with the operand. The value is the SQL operator.
+type Comparison string
+
+const (
+	ComparisonEQ Comparison = "="
+	ComparisonLT Comparison = "<"
+	ComparisonLE Comparison = "<="
+	ComparisonGT Comparison = ">"
+	ComparisonGE Comparison = ">="
+)
+
+// Compare reports whether t satisfies the comparison with the operand.
+func (c Comparison) Compare(t, operand time.Time) bool {
+	switch c {
+	case ComparisonLT:
+		return t.Before(operand)
+	case ComparisonLE:
+		return !t.After(operand)
+	case ComparisonGT:
+		return t.After(operand)
+	case ComparisonGE:
+		return !t.Before(operand)
+	default:
+		return t.Equal(operand)
+	}
+}
@@ -0,0 +1,11 @@
+package model
+
+// SearchHit is a todo found by full-text search.
+type SearchHit struct {
+	Todo  *Todo
+	Score float64 // relevance to the search terms, higher is more relevant
+	// Title is HTML escaped title whose matches are enclosed in <mark>.
+	Title string
+	// Snippet is HTML escaped part of description around the first match, whose matches are enclosed in <mark>.
+	Snippet string
+}
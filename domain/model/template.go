@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Template is a set of todos to be created repeatedly, such as the steps of a release process.
+// Title and description of the items can contain placeholders like `{{name}}`.
+type Template struct {
+	ID        int            `gorm:"primaryKey"`
+	UserID    string         `gorm:"not null"`
+	Name      string         `gorm:"not null"`
+	Items     []TemplateItem `gorm:"serializer:json;not null"`
+	CreatedAt time.Time      `gorm:"not null"`
+	UpdatedAt time.Time      `gorm:"not null"`
+}
+
+func (Template) TableName() string {
+	return "templates"
+}
+
+// TemplateItem is a todo to be created from a template.
+type TemplateItem struct {
+	Title           string   `json:"title"`
+	Description     string   `json:"description"`
+	Status          Status   `json:"status"`
+	Priority        Priority `json:"priority"`
+	EstimatePoints  *int     `json:"estimatePoints,omitempty"`
+	EstimateMinutes *int     `json:"estimateMinutes,omitempty"`
+}
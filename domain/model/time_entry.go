@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+type TimeEntry struct {
+	ID        int        `gorm:"primaryKey"`
+	UserID    string     `gorm:"not null"`
+	TodoID    int        `gorm:"not null"`
+	StartedAt time.Time  `gorm:"not null"`
+	StoppedAt *time.Time // nil while the timer is running
+	CreatedAt time.Time  `gorm:"not null"`
+	UpdatedAt time.Time  `gorm:"not null"`
+}
+
+func (TimeEntry) TableName() string {
+	return "time_entries"
+}
+
+func (e *TimeEntry) Running() bool {
+	return e.StoppedAt == nil
+}
+
+// Duration returns the tracked time. A running entry is counted up to now.
+func (e *TimeEntry) Duration(now time.Time) time.Duration {
+	if e.StoppedAt != nil {
+		return e.StoppedAt.Sub(e.StartedAt)
+	}
+	return now.Sub(e.StartedAt)
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -35,6 +36,11 @@ func (s Status) GoString() string {
 	return s.String()
 }
 
+// Name returns the name of the status in the API, such as `not-ready`.
+func (s Status) Name() string {
+	return strings.ReplaceAll(strings.ToLower(s.String()), " ", "-")
+}
+
 func ToStatus(v int) Status {
 	switch v {
 	case 1:
@@ -50,6 +56,20 @@ func ToStatus(v int) Status {
 	}
 }
 
+// ParseStatus returns the status of the number or the name, ignoring case, spaces, hyphens and underscores,
+// such as `2`, `ready`, `not-ready` or `Not Ready`. StatusUnknown is returned for anything else.
+func ParseStatus(s string) Status {
+	if n, err := strconv.Atoi(s); err == nil {
+		return ToStatus(n)
+	}
+	for status := StatusNotReady; status <= StatusDone; status++ {
+		if normalizeEnumName(s) == normalizeEnumName(status.String()) {
+			return status
+		}
+	}
+	return StatusUnknown
+}
+
 type Priority int
 
 const (
@@ -76,6 +96,11 @@ func (p Priority) GoString() string {
 	return p.String()
 }
 
+// Name returns the name of the priority in the API, such as `high`.
+func (p Priority) Name() string {
+	return strings.ToLower(p.String())
+}
+
 func ToPriority(v int) Priority {
 	switch v {
 	case 1:
@@ -89,16 +114,49 @@ func ToPriority(v int) Priority {
 	}
 }
 
+// ParsePriority returns the priority of the number or the name ignoring case, such as `1` or `high`.
+// PriorityUnknown is returned for anything else.
+func ParsePriority(s string) Priority {
+	if n, err := strconv.Atoi(s); err == nil {
+		return ToPriority(n)
+	}
+	for priority := PriorityHigh; priority <= PriorityLow; priority++ {
+		if normalizeEnumName(s) == normalizeEnumName(priority.String()) {
+			return priority
+		}
+	}
+	return PriorityUnknown
+}
+
+// normalizeEnumName removes the differences of names which are regarded as the same, such as `Not Ready`,
+// `not-ready` and `NOT_READY`.
+func normalizeEnumName(s string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(s))
+}
+
 type Todo struct {
-	ID          int    `gorm:"primaryKey"`
-	UserID      string `gorm:"not null"`
-	Title       string `gorm:"not null"`
-	Description string
-	Status      Status    `gorm:"not null"`
-	Priority    Priority  `gorm:"not null"`
-	CreatedAt   time.Time `gorm:"not null"`
-	UpdatedAt   time.Time `gorm:"not null"`
-	User        *User
+	ID              int    `gorm:"primaryKey"`
+	UserID          string `gorm:"not null"`
+	Title           string `gorm:"not null"`
+	Description     string
+	Status          Status            `gorm:"not null"`
+	Priority        Priority          `gorm:"not null"`
+	Position        string            `gorm:"not null"`
+	AssigneeID      *string           // nil if nobody is assigned
+	CustomFields    CustomFieldValues `gorm:"serializer:json;not null"`
+	EstimatePoints  *int              // nil if the todo isn't estimated
+	EstimateMinutes *int              // nil if the todo isn't estimated
+	StartAt         *time.Time        // the todo is hidden until then, nil if it isn't snoozed
+	Version         int               `gorm:"not null"` // incremented whenever the todo is changed, starting from 1
+	CreatedAt       time.Time         `gorm:"not null"`
+	UpdatedAt       time.Time         `gorm:"not null"`
+	User            *User
+	TrackedTime     time.Duration `gorm:"-"` // total of time entries, filled by usecase
+}
+
+// Snoozed reports whether the todo is hidden at the time.
+func (t Todo) Snoozed(now time.Time) bool {
+	return t.StartAt != nil && t.StartAt.After(now)
 }
 
 func (Todo) TableName() string {
@@ -108,19 +166,99 @@ func (Todo) TableName() string {
 type Sorter string
 
 const (
-	SortByID       Sorter = "id"
-	SortByPriority Sorter = "priority"
+	SortByID        Sorter = "id"
+	SortByPriority  Sorter = "priority"
+	SortByManual    Sorter = "manual"
+	SortByStatus    Sorter = "status"
+	SortByTitle     Sorter = "title"
+	SortByCreatedAt Sorter = "created_at"
+	SortByUpdatedAt Sorter = "updated_at"
+
+	SortByEstimatePoints  Sorter = "estimate_points"
+	SortByEstimateMinutes Sorter = "estimate_minutes"
 )
 
+// customFieldSorterPrefix is the prefix of sorters by a custom field, such as "cf.sprint".
+const customFieldSorterPrefix = "cf."
+
+// CustomField returns the name of the custom field if the sorter sorts by a custom field.
+func (s Sorter) CustomField() (string, bool) {
+	if !strings.HasPrefix(string(s), customFieldSorterPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(string(s), customFieldSorterPrefix), true
+}
+
+// Key returns the value of the todo sorted by the sorter. Nil means the todo doesn't have the value.
+func (s Sorter) Key(t *Todo) interface{} {
+	switch s {
+	case SortByID:
+		return t.ID
+	case SortByPriority:
+		return int(t.Priority)
+	case SortByManual:
+		return t.Position
+	case SortByStatus:
+		return int(t.Status)
+	case SortByTitle:
+		return t.Title
+	case SortByCreatedAt:
+		return t.CreatedAt
+	case SortByUpdatedAt:
+		return t.UpdatedAt
+	case SortByEstimatePoints:
+		if t.EstimatePoints == nil {
+			return nil
+		}
+		return *t.EstimatePoints
+	case SortByEstimateMinutes:
+		if t.EstimateMinutes == nil {
+			return nil
+		}
+		return *t.EstimateMinutes
+	}
+	if name, ok := s.CustomField(); ok {
+		return t.CustomFields[name]
+	}
+	return nil
+}
+
+// Nullable reports whether some todos don't have the value sorted by. They come last in both orders.
+func (s Sorter) Nullable() bool {
+	_, ok := s.CustomField()
+	return ok || s == SortByEstimatePoints || s == SortByEstimateMinutes
+}
+
 func ToSorter(v string) (Sorter, error) {
 	lv := strings.ToLower(v)
+	if strings.HasPrefix(lv, customFieldSorterPrefix) && len(v) > len(customFieldSorterPrefix) {
+		// name of custom field is case sensitive
+		return Sorter(customFieldSorterPrefix + v[len(customFieldSorterPrefix):]), nil
+	}
 	switch lv {
 	case "id":
 		return SortByID, nil
 	case "priority":
 		return SortByPriority, nil
+	case "manual":
+		return SortByManual, nil
+	case "status":
+		return SortByStatus, nil
+	case "title":
+		return SortByTitle, nil
+	case "createdat":
+		return SortByCreatedAt, nil
+	case "updatedat":
+		return SortByUpdatedAt, nil
+	case "estimatepoints":
+		return SortByEstimatePoints, nil
+	case "estimateminutes":
+		return SortByEstimateMinutes, nil
 	default:
-		return "", fmt.Errorf("sorter must be id or priority, but %s", v)
+		return "", fmt.Errorf(
+			"sorter must be id, priority, manual, status, title, createdAt, updatedAt, estimatePoints, "+
+				"estimateMinutes or cf.<custom field>, but %s", v,
+		)
 	}
 }
 
@@ -142,3 +280,61 @@ func ToOrder(v string) (Order, error) {
 		return "", fmt.Errorf("order must be asc or desc, but %s", v)
 	}
 }
+
+// SortKey is one of the keys to sort todos by, in the order of priority.
+type SortKey struct {
+	Sorter Sorter
+	Order  Order
+}
+
+// TodoFilter is the conditions to narrow down todos to be listed.
+// Nil fields are not used as conditions.
+type TodoFilter struct {
+	IncludeDone bool
+	// IncludeSnoozed lists todos whose start time hasn't come yet as well.
+	IncludeSnoozed bool
+	// AssignedToMe lists todos assigned to the user instead of todos owned by the user.
+	AssignedToMe bool
+	// AssigneeID lists todos assigned to the user. Empty string lists todos assigned to nobody.
+	AssigneeID *string
+
+	// Statuses lists todos in any of the statuses. Empty means any status.
+	Statuses []Status
+	// Priorities lists todos in any of the priorities. Empty means any priority.
+	Priorities []Priority
+	CreatedAt  TimeRange
+	UpdatedAt  TimeRange
+	// TitleContains lists todos whose title contains the text ignoring case. Empty means any title.
+	TitleContains string
+	// DescriptionContains lists todos whose description contains the text ignoring case. Empty means any description.
+	DescriptionContains string
+
+	MinEstimatePoints  *int
+	MaxEstimatePoints  *int
+	MinEstimateMinutes *int
+	MaxEstimateMinutes *int
+	// CustomFields lists todos whose custom fields have the values.
+	CustomFields CustomFieldValues
+	// Query lists todos matching the search query. Nil means any todo.
+	Query Query
+}
+
+// TimeRange is the range of time from From (inclusive) to To (exclusive). Nil means unbounded.
+type TimeRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// Contains reports whether the time is within the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From == nil || !t.Before(*r.From)) && (r.To == nil || t.Before(*r.To))
+}
+
+// Page is the range of todos to be listed by keyset pagination.
+type Page struct {
+	// Limit is the max number of todos to be listed. Zero means no limit.
+	Limit int
+	// After is the last todo of the previous page, and todos after it in the order are listed.
+	// Only its id and the value sorted by are used. Nil lists from the first todo.
+	After *Todo
+}
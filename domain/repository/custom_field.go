@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+type CustomFieldRepository interface {
+	Create(ctx context.Context, field model.CustomField) (int, error)
+	Get(ctx context.Context, userID string, id int) (*model.CustomField, error)
+	List(ctx context.Context, userID string) ([]*model.CustomField, error)
+	Delete(ctx context.Context, id int) error
+}
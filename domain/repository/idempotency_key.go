@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+type IdempotencyKeyRepository interface {
+	// Reserve saves the key and returns it with true, unless the user has the same key which hasn't expired.
+	// Otherwise the existing key is returned with false. Expired keys are replaced, and expired keys of other
+	// users are purged meanwhile, so that keys which are never retried don't pile up.
+	Reserve(ctx context.Context, key model.IdempotencyKey) (*model.IdempotencyKey, bool, error)
+	// Complete saves the response of the key.
+	Complete(ctx context.Context, key *model.IdempotencyKey) error
+	// Release deletes the key, so that the request can be retried.
+	Release(ctx context.Context, userID, key string) error
+}
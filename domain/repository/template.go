@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+type TemplateRepository interface {
+	Create(ctx context.Context, template model.Template) (int, error)
+	Get(ctx context.Context, userID string, id int) (*model.Template, error)
+	List(ctx context.Context, userID string) ([]*model.Template, error)
+	Update(ctx context.Context, template *model.Template) error
+	Delete(ctx context.Context, id int) error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+type TimeEntryRepository interface {
+	Create(ctx context.Context, entry model.TimeEntry) (int, error)
+	Get(ctx context.Context, userID string, id int) (*model.TimeEntry, error)
+	// GetRunning returns the running entry of the user, or nil if there is none.
+	GetRunning(ctx context.Context, userID string) (*model.TimeEntry, error)
+	List(ctx context.Context, userID string, from, to *time.Time) ([]*model.TimeEntry, error)
+	Update(ctx context.Context, entry *model.TimeEntry) error
+	Delete(ctx context.Context, id int) error
+	// TotalDurations returns the tracked time of each todo. Running entries are counted up to now.
+	TotalDurations(ctx context.Context, todoIDs []int, now time.Time) (map[int]time.Duration, error)
+}
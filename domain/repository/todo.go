@@ -9,7 +9,18 @@ import (
 type TodoRepository interface {
 	Create(ctx context.Context, todo model.Todo) (int, error)
 	Get(ctx context.Context, userID string, id int) (*model.Todo, error)
-	List(ctx context.Context, userID string, sortBy model.Sorter, orderBy model.Order, includeDone bool) ([]*model.Todo, error)
+	// List returns todos sorted by the keys, and then by id in ascending order unless id is one of the keys.
+	List(ctx context.Context, userID string, sort []model.SortKey, filter model.TodoFilter, page model.Page) ([]*model.Todo, error)
+	// Update saves the todo and increments its version, only if the stored todo is still in the version of the todo.
+	// Conflict is returned otherwise, so that changes made since the todo was read aren't overwritten.
 	Update(ctx context.Context, todo *model.Todo) error
-	Delete(ctx context.Context, id int) error
+	// Delete deletes the todo only if it's in the version, and returns Conflict otherwise.
+	Delete(ctx context.Context, id, version int) error
+	Move(ctx context.Context, userID string, id, anchorID int, placement model.Placement) error
+	Summarize(ctx context.Context, userID string) ([]*model.EffortSummary, error)
+	// Search returns todos of the user whose title or description contains every term ignoring case.
+	// At most limit todos are returned in the order of search.Score, recent updates and then ids.
+	Search(ctx context.Context, userID string, terms []string, limit int) ([]*model.Todo, error)
+	// RemoveCustomField removes the value of the custom field from all todos of the user.
+	RemoveCustomField(ctx context.Context, userID string, name string) error
 }
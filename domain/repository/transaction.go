@@ -0,0 +1,9 @@
+package repository
+
+import "context"
+
+type TransactionRepository interface {
+	// Savepoint calls fn, and undoes the changes made by fn if it returns an error. Savepoints can be nested.
+	// It must be called in a transaction, such as the one started by DBMiddleware.NewTransaction.
+	Savepoint(ctx context.Context, fn func(ctx context.Context) error) error
+}
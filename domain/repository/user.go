@@ -5,4 +5,5 @@ import "context"
 type UserRepository interface {
 	Authenticate(ctx context.Context, id, password string) (bool, error)
 	Create(ctx context.Context, id, password string) error
+	Exists(ctx context.Context, id string) (bool, error)
 }
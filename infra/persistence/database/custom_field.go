@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+	"gorm.io/gorm"
+)
+
+type databaseCustomFieldRepository struct {
+}
+
+func NewDatabaseCustomFieldRepository() repository.CustomFieldRepository {
+	return &databaseCustomFieldRepository{}
+}
+
+func (r *databaseCustomFieldRepository) Create(ctx context.Context, field model.CustomField) (int, error) {
+	field.CreatedAt = time.Now()
+	if err := db.GetDBFromContext(ctx).Create(&field).Error; err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok {
+			if pgErr.Code.Name() == "unique_violation" {
+				return 0, utility.Conflict(fmt.Sprintf("custom field %s already exists", field.Name), pgErr).
+					WithCode(utility.ErrorCodeCustomFieldAlreadyExists)
+			}
+		}
+		return 0, utility.InternalServerError("can't create custom field", err)
+	}
+	return field.ID, nil
+}
+
+func (r *databaseCustomFieldRepository) Get(ctx context.Context, userID string, id int) (*model.CustomField, error) {
+	var ret model.CustomField
+	if err := db.GetDBFromContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&ret).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utility.NotFound(fmt.Sprintf("custom field with id %d is not found", id), err).
+				WithCode(utility.ErrorCodeCustomFieldNotFound)
+		}
+		return nil, utility.InternalServerError(fmt.Sprintf("can't find custom field with id %d from db", id), err)
+	}
+	return &ret, nil
+}
+
+func (r *databaseCustomFieldRepository) List(ctx context.Context, userID string) ([]*model.CustomField, error) {
+	var ret []*model.CustomField
+	if err := db.GetDBFromContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&ret).Error; err != nil {
+		return nil, utility.InternalServerError(fmt.Sprintf("can't find custom field for user %s from db", userID), err)
+	}
+	return ret, nil
+}
+
+func (r *databaseCustomFieldRepository) Delete(ctx context.Context, id int) error {
+	result := db.GetDBFromContext(ctx).
+		Where("id = ?", id).
+		Delete(&model.CustomField{})
+	if err := result.Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't delete custom field with id %d from db", id), err)
+	}
+	if result.RowsAffected == 0 {
+		return utility.NotFound("", fmt.Errorf("custom field with id %d is not found", id)).
+			WithCode(utility.ErrorCodeCustomFieldNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyKeyPurgeLimit is the max number of expired keys purged by a reservation.
+const idempotencyKeyPurgeLimit = 100
+
+type databaseIdempotencyKeyRepository struct {
+}
+
+func NewDatabaseIdempotencyKeyRepository() repository.IdempotencyKeyRepository {
+	return &databaseIdempotencyKeyRepository{}
+}
+
+func (r *databaseIdempotencyKeyRepository) Reserve(
+	ctx context.Context, key model.IdempotencyKey,
+) (*model.IdempotencyKey, bool, error) {
+	tx := db.GetDBFromContext(ctx)
+	// Expired keys are purged a few at a time. Keys locked by other requests are skipped, since they may be
+	// being replaced, and waiting for them could deadlock.
+	if err := tx.Exec(
+		"DELETE FROM idempotency_keys WHERE (user_id, key) IN "+
+			"(SELECT user_id, key FROM idempotency_keys WHERE expires_at <= ? LIMIT ? FOR UPDATE SKIP LOCKED)",
+		key.CreatedAt, idempotencyKeyPurgeLimit,
+	).Error; err != nil {
+		return nil, false, utility.InternalServerError("can't purge expired idempotency keys", err)
+	}
+	// A concurrent request with the same key waits here until the transaction of the first one ends,
+	// since the key is unique. Then it finds the key with the response, or reserves the key if the first one failed.
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"request_hash", "status_code", "body", "header", "created_at", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("idempotency_keys.expires_at <= ?", key.CreatedAt),
+		}},
+	}).Create(&key)
+	if err := result.Error; err != nil {
+		return nil, false, utility.InternalServerError("can't save idempotency key", err)
+	}
+	if result.RowsAffected > 0 {
+		return &key, true, nil
+	}
+
+	var ret model.IdempotencyKey
+	if err := tx.Where("user_id = ? AND key = ?", key.UserID, key.Key).Take(&ret).Error; err != nil {
+		return nil, false, utility.InternalServerError(fmt.Sprintf("can't find idempotency key %s from db", key.Key), err)
+	}
+	return &ret, false, nil
+}
+
+func (r *databaseIdempotencyKeyRepository) Complete(ctx context.Context, key *model.IdempotencyKey) error {
+	if err := db.GetDBFromContext(ctx).
+		Model(&model.IdempotencyKey{}).
+		Where("user_id = ? AND key = ?", key.UserID, key.Key).
+		Select("status_code", "body", "header").
+		Updates(key).Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't save response of idempotency key %s", key.Key), err)
+	}
+	return nil
+}
+
+func (r *databaseIdempotencyKeyRepository) Release(ctx context.Context, userID, key string) error {
+	if err := db.GetDBFromContext(ctx).
+		Where("user_id = ? AND key = ?", userID, key).
+		Delete(&model.IdempotencyKey{}).Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't delete idempotency key %s from db", key), err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+// queryCondition translates the search query to a condition of WHERE and its arguments.
+// Nullable columns are compared so that NOT of a condition matches the rest of todos, as onmemory does.
+func queryCondition(q model.Query) (string, []interface{}) {
+	switch q := q.(type) {
+	case model.AndQuery:
+		return joinConditions(q.Operands, " AND ", "TRUE")
+	case model.OrQuery:
+		return joinConditions(q.Operands, " OR ", "FALSE")
+	case model.NotQuery:
+		sql, vars := queryCondition(q.Operand)
+		return fmt.Sprintf("NOT (%s)", sql), vars
+	case model.StatusQuery:
+		return "status IN ?", []interface{}{q.Statuses}
+	case model.PriorityQuery:
+		return "priority IN ?", []interface{}{q.Priorities}
+	case model.AssigneeQuery:
+		if q.AssigneeID == nil {
+			return "assignee_id IS NULL", nil
+		}
+		return "assignee_id IS NOT DISTINCT FROM ?", []interface{}{*q.AssigneeID}
+	case model.TextQuery:
+		conditions := make([]string, 0, len(q.Fields))
+		vars := make([]interface{}, 0, len(q.Fields))
+		for _, field := range q.Fields {
+			conditions = append(conditions, containsCondition(string(field)))
+			vars = append(vars, containsPattern(q.Text))
+		}
+		return fmt.Sprintf("(%s)", strings.Join(conditions, " OR ")), vars
+	case model.TimeQuery:
+		return fmt.Sprintf("%s %s ?", string(q.Field), string(q.Comparison)), []interface{}{q.Time}
+	default:
+		return "TRUE", nil
+	}
+}
+
+// joinConditions joins the conditions of the queries with the operator. Empty queries are the identity.
+func joinConditions(queries []model.Query, operator, identity string) (string, []interface{}) {
+	if len(queries) == 0 {
+		return identity, nil
+	}
+	conditions := make([]string, 0, len(queries))
+	vars := []interface{}{}
+	for _, q := range queries {
+		sql, v := queryCondition(q)
+		conditions = append(conditions, sql)
+		vars = append(vars, v...)
+	}
+	return fmt.Sprintf("(%s)", strings.Join(conditions, operator)), vars
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+	"gorm.io/gorm"
+)
+
+type databaseTemplateRepository struct {
+}
+
+func NewDatabaseTemplateRepository() repository.TemplateRepository {
+	return &databaseTemplateRepository{}
+}
+
+func (r *databaseTemplateRepository) Create(ctx context.Context, template model.Template) (int, error) {
+	now := time.Now()
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	if err := db.GetDBFromContext(ctx).Create(&template).Error; err != nil {
+		return 0, utility.InternalServerError("can't create template", err)
+	}
+	return template.ID, nil
+}
+
+func (r *databaseTemplateRepository) Get(ctx context.Context, userID string, id int) (*model.Template, error) {
+	var ret model.Template
+	if err := db.GetDBFromContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&ret).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utility.NotFound(fmt.Sprintf("template with id %d is not found", id), err).
+				WithCode(utility.ErrorCodeTemplateNotFound)
+		}
+		return nil, utility.InternalServerError(fmt.Sprintf("can't find template with id %d from db", id), err)
+	}
+	return &ret, nil
+}
+
+func (r *databaseTemplateRepository) List(ctx context.Context, userID string) ([]*model.Template, error) {
+	var ret []*model.Template
+	if err := db.GetDBFromContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&ret).Error; err != nil {
+		return nil, utility.InternalServerError(fmt.Sprintf("can't find template for user %s from db", userID), err)
+	}
+	return ret, nil
+}
+
+func (r *databaseTemplateRepository) Update(ctx context.Context, template *model.Template) error {
+	template.UpdatedAt = time.Now()
+	result := db.GetDBFromContext(ctx).Save(template)
+	if err := result.Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't update template with id %d", template.ID), err)
+	}
+	if result.RowsAffected == 0 {
+		return utility.NotFound("", fmt.Errorf("template with id %d is not found", template.ID)).
+			WithCode(utility.ErrorCodeTemplateNotFound)
+	}
+	return nil
+}
+
+func (r *databaseTemplateRepository) Delete(ctx context.Context, id int) error {
+	result := db.GetDBFromContext(ctx).
+		Where("id = ?", id).
+		Delete(&model.Template{})
+	if err := result.Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't delete template with id %d from db", id), err)
+	}
+	if result.RowsAffected == 0 {
+		return utility.NotFound("", fmt.Errorf("template with id %d is not found", id)).
+			WithCode(utility.ErrorCodeTemplateNotFound)
+	}
+	return nil
+}
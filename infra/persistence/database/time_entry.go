@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+	"gorm.io/gorm"
+)
+
+type databaseTimeEntryRepository struct {
+}
+
+func NewDatabaseTimeEntryRepository() repository.TimeEntryRepository {
+	return &databaseTimeEntryRepository{}
+}
+
+func (r *databaseTimeEntryRepository) Create(ctx context.Context, entry model.TimeEntry) (int, error) {
+	now := time.Now()
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	if err := db.GetDBFromContext(ctx).Create(&entry).Error; err != nil {
+		// at most one running entry per user is guaranteed by an unique index
+		pgErr, ok := err.(*pq.Error)
+		if ok {
+			if pgErr.Code.Name() == "unique_violation" {
+				return 0, utility.Conflict(fmt.Sprintf("user %s already has a running timer", entry.UserID), pgErr).
+					WithCode(utility.ErrorCodeTimerAlreadyRunning)
+			}
+		}
+		return 0, utility.InternalServerError("can't create time entry", err)
+	}
+	return entry.ID, nil
+}
+
+func (r *databaseTimeEntryRepository) Get(ctx context.Context, userID string, id int) (*model.TimeEntry, error) {
+	var ret model.TimeEntry
+	if err := db.GetDBFromContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&ret).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utility.NotFound(fmt.Sprintf("time entry with id %d is not found", id), err).
+				WithCode(utility.ErrorCodeTimeEntryNotFound)
+		}
+		return nil, utility.InternalServerError(fmt.Sprintf("can't find time entry with id %d from db", id), err)
+	}
+	return &ret, nil
+}
+
+func (r *databaseTimeEntryRepository) GetRunning(ctx context.Context, userID string) (*model.TimeEntry, error) {
+	var ret model.TimeEntry
+	if err := db.GetDBFromContext(ctx).
+		Where("user_id = ? AND stopped_at IS NULL", userID).
+		First(&ret).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, utility.InternalServerError(fmt.Sprintf("can't find running time entry for user %s from db", userID), err)
+	}
+	return &ret, nil
+}
+
+func (r *databaseTimeEntryRepository) List(
+	ctx context.Context, userID string, from, to *time.Time,
+) ([]*model.TimeEntry, error) {
+	query := db.GetDBFromContext(ctx).
+		Where("user_id = ?", userID).
+		Order("started_at ASC, id ASC")
+	if from != nil {
+		query = query.Where("started_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("started_at < ?", *to)
+	}
+
+	var ret []*model.TimeEntry
+	if err := query.Find(&ret).Error; err != nil {
+		return nil, utility.InternalServerError(fmt.Sprintf("can't find time entry for user %s from db", userID), err)
+	}
+	return ret, nil
+}
+
+func (r *databaseTimeEntryRepository) Update(ctx context.Context, entry *model.TimeEntry) error {
+	entry.UpdatedAt = time.Now()
+	result := db.GetDBFromContext(ctx).Save(entry)
+	if err := result.Error; err != nil {
+		pgErr, ok := err.(*pq.Error)
+		if ok {
+			if pgErr.Code.Name() == "unique_violation" {
+				return utility.Conflict(fmt.Sprintf("user %s already has a running timer", entry.UserID), pgErr).
+					WithCode(utility.ErrorCodeTimerAlreadyRunning)
+			}
+		}
+		return utility.InternalServerError(fmt.Sprintf("can't update time entry with id %d", entry.ID), err)
+	}
+	if result.RowsAffected == 0 {
+		return utility.NotFound("", fmt.Errorf("time entry with id %d is not found", entry.ID)).
+			WithCode(utility.ErrorCodeTimeEntryNotFound)
+	}
+	return nil
+}
+
+func (r *databaseTimeEntryRepository) Delete(ctx context.Context, id int) error {
+	result := db.GetDBFromContext(ctx).
+		Where("id = ?", id).
+		Delete(&model.TimeEntry{})
+	if err := result.Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't delete time entry with id %d from db", id), err)
+	}
+	if result.RowsAffected == 0 {
+		return utility.NotFound("", fmt.Errorf("time entry with id %d is not found", id)).
+			WithCode(utility.ErrorCodeTimeEntryNotFound)
+	}
+	return nil
+}
+
+func (r *databaseTimeEntryRepository) TotalDurations(
+	ctx context.Context, todoIDs []int, now time.Time,
+) (map[int]time.Duration, error) {
+	ret := make(map[int]time.Duration, len(todoIDs))
+	if len(todoIDs) == 0 {
+		return ret, nil
+	}
+
+	var rows []struct {
+		TodoID  int
+		Seconds float64
+	}
+	if err := db.GetDBFromContext(ctx).
+		Model(&model.TimeEntry{}).
+		Select("todo_id, SUM(EXTRACT(EPOCH FROM (COALESCE(stopped_at, ?) - started_at))) AS seconds", now).
+		Where("todo_id IN ?", todoIDs).
+		Group("todo_id").
+		Scan(&rows).Error; err != nil {
+		return nil, utility.InternalServerError("can't sum time entries from db", err)
+	}
+	for _, row := range rows {
+		ret[row.TodoID] = time.Duration(row.Seconds * float64(time.Second))
+	}
+	return ret, nil
+}
@@ -2,15 +2,19 @@ package database
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/search"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type databaseTodoRepository struct {
@@ -21,10 +25,30 @@ func NewDatabaseTodoRepository() repository.TodoRepository {
 }
 
 func (r *databaseTodoRepository) Create(ctx context.Context, todo model.Todo) (int, error) {
+	tx := db.GetDBFromContext(ctx)
+	if err := lockUser(tx, todo.UserID); err != nil {
+		return 0, err
+	}
+
+	// new todo is placed at the end of manual order
+	last, err := findPosition(tx.Where("user_id = ?", todo.UserID).Order("position DESC"))
+	if err != nil {
+		return 0, err
+	}
+	position, err := model.PositionBetween(last, "")
+	if err != nil {
+		return 0, utility.InternalServerError("can't decide position of todo", err)
+	}
+
 	now := time.Now()
+	todo.Position = position
+	if todo.CustomFields == nil {
+		todo.CustomFields = model.CustomFieldValues{}
+	}
 	todo.CreatedAt = now
 	todo.UpdatedAt = now
-	if err := db.GetDBFromContext(ctx).Create(&todo).Error; err != nil {
+	todo.Version = 1
+	if err := tx.Create(&todo).Error; err != nil {
 		return 0, utility.InternalServerError("can't create todo", err)
 	}
 	return todo.ID, nil
@@ -33,10 +57,11 @@ func (r *databaseTodoRepository) Create(ctx context.Context, todo model.Todo) (i
 func (r *databaseTodoRepository) Get(ctx context.Context, userID string, id int) (*model.Todo, error) {
 	var ret model.Todo
 	if err := db.GetDBFromContext(ctx).
-		Where("id = ? AND user_id = ?", id, userID).
+		Where("id = ? AND (user_id = ? OR assignee_id = ?)", id, userID, userID).
 		First(&ret).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, utility.NotFound(fmt.Sprintf("todo with id %d is not found", id), err)
+			return nil, utility.NotFound(fmt.Sprintf("todo with id %d is not found", id), err).
+				WithCode(utility.ErrorCodeTodoNotFound)
 		}
 		return nil, utility.InternalServerError(fmt.Sprintf("todo with id %d is not found", id), err)
 	}
@@ -44,14 +69,74 @@ func (r *databaseTodoRepository) Get(ctx context.Context, userID string, id int)
 }
 
 func (r *databaseTodoRepository) List(
-	ctx context.Context, userID string, sortBy model.Sorter, orderBy model.Order, includeDone bool,
+	ctx context.Context, userID string, sort []model.SortKey, filter model.TodoFilter, page model.Page,
 ) ([]*model.Todo, error) {
-	query := db.GetDBFromContext(ctx).
-		Where("user_id = ?", userID).
-		Order(fmt.Sprintf("%s %s", string(sortBy), string(orderBy)))
-	if !includeDone {
+	query := db.GetDBFromContext(ctx)
+	if filter.AssignedToMe {
+		query = query.Where("assignee_id = ?", userID)
+	} else {
+		query = query.Where("user_id = ?", userID)
+	}
+	query = query.Clauses(orderBy(sort))
+	if !filter.IncludeDone {
 		query.Where("status <> ?", int(model.StatusDone))
 	}
+	if !filter.IncludeSnoozed {
+		query = query.Where("(start_at IS NULL OR start_at <= ?)", time.Now())
+	}
+	if filter.AssigneeID != nil {
+		if *filter.AssigneeID == "" {
+			query = query.Where("assignee_id IS NULL")
+		} else {
+			query = query.Where("assignee_id = ?", *filter.AssigneeID)
+		}
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if len(filter.Priorities) > 0 {
+		query = query.Where("priority IN ?", filter.Priorities)
+	}
+	query = whereInRange(query, "created_at", filter.CreatedAt)
+	query = whereInRange(query, "updated_at", filter.UpdatedAt)
+	if filter.TitleContains != "" {
+		query = query.Where(containsCondition("title"), containsPattern(filter.TitleContains))
+	}
+	if filter.DescriptionContains != "" {
+		query = query.Where(containsCondition("description"), containsPattern(filter.DescriptionContains))
+	}
+	if filter.Query != nil {
+		sql, vars := queryCondition(filter.Query)
+		query = query.Where(sql, vars...)
+	}
+	if filter.MinEstimatePoints != nil {
+		query = query.Where("estimate_points >= ?", *filter.MinEstimatePoints)
+	}
+	if filter.MaxEstimatePoints != nil {
+		query = query.Where("estimate_points <= ?", *filter.MaxEstimatePoints)
+	}
+	if filter.MinEstimateMinutes != nil {
+		query = query.Where("estimate_minutes >= ?", *filter.MinEstimateMinutes)
+	}
+	if filter.MaxEstimateMinutes != nil {
+		query = query.Where("estimate_minutes <= ?", *filter.MaxEstimateMinutes)
+	}
+	if len(filter.CustomFields) > 0 {
+		values, err := json.Marshal(filter.CustomFields)
+		if err != nil {
+			return nil, utility.InternalServerError("can't encode custom fields", err)
+		}
+		query = query.Where("custom_fields @> ?", string(values))
+	}
+	if page.After != nil {
+		var err error
+		if query, err = whereAfter(query, sort, page.After); err != nil {
+			return nil, err
+		}
+	}
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit)
+	}
 
 	var ret []*model.Todo
 	if err := query.Find(&ret).Error; err != nil {
@@ -61,26 +146,339 @@ func (r *databaseTodoRepository) List(
 }
 
 func (r *databaseTodoRepository) Update(ctx context.Context, todo *model.Todo) error {
+	tx := db.GetDBFromContext(ctx)
+	version := todo.Version
+	todo.Version++
 	todo.UpdatedAt = time.Now()
-	result := db.GetDBFromContext(ctx).Save(todo)
+	if todo.CustomFields == nil {
+		todo.CustomFields = model.CustomFieldValues{}
+	}
+	// position is changed only through Move, so that a concurrent move isn't overwritten.
+	// Save isn't used, since it inserts the todo when no row is updated.
+	result := tx.Model(todo).
+		Select("*").Omit("position").
+		Where("version = ?", version).
+		Updates(todo)
 	if err := result.Error; err != nil {
-		return utility.InternalServerError(fmt.Sprintf("todo with id %d is not found", todo.ID), err)
+		todo.Version = version
+		return utility.InternalServerError(fmt.Sprintf("can't update todo with id %d", todo.ID), err)
 	}
 	if result.RowsAffected == 0 {
-		return utility.NotFound("", fmt.Errorf(fmt.Sprintf("todo with id %d is not found", todo.ID)))
+		todo.Version = version
+		return notFoundOrModified(tx, todo.ID)
 	}
 	return nil
 }
 
-func (r *databaseTodoRepository) Delete(ctx context.Context, id int) error {
-	result := db.GetDBFromContext(ctx).
-		Where("id = ?", id).
+func (r *databaseTodoRepository) Delete(ctx context.Context, id, version int) error {
+	tx := db.GetDBFromContext(ctx)
+	result := tx.
+		Where("id = ? AND version = ?", id, version).
 		Delete(&model.Todo{})
 	if err := result.Error; err != nil {
 		return utility.InternalServerError(fmt.Sprintf("can't delete todo with id %d from db", id), err)
 	}
 	if result.RowsAffected == 0 {
-		return utility.NotFound("", fmt.Errorf(fmt.Sprintf("todo with id %d is not found", id)))
+		return notFoundOrModified(tx, id)
+	}
+	return nil
+}
+
+// notFoundOrModified returns the error of a conditional update or delete of the todo which affected no rows.
+func notFoundOrModified(tx *gorm.DB, id int) error {
+	var count int64
+	if err := tx.Model(&model.Todo{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't find todo with id %d from db", id), err)
+	}
+	if count == 0 {
+		return utility.NotFound("", fmt.Errorf("todo with id %d is not found", id)).
+			WithCode(utility.ErrorCodeTodoNotFound)
+	}
+	return utility.Conflict("", fmt.Errorf("todo with id %d has been modified by another request", id)).
+		WithCode(utility.ErrorCodeConcurrentModification)
+}
+
+func (r *databaseTodoRepository) Move(
+	ctx context.Context, userID string, id, anchorID int, placement model.Placement,
+) error {
+	tx := db.GetDBFromContext(ctx)
+	// moves of the same user are serialized, so that two todos never get the same position
+	if err := lockUser(tx, userID); err != nil {
+		return err
+	}
+
+	var anchor model.Todo
+	if err := tx.Where("id = ? AND user_id = ?", anchorID, userID).Take(&anchor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utility.NotFound(fmt.Sprintf("todo with id %d is not found", anchorID), err).
+				WithCode(utility.ErrorCodeTodoNotFound)
+		}
+		return utility.InternalServerError(fmt.Sprintf("can't find todo with id %d from db", anchorID), err)
+	}
+
+	neighborQuery := tx.Where("user_id = ? AND id <> ?", userID, id)
+	var prev, next string
+	if placement == model.PlaceBefore {
+		neighbor, err := findPosition(
+			neighborQuery.Where("position < ?", anchor.Position).Order("position DESC"),
+		)
+		if err != nil {
+			return err
+		}
+		prev, next = neighbor, anchor.Position
+	} else {
+		neighbor, err := findPosition(
+			neighborQuery.Where("position > ?", anchor.Position).Order("position ASC"),
+		)
+		if err != nil {
+			return err
+		}
+		prev, next = anchor.Position, neighbor
+	}
+	position, err := model.PositionBetween(prev, next)
+	if err != nil {
+		return utility.InternalServerError("can't decide position of todo", err)
+	}
+
+	result := tx.Model(&model.Todo{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(map[string]interface{}{
+			"position": position, "updated_at": time.Now(), "version": gorm.Expr("version + 1"),
+		})
+	if err := result.Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't move todo with id %d", id), err)
+	}
+	if result.RowsAffected == 0 {
+		return utility.NotFound("", fmt.Errorf("todo with id %d is not found", id)).
+			WithCode(utility.ErrorCodeTodoNotFound)
+	}
+	return nil
+}
+
+func (r *databaseTodoRepository) Summarize(ctx context.Context, userID string) ([]*model.EffortSummary, error) {
+	var ret []*model.EffortSummary
+	if err := db.GetDBFromContext(ctx).
+		Model(&model.Todo{}).
+		Select(
+			"status, priority, COUNT(*) AS count, "+
+				"COALESCE(SUM(estimate_points), 0) AS estimate_points, "+
+				"COALESCE(SUM(estimate_minutes), 0) AS estimate_minutes",
+		).
+		Where("user_id = ?", userID).
+		Group("status, priority").
+		Scan(&ret).Error; err != nil {
+		return nil, utility.InternalServerError(fmt.Sprintf("can't summarize todo for user %s from db", userID), err)
+	}
+	return ret, nil
+}
+
+func (r *databaseTodoRepository) Search(
+	ctx context.Context, userID string, terms []string, limit int,
+) ([]*model.Todo, error) {
+	query := db.GetDBFromContext(ctx).Where("user_id = ?", userID)
+	scores := make([]string, 0, len(terms))
+	vars := []interface{}{}
+	for _, term := range terms {
+		pattern := containsPattern(term)
+		query = query.Where(
+			fmt.Sprintf("(%s OR %s)", containsCondition("title"), containsCondition("description")), pattern, pattern,
+		)
+		scores = append(scores, fmt.Sprintf(
+			"%v * ln(1 + %s) + ln(1 + %s)", search.TitleWeight, occurrences("title"), occurrences("description"),
+		))
+		vars = append(vars, term, term, term, term)
+	}
+	// todos are ranked as search.Score in the database, so that only the ones returned are loaded
+	order := clause.OrderBy{Expression: clause.Expr{
+		SQL: strings.Join(scores, " + ") + " DESC, updated_at DESC, id ASC", Vars: vars,
+	}}
+
+	var ret []*model.Todo
+	if err := query.Clauses(order).Limit(limit).Find(&ret).Error; err != nil {
+		return nil, utility.InternalServerError(fmt.Sprintf("can't search todo for user %s from db", userID), err)
+	}
+	return ret, nil
+}
+
+func (r *databaseTodoRepository) RemoveCustomField(ctx context.Context, userID string, name string) error {
+	if err := db.GetDBFromContext(ctx).
+		Model(&model.Todo{}).
+		Where("user_id = ? AND custom_fields -> ? IS NOT NULL", userID, name).
+		UpdateColumns(map[string]interface{}{
+			"custom_fields": gorm.Expr("custom_fields - ?", name), "version": gorm.Expr("version + 1"),
+		}).Error; err != nil {
+		return utility.InternalServerError(fmt.Sprintf("can't remove custom field %s from todos", name), err)
 	}
 	return nil
 }
+
+// whereInRange narrows down the query to rows whose column is within the range.
+func whereInRange(query *gorm.DB, column string, r model.TimeRange) *gorm.DB {
+	if r.From != nil {
+		query = query.Where(fmt.Sprintf("%s >= ?", column), *r.From)
+	}
+	if r.To != nil {
+		query = query.Where(fmt.Sprintf("%s < ?", column), *r.To)
+	}
+	return query
+}
+
+// likeEscaper escapes wildcards of LIKE with the default escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike returns the pattern of LIKE which matches the text literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// containsCondition returns the condition that the text column contains containsPattern ignoring case.
+// The column is compared in lower case by LIKE as the bigram index of it is built, since pg_bigm doesn't
+// support ILIKE. NULL contains nothing, and NOT of the condition matches it.
+func containsCondition(column string) string {
+	return fmt.Sprintf("(%s IS NOT NULL AND lower(%s) LIKE lower(?))", column, column)
+}
+
+// occurrences returns the expression of the number of the term in the text column ignoring case, without
+// overlaps as search.Score counts. It takes the term twice as the arguments.
+func occurrences(column string) string {
+	text := fmt.Sprintf("lower(COALESCE(%s, ''))", column)
+	return fmt.Sprintf(
+		"(char_length(%s) - char_length(replace(%s, lower(?), '')))::float8 / char_length(?)", text, text,
+	)
+}
+
+// containsPattern returns the pattern of containsCondition which matches the text as a substring.
+func containsPattern(text string) string {
+	return "%" + escapeLike(text) + "%"
+}
+
+// sortColumn returns the expression of the column sorted by the sorter.
+func sortColumn(sorter model.Sorter) interface{} {
+	if name, ok := sorter.CustomField(); ok {
+		return gorm.Expr("custom_fields -> ?", name)
+	}
+	switch sorter {
+	case model.SortByManual:
+		return clause.Column{Name: "position"}
+	case model.SortByTitle:
+		// titles are compared byte by byte as onmemory does, regardless of the locale of the database
+		return gorm.Expr(`title COLLATE "C"`)
+	default:
+		return clause.Column{Name: string(sorter)}
+	}
+}
+
+// sortKeys returns the keys followed by id as the final tie-breaker, unless id is one of the keys.
+func sortKeys(sort []model.SortKey) []model.SortKey {
+	for i, key := range sort {
+		if key.Sorter == model.SortByID {
+			return sort[:i+1]
+		}
+	}
+	return append(append([]model.SortKey{}, sort...), model.SortKey{Sorter: model.SortByID, Order: model.OrderByASC})
+}
+
+// orderBy returns ORDER BY of the keys. Todos without the value of a nullable key come last in both orders.
+func orderBy(sort []model.SortKey) clause.OrderBy {
+	columns := []string{}
+	vars := []interface{}{}
+	for _, key := range sortKeys(sort) {
+		column := "? " + string(key.Order)
+		if key.Sorter.Nullable() {
+			column += " NULLS LAST"
+		}
+		columns = append(columns, column)
+		vars = append(vars, sortColumn(key.Sorter))
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(columns, ", "), Vars: vars}}
+}
+
+// whereAfter narrows down the query to todos after the todo in the order of the keys, which is
+// that the todo has the same values for some first keys and a value after it for the next key.
+func whereAfter(query *gorm.DB, sort []model.SortKey, after *model.Todo) (*gorm.DB, error) {
+	conditions := []string{}
+	vars := []interface{}{}
+	equals := []string{}
+	equalVars := []interface{}{}
+	for _, key := range sortKeys(sort) {
+		column := sortColumn(key.Sorter)
+		value, err := sortKey(key.Sorter, after)
+		if err != nil {
+			return nil, err
+		}
+		cmp := ">"
+		if key.Order == model.OrderByDESC {
+			cmp = "<"
+		}
+
+		// todos without the value come after todos with it, and are equal to each other
+		switch {
+		case value == nil:
+		case key.Sorter.Nullable():
+			conditions = append(conditions, fmt.Sprintf("(%s(? %s ? OR ? IS NULL))", prefix(equals), cmp))
+			vars = append(append(vars, equalVars...), column, value, column)
+		default:
+			conditions = append(conditions, fmt.Sprintf("(%s? %s ?)", prefix(equals), cmp))
+			vars = append(append(vars, equalVars...), column, value)
+		}
+		if value == nil {
+			equals = append(equals, "? IS NULL")
+			equalVars = append(equalVars, column)
+		} else {
+			equals = append(equals, "? = ?")
+			equalVars = append(equalVars, column, value)
+		}
+	}
+	if len(conditions) == 0 {
+		return query.Where("FALSE"), nil
+	}
+	return query.Where(fmt.Sprintf("(%s)", strings.Join(conditions, " OR ")), vars...), nil
+}
+
+// prefix returns the conditions joined by AND followed by AND, or empty string if there is none.
+func prefix(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return strings.Join(conditions, " AND ") + " AND "
+}
+
+// sortKey returns the value of the todo sorted by the sorter as a variable of SQL.
+func sortKey(sorter model.Sorter, todo *model.Todo) (interface{}, error) {
+	value := sorter.Key(todo)
+	if _, ok := sorter.CustomField(); ok && value != nil {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, utility.InternalServerError("can't encode custom field", err)
+		}
+		return gorm.Expr("CAST(? AS jsonb)", string(b)), nil
+	}
+	return value, nil
+}
+
+// lockUser takes a row lock of the user until the end of the transaction.
+func lockUser(tx *gorm.DB, userID string) error {
+	var u model.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userID).
+		Take(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return utility.NotFound(fmt.Sprintf("user %s is not found", userID), err).
+				WithCode(utility.ErrorCodeUserNotFound)
+		}
+		return utility.InternalServerError(fmt.Sprintf("can't lock user %s", userID), err)
+	}
+	return nil
+}
+
+// findPosition returns the position of the first todo of query, or empty string if there is none.
+func findPosition(query *gorm.DB) (string, error) {
+	var todo model.Todo
+	if err := query.Select("position").Take(&todo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", utility.InternalServerError("can't find position of todo from db", err)
+	}
+	return todo.Position, nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+)
+
+type databaseTransactionRepository struct {
+	// seq numbers savepoints, so that nested ones have distinct names
+	seq int64
+}
+
+func NewDatabaseTransactionRepository() repository.TransactionRepository {
+	return &databaseTransactionRepository{}
+}
+
+func (r *databaseTransactionRepository) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx := db.GetDBFromContext(ctx)
+	name := fmt.Sprintf("sp%d", atomic.AddInt64(&r.seq, 1))
+	if err := tx.SavePoint(name).Error; err != nil {
+		return utility.InternalServerError("can't create savepoint", err)
+	}
+
+	if err := fn(ctx); err != nil {
+		// rolling back to the savepoint also makes the transaction usable again after an error of the database
+		if rerr := tx.RollbackTo(name).Error; rerr != nil {
+			return utility.InternalServerError("can't rollback to savepoint", rerr)
+		}
+		return err
+	}
+	return nil
+}
@@ -40,10 +40,19 @@ func (r *databaseUserRepository) Create(ctx context.Context, id, password string
 		pgErr, ok := err.(*pq.Error)
 		if ok {
 			if pgErr.Code.Name() == "unique_violation" {
-				return utility.Conflict(fmt.Sprintf("user %s already exists", id), pgErr)
+				return utility.Conflict(fmt.Sprintf("user %s already exists", id), pgErr).
+					WithCode(utility.ErrorCodeUserAlreadyExists)
 			}
 		}
 		return utility.InternalServerError("failed to create user", err)
 	}
 	return nil
 }
+
+func (r *databaseUserRepository) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+	if err := db.GetDBFromContext(ctx).Model(&model.User{}).Where("user_id = ?", id).Count(&count).Error; err != nil {
+		return false, utility.InternalServerError("can't find user from db", err)
+	}
+	return count > 0, nil
+}
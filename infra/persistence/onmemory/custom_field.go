@@ -0,0 +1,83 @@
+package onmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type onmemoryCustomFieldRepository struct {
+	sync sync.Mutex
+	id   int
+	data []model.CustomField
+}
+
+func NewOnmemoryCustomFieldRepository() repository.CustomFieldRepository {
+	fields := make([]model.CustomField, 0)
+	return &onmemoryCustomFieldRepository{data: fields}
+}
+
+func (r *onmemoryCustomFieldRepository) Create(ctx context.Context, field model.CustomField) (int, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for _, f := range r.data {
+		if f.UserID == field.UserID && f.Name == field.Name {
+			return 0, utility.Conflict("", fmt.Errorf("custom field %s already exists", field.Name)).
+				WithCode(utility.ErrorCodeCustomFieldAlreadyExists)
+		}
+	}
+
+	r.id += 1
+	field.ID = r.id
+	field.CreatedAt = time.Now()
+	r.data = append(r.data, field)
+	return field.ID, nil
+}
+
+func (r *onmemoryCustomFieldRepository) Get(ctx context.Context, userID string, id int) (*model.CustomField, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id && r.data[i].UserID == userID {
+			ret := r.data[i]
+			return &ret, nil
+		}
+	}
+	return nil, utility.NotFound("", fmt.Errorf("custom field with id %d for user %s is not found", id, userID)).
+		WithCode(utility.ErrorCodeCustomFieldNotFound)
+}
+
+func (r *onmemoryCustomFieldRepository) List(ctx context.Context, userID string) ([]*model.CustomField, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	ret := make([]*model.CustomField, 0)
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].UserID == userID {
+			field := r.data[i]
+			ret = append(ret, &field)
+		}
+	}
+	return ret, nil
+}
+
+func (r *onmemoryCustomFieldRepository) Delete(ctx context.Context, id int) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id {
+			r.data = append(r.data[:i], r.data[i+1:]...)
+			return nil
+		}
+	}
+	return utility.NotFound("", fmt.Errorf("custom field with id %d is not found", id)).
+		WithCode(utility.ErrorCodeCustomFieldNotFound)
+}
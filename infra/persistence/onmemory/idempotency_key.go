@@ -0,0 +1,65 @@
+package onmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+)
+
+// idempotencyKeyID identifies an idempotency key, which is unique for each user.
+type idempotencyKeyID struct {
+	userID string
+	key    string
+}
+
+type onmemoryIdempotencyKeyRepository struct {
+	sync sync.Mutex
+	data map[idempotencyKeyID]model.IdempotencyKey
+}
+
+func NewOnmemoryIdempotencyKeyRepository() repository.IdempotencyKeyRepository {
+	return &onmemoryIdempotencyKeyRepository{data: map[idempotencyKeyID]model.IdempotencyKey{}}
+}
+
+func (r *onmemoryIdempotencyKeyRepository) Reserve(
+	ctx context.Context, key model.IdempotencyKey,
+) (*model.IdempotencyKey, bool, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for id, saved := range r.data {
+		if !saved.ExpiresAt.After(key.CreatedAt) {
+			delete(r.data, id)
+		}
+	}
+	id := idempotencyKeyID{key.UserID, key.Key}
+	if saved, ok := r.data[id]; ok {
+		return &saved, false, nil
+	}
+	r.data[id] = key
+	return &key, true, nil
+}
+
+func (r *onmemoryIdempotencyKeyRepository) Complete(ctx context.Context, key *model.IdempotencyKey) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	id := idempotencyKeyID{key.UserID, key.Key}
+	if saved, ok := r.data[id]; ok {
+		saved.StatusCode = key.StatusCode
+		saved.Body = key.Body
+		saved.Header = key.Header
+		r.data[id] = saved
+	}
+	return nil
+}
+
+func (r *onmemoryIdempotencyKeyRepository) Release(ctx context.Context, userID, key string) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	delete(r.data, idempotencyKeyID{userID, key})
+	return nil
+}
@@ -0,0 +1,52 @@
+package onmemory
+
+import (
+	"strings"
+
+	linq "github.com/ahmetb/go-linq/v3"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+// matchQuery reports whether the todo matches the search query.
+func matchQuery(q model.Query, t model.Todo) bool {
+	switch q := q.(type) {
+	case model.AndQuery:
+		for _, operand := range q.Operands {
+			if !matchQuery(operand, t) {
+				return false
+			}
+		}
+		return true
+	case model.OrQuery:
+		for _, operand := range q.Operands {
+			if matchQuery(operand, t) {
+				return true
+			}
+		}
+		return false
+	case model.NotQuery:
+		return !matchQuery(q.Operand, t)
+	case model.StatusQuery:
+		return linq.From(q.Statuses).Contains(t.Status)
+	case model.PriorityQuery:
+		return linq.From(q.Priorities).Contains(t.Priority)
+	case model.AssigneeQuery:
+		if q.AssigneeID == nil {
+			return t.AssigneeID == nil
+		}
+		return t.AssigneeID != nil && *t.AssigneeID == *q.AssigneeID
+	case model.TextQuery:
+		text := strings.ToLower(q.Text)
+		for _, field := range q.Fields {
+			if strings.Contains(strings.ToLower(field.Text(t)), text) {
+				return true
+			}
+		}
+		return false
+	case model.TimeQuery:
+		return q.Comparison.Compare(q.Field.Time(t), q.Time)
+	default:
+		return true
+	}
+}
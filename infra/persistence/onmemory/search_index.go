@@ -0,0 +1,103 @@
+package onmemory
+
+import (
+	"unicode"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/search"
+)
+
+// searchIndex is the inverted index from characters and pairs of adjacent characters (bigrams) of todos
+// to their ids. Splitting text into bigrams doesn't depend on spaces between words, so it works for Japanese.
+type searchIndex struct {
+	postings map[string]map[int]struct{}
+	grams    map[int][]string // grams of each todo, to remove it from postings
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{postings: map[string]map[int]struct{}{}, grams: map[int][]string{}}
+}
+
+// add indexes title and description of the todo, replacing the previous ones.
+func (idx *searchIndex) add(todo model.Todo) {
+	idx.remove(todo.ID)
+	grams := map[string]struct{}{}
+	for _, g := range textGrams(todo.Title) {
+		grams[g] = struct{}{}
+	}
+	for _, g := range textGrams(todo.Description) {
+		grams[g] = struct{}{}
+	}
+	for g := range grams {
+		if idx.postings[g] == nil {
+			idx.postings[g] = map[int]struct{}{}
+		}
+		idx.postings[g][todo.ID] = struct{}{}
+		idx.grams[todo.ID] = append(idx.grams[todo.ID], g)
+	}
+}
+
+func (idx *searchIndex) remove(id int) {
+	for _, g := range idx.grams[id] {
+		delete(idx.postings[g], id)
+		if len(idx.postings[g]) == 0 {
+			delete(idx.postings, g)
+		}
+	}
+	delete(idx.grams, id)
+}
+
+// candidates returns ids of todos having all grams of the term. They may not contain the term itself,
+// since the grams can appear apart from each other.
+func (idx *searchIndex) candidates(term string) map[int]struct{} {
+	grams := termGrams(term)
+	if len(grams) == 0 {
+		return nil
+	}
+	ret := map[int]struct{}{}
+	for id := range idx.postings[grams[0]] {
+		ret[id] = struct{}{}
+	}
+	for _, g := range grams[1:] {
+		for id := range ret {
+			if _, ok := idx.postings[g][id]; !ok {
+				delete(ret, id)
+			}
+		}
+	}
+	return ret
+}
+
+// textGrams returns characters and bigrams of the text, except those including spaces.
+func textGrams(s string) []string {
+	runes := search.Fold(s)
+	ret := []string{}
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		ret = append(ret, string(r))
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			ret = append(ret, string(runes[i:i+2]))
+		}
+	}
+	return ret
+}
+
+// termGrams returns grams to look up the term, which are bigrams, or characters if the term is too short.
+func termGrams(s string) []string {
+	runes := search.Fold(s)
+	ret := []string{}
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			ret = append(ret, string(runes[i:i+2]))
+		} else if (i == 0 || unicode.IsSpace(runes[i-1])) && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])) {
+			// a character alone can't be looked up by bigrams
+			ret = append(ret, string(r))
+		}
+	}
+	return ret
+}
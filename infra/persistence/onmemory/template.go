@@ -0,0 +1,95 @@
+package onmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type onmemoryTemplateRepository struct {
+	sync sync.Mutex
+	id   int
+	data []model.Template
+}
+
+func NewOnmemoryTemplateRepository() repository.TemplateRepository {
+	templates := make([]model.Template, 0)
+	return &onmemoryTemplateRepository{data: templates}
+}
+
+func (r *onmemoryTemplateRepository) Create(ctx context.Context, template model.Template) (int, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	r.id += 1
+	now := time.Now()
+	template.ID = r.id
+	template.CreatedAt = now
+	template.UpdatedAt = now
+	template.Items = append([]model.TemplateItem{}, template.Items...)
+	r.data = append(r.data, template)
+	return template.ID, nil
+}
+
+func (r *onmemoryTemplateRepository) Get(ctx context.Context, userID string, id int) (*model.Template, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id && r.data[i].UserID == userID {
+			ret := r.data[i]
+			return &ret, nil
+		}
+	}
+	return nil, utility.NotFound("", fmt.Errorf("template with id %d for user %s is not found", id, userID)).
+		WithCode(utility.ErrorCodeTemplateNotFound)
+}
+
+func (r *onmemoryTemplateRepository) List(ctx context.Context, userID string) ([]*model.Template, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	ret := make([]*model.Template, 0)
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].UserID == userID {
+			template := r.data[i]
+			ret = append(ret, &template)
+		}
+	}
+	return ret, nil
+}
+
+func (r *onmemoryTemplateRepository) Update(ctx context.Context, template *model.Template) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == template.ID {
+			r.data[i] = *template
+			r.data[i].Items = append([]model.TemplateItem{}, template.Items...)
+			r.data[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return utility.NotFound("", fmt.Errorf("template with id %d is not found", template.ID)).
+		WithCode(utility.ErrorCodeTemplateNotFound)
+}
+
+func (r *onmemoryTemplateRepository) Delete(ctx context.Context, id int) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id {
+			r.data = append(r.data[:i], r.data[i+1:]...)
+			return nil
+		}
+	}
+	return utility.NotFound("", fmt.Errorf("template with id %d is not found", id)).
+		WithCode(utility.ErrorCodeTemplateNotFound)
+}
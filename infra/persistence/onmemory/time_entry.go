@@ -0,0 +1,233 @@
+package onmemory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type onmemoryTimeEntryRepository struct {
+	sync sync.Mutex
+	id   int
+	data []model.TimeEntry
+}
+
+// NewOnmemoryTimeEntryRepository returns TimeEntryRepository whose entries are deleted with their todos
+// in todoRepo. It returns an error unless todoRepo is the onmemory one, which can't delete the entries.
+func NewOnmemoryTimeEntryRepository(todoRepo repository.TodoRepository) (repository.TimeEntryRepository, error) {
+	registrar, ok := todoRepo.(cascadeRegistrar)
+	if !ok {
+		return nil, fmt.Errorf("todo repository %T can't delete time entries with todos", todoRepo)
+	}
+	entries := make([]model.TimeEntry, 0)
+	r := &onmemoryTimeEntryRepository{data: entries}
+	registrar.cascade(r.deleteByTodo)
+	return r, nil
+}
+
+func (r *onmemoryTimeEntryRepository) Create(ctx context.Context, entry model.TimeEntry) (int, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	if entry.Running() && r.hasRunning(entry.UserID, 0) {
+		return 0, utility.Conflict("", fmt.Errorf("user %s already has a running timer", entry.UserID)).
+			WithCode(utility.ErrorCodeTimerAlreadyRunning)
+	}
+
+	now := time.Now()
+	r.id += 1
+	entry.ID = r.id
+	r.recordUndo(ctx, entry.ID)
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	r.data = append(r.data, entry)
+	return entry.ID, nil
+}
+
+func (r *onmemoryTimeEntryRepository) Get(ctx context.Context, userID string, id int) (*model.TimeEntry, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id && r.data[i].UserID == userID {
+			ret := r.data[i]
+			return &ret, nil
+		}
+	}
+	return nil, utility.NotFound("", fmt.Errorf("time entry with id %d for user %s is not found", id, userID)).
+		WithCode(utility.ErrorCodeTimeEntryNotFound)
+}
+
+func (r *onmemoryTimeEntryRepository) GetRunning(ctx context.Context, userID string) (*model.TimeEntry, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].UserID == userID && r.data[i].Running() {
+			ret := r.data[i]
+			return &ret, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *onmemoryTimeEntryRepository) List(
+	ctx context.Context, userID string, from, to *time.Time,
+) ([]*model.TimeEntry, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	ret := make([]*model.TimeEntry, 0)
+	for i := 0; i < len(r.data); i++ {
+		entry := r.data[i]
+		if entry.UserID != userID {
+			continue
+		}
+		if from != nil && entry.StartedAt.Before(*from) {
+			continue
+		}
+		if to != nil && !entry.StartedAt.Before(*to) {
+			continue
+		}
+		ret = append(ret, &entry)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].StartedAt.Equal(ret[j].StartedAt) {
+			return ret[i].ID < ret[j].ID
+		}
+		return ret[i].StartedAt.Before(ret[j].StartedAt)
+	})
+	return ret, nil
+}
+
+func (r *onmemoryTimeEntryRepository) Update(ctx context.Context, entry *model.TimeEntry) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	if entry.Running() && r.hasRunning(entry.UserID, entry.ID) {
+		return utility.Conflict("", fmt.Errorf("user %s already has a running timer", entry.UserID)).
+			WithCode(utility.ErrorCodeTimerAlreadyRunning)
+	}
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == entry.ID {
+			r.recordUndo(ctx, entry.ID)
+			r.data[i] = *entry
+			r.data[i].UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return utility.NotFound("", fmt.Errorf("time entry with id %d is not found", entry.ID)).
+		WithCode(utility.ErrorCodeTimeEntryNotFound)
+}
+
+func (r *onmemoryTimeEntryRepository) Delete(ctx context.Context, id int) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id {
+			r.recordUndo(ctx, id)
+			r.data = append(r.data[:i], r.data[i+1:]...)
+			return nil
+		}
+	}
+	return utility.NotFound("", fmt.Errorf("time entry with id %d is not found", id)).
+		WithCode(utility.ErrorCodeTimeEntryNotFound)
+}
+
+// deleteByTodo deletes the entries of the todo, as the foreign key of time_entries cascades.
+func (r *onmemoryTimeEntryRepository) deleteByTodo(ctx context.Context, todoID int) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	entries := make([]model.TimeEntry, 0, len(r.data))
+	for _, entry := range r.data {
+		if entry.TodoID != todoID {
+			entries = append(entries, entry)
+		} else {
+			r.recordUndo(ctx, entry.ID)
+		}
+	}
+	r.data = entries
+}
+
+func (r *onmemoryTimeEntryRepository) TotalDurations(
+	ctx context.Context, todoIDs []int, now time.Time,
+) (map[int]time.Duration, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	ret := make(map[int]time.Duration, len(todoIDs))
+	targets := make(map[int]bool, len(todoIDs))
+	for _, id := range todoIDs {
+		targets[id] = true
+	}
+	for i := 0; i < len(r.data); i++ {
+		if targets[r.data[i].TodoID] {
+			ret[r.data[i].TodoID] += r.data[i].Duration(now)
+		}
+	}
+	return ret, nil
+}
+
+// hasRunning reports whether the user has a running entry other than exceptID.
+// It must be called with the lock held.
+func (r *onmemoryTimeEntryRepository) hasRunning(userID string, exceptID int) bool {
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].UserID == userID && r.data[i].Running() && r.data[i].ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// recordUndo records restoring the entries to the current ones, or removing them if they don't exist now,
+// in the undo log of the context. It must be called with the lock held.
+func (r *onmemoryTimeEntryRepository) recordUndo(ctx context.Context, ids ...int) {
+	prev := make(map[int]*model.TimeEntry, len(ids))
+	for _, id := range ids {
+		prev[id] = nil
+	}
+	for i := 0; i < len(r.data); i++ {
+		if _, ok := prev[r.data[i].ID]; ok {
+			entry := r.data[i]
+			prev[entry.ID] = &entry
+		}
+	}
+
+	recordUndo(ctx, func() {
+		r.sync.Lock()
+		defer r.sync.Unlock()
+
+		for id, entry := range prev {
+			r.restore(id, entry)
+		}
+	})
+}
+
+// restore replaces the entry by the previous one, or removes it if prev is nil. A removed entry is inserted
+// back in the order of ids. It must be called with the lock held.
+func (r *onmemoryTimeEntryRepository) restore(id int, prev *model.TimeEntry) {
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id {
+			if prev == nil {
+				r.data = r.data[:i+copy(r.data[i:], r.data[i+1:])]
+			} else {
+				r.data[i] = *prev
+			}
+			return
+		}
+	}
+	if prev == nil {
+		return
+	}
+	i := sort.Search(len(r.data), func(i int) bool { return r.data[i].ID > id })
+	r.data = append(r.data[:i], append([]model.TimeEntry{*prev}, r.data[i:]...)...)
+}
@@ -3,6 +3,8 @@ package onmemory
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,56 +13,91 @@ import (
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/search"
 )
 
 type onmemoryTodoRepository struct {
-	sync sync.Mutex
-	id   int
-	data []model.Todo
+	sync  sync.Mutex
+	id    int
+	data  []model.Todo
+	index *searchIndex
+	// cascades are called with the ids of deleted todos, so that the repositories of rows referencing todos
+	// delete them as the foreign keys of the database do.
+	cascades []func(ctx context.Context, id int)
 }
 
 func NewOnmemoryTodoRepository() repository.TodoRepository {
 	todos := make([]model.Todo, 0)
-	return &onmemoryTodoRepository{data: todos}
+	return &onmemoryTodoRepository{data: todos, index: newSearchIndex()}
 }
 
 func (r *onmemoryTodoRepository) Create(ctx context.Context, todo model.Todo) (int, error) {
 	r.sync.Lock()
 	defer r.sync.Unlock()
 
+	// new todo is placed at the end of manual order
+	last := ""
+	for _, t := range r.data {
+		if t.UserID == todo.UserID && t.Position > last {
+			last = t.Position
+		}
+	}
+	position, err := model.PositionBetween(last, "")
+	if err != nil {
+		return 0, utility.InternalServerError("can't decide position of todo", err)
+	}
+
 	now := time.Now()
 	r.id += 1
 	todo.ID = r.id
+	r.recordUndo(ctx, todo.ID)
+	todo.Position = position
+	if todo.CustomFields == nil {
+		todo.CustomFields = model.CustomFieldValues{}
+	}
 	todo.CreatedAt = now
 	todo.UpdatedAt = now
+	todo.Version = 1
 	r.data = append(r.data, todo)
+	r.index.add(todo)
 	return todo.ID, nil
 }
 
 func (r *onmemoryTodoRepository) Get(ctx context.Context, userID string, id int) (*model.Todo, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
 	for i := 0; i < len(r.data); i++ {
 		todo := r.data[i]
 		if todo.ID == id {
-			if todo.UserID == userID {
+			if todo.UserID == userID || (todo.AssigneeID != nil && *todo.AssigneeID == userID) {
 				ret := todo
 				return &ret, nil
 			}
-			return nil, utility.NotFound("", fmt.Errorf("todo with id %d for user %s is not found", id, userID))
+			return nil, utility.NotFound("", fmt.Errorf("todo with id %d for user %s is not found", id, userID)).
+				WithCode(utility.ErrorCodeTodoNotFound)
 		}
 	}
-	return nil, utility.NotFound("", fmt.Errorf("todo with id %d for user %s is not found", id, userID))
+	return nil, utility.NotFound("", fmt.Errorf("todo with id %d for user %s is not found", id, userID)).
+		WithCode(utility.ErrorCodeTodoNotFound)
 }
 
 func (r *onmemoryTodoRepository) List(
-	ctx context.Context, userID string, sortBy model.Sorter, orderBy model.Order, includeDone bool,
+	ctx context.Context, userID string, sort []model.SortKey, filter model.TodoFilter, page model.Page,
 ) ([]*model.Todo, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
 	sortedTodos := []model.Todo{}
 	query := linq.From(r.data).WhereT(
 		func(t model.Todo) bool {
+			if filter.AssignedToMe {
+				return t.AssigneeID != nil && *t.AssigneeID == userID
+			}
 			return t.UserID == userID
 		},
 	)
-	if !includeDone {
+	if !filter.IncludeDone {
 		// exclude finished todo
 		query = query.WhereT(
 			func(t model.Todo) bool {
@@ -68,19 +105,94 @@ func (r *onmemoryTodoRepository) List(
 			},
 		)
 	}
+	if !filter.IncludeSnoozed {
+		now := time.Now()
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				return !t.Snoozed(now)
+			},
+		)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				return linq.From(filter.Statuses).Contains(t.Status)
+			},
+		)
+	}
+	if len(filter.Priorities) > 0 {
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				return linq.From(filter.Priorities).Contains(t.Priority)
+			},
+		)
+	}
+	if filter.TitleContains != "" || filter.DescriptionContains != "" {
+		title := strings.ToLower(filter.TitleContains)
+		description := strings.ToLower(filter.DescriptionContains)
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				return strings.Contains(strings.ToLower(t.Title), title) &&
+					strings.Contains(strings.ToLower(t.Description), description)
+			},
+		)
+	}
+	query = query.WhereT(
+		func(t model.Todo) bool {
+			return filter.CreatedAt.Contains(t.CreatedAt) && filter.UpdatedAt.Contains(t.UpdatedAt)
+		},
+	)
+	if filter.AssigneeID != nil {
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				if *filter.AssigneeID == "" {
+					return t.AssigneeID == nil
+				}
+				return t.AssigneeID != nil && *t.AssigneeID == *filter.AssigneeID
+			},
+		)
+	}
+	if len(filter.CustomFields) > 0 {
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				for name, v := range filter.CustomFields {
+					if t.CustomFields[name] != v {
+						return false
+					}
+				}
+				return true
+			},
+		)
+	}
+	if filter.Query != nil {
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				return matchQuery(filter.Query, t)
+			},
+		)
+	}
+	query = query.WhereT(
+		func(t model.Todo) bool {
+			return inRange(t.EstimatePoints, filter.MinEstimatePoints, filter.MaxEstimatePoints) &&
+				inRange(t.EstimateMinutes, filter.MinEstimateMinutes, filter.MaxEstimateMinutes)
+		},
+	)
+	if page.After != nil {
+		after := *page.After
+		query = query.WhereT(
+			func(t model.Todo) bool {
+				return compareTodo(after, t, sort) < 0
+			},
+		)
+	}
 	query.SortT(
 		func(t1, t2 model.Todo) bool {
-			if sortBy == model.SortByID && orderBy == model.OrderByASC {
-				return t1.ID < t2.ID
-			} else if sortBy == model.SortByID && orderBy == model.OrderByDESC {
-				return t1.ID > t2.ID
-			} else if sortBy == model.SortByPriority && orderBy == model.OrderByASC {
-				return t1.Priority < t2.Priority
-			} else {
-				return t1.Priority > t2.Priority
-			}
+			return compareTodo(t1, t2, sort) < 0
 		},
 	).ToSlice(&sortedTodos)
+	if page.Limit > 0 && len(sortedTodos) > page.Limit {
+		sortedTodos = sortedTodos[:page.Limit]
+	}
 
 	ret := make([]*model.Todo, 0, len(sortedTodos))
 	for i := 0; i < len(sortedTodos); i++ {
@@ -93,22 +205,46 @@ func (r *onmemoryTodoRepository) Update(ctx context.Context, todo *model.Todo) e
 	r.sync.Lock()
 	defer r.sync.Unlock()
 
-	processed := false
 	for i := 0; i < len(r.data); i++ {
 		if r.data[i].ID == todo.ID {
+			if r.data[i].Version != todo.Version {
+				return utility.Conflict("", fmt.Errorf("todo with id %d has been modified by another request", todo.ID)).
+					WithCode(utility.ErrorCodeConcurrentModification)
+			}
+			r.recordUndo(ctx, todo.ID)
+			todo.Version++
+			todo.UpdatedAt = time.Now()
+			// position is changed only through Move, so that a concurrent move isn't overwritten
+			position := r.data[i].Position
 			r.data[i] = *todo
-			r.data[i].UpdatedAt = time.Now()
-			processed = true
-			break
+			r.data[i].Position = position
+			r.index.add(r.data[i])
+			return nil
 		}
 	}
-	if !processed {
-		return utility.NotFound("", fmt.Errorf("todo with id %d is not found", todo.ID))
+	return utility.NotFound("", fmt.Errorf("todo with id %d is not found", todo.ID)).
+		WithCode(utility.ErrorCodeTodoNotFound)
+}
+
+func (r *onmemoryTodoRepository) Delete(ctx context.Context, id, version int) error {
+	cascades, err := r.delete(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	// called without the lock, since the other repositories lock themselves
+	for _, cascade := range cascades {
+		cascade(ctx, id)
 	}
 	return nil
 }
 
-func (r *onmemoryTodoRepository) Delete(ctx context.Context, id int) error {
+// delete deletes the todo, and returns the cascades to be called for it.
+func (r *onmemoryTodoRepository) delete(
+	ctx context.Context, id, version int,
+) ([]func(ctx context.Context, id int), error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
 	targetNum := 0
 	found := false
 	for i := 0; i < len(r.data); i++ {
@@ -119,9 +255,328 @@ func (r *onmemoryTodoRepository) Delete(ctx context.Context, id int) error {
 		}
 	}
 	if !found {
-		return utility.NotFound("", fmt.Errorf("todo with id %d is not found", id))
+		return nil, utility.NotFound("", fmt.Errorf("todo with id %d is not found", id)).
+			WithCode(utility.ErrorCodeTodoNotFound)
+	}
+	if r.data[targetNum].Version != version {
+		return nil, utility.Conflict("", fmt.Errorf("todo with id %d has been modified by another request", id)).
+			WithCode(utility.ErrorCodeConcurrentModification)
 	}
 
+	r.recordUndo(ctx, id)
 	r.data = r.data[:targetNum+copy(r.data[targetNum:], r.data[targetNum+1:])]
+	r.index.remove(id)
+	return append([]func(ctx context.Context, id int){}, r.cascades...), nil
+}
+
+// cascadeRegistrar is the todo repository which calls the registered functions with the ids of deleted todos.
+type cascadeRegistrar interface {
+	cascade(fn func(ctx context.Context, id int))
+}
+
+// cascade registers fn called with the ids of deleted todos.
+func (r *onmemoryTodoRepository) cascade(fn func(ctx context.Context, id int)) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	r.cascades = append(r.cascades, fn)
+}
+
+func (r *onmemoryTodoRepository) Move(
+	ctx context.Context, userID string, id, anchorID int, placement model.Placement,
+) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	target := -1
+	anchor := -1
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].UserID != userID {
+			continue
+		}
+		if r.data[i].ID == id {
+			target = i
+		}
+		if r.data[i].ID == anchorID {
+			anchor = i
+		}
+	}
+	if target < 0 {
+		return utility.NotFound("", fmt.Errorf("todo with id %d is not found", id)).
+			WithCode(utility.ErrorCodeTodoNotFound)
+	}
+	if anchor < 0 {
+		return utility.NotFound("", fmt.Errorf("todo with id %d is not found", anchorID)).
+			WithCode(utility.ErrorCodeTodoNotFound)
+	}
+
+	// find the todo next to the anchor on the side where the target is placed
+	anchorPosition := r.data[anchor].Position
+	neighbor := ""
+	for i := 0; i < len(r.data); i++ {
+		t := r.data[i]
+		if t.UserID != userID || i == target {
+			continue
+		}
+		if placement == model.PlaceBefore && t.Position < anchorPosition && t.Position > neighbor {
+			neighbor = t.Position
+		}
+		if placement == model.PlaceAfter && t.Position > anchorPosition && (neighbor == "" || t.Position < neighbor) {
+			neighbor = t.Position
+		}
+	}
+
+	var position string
+	var err error
+	if placement == model.PlaceBefore {
+		position, err = model.PositionBetween(neighbor, anchorPosition)
+	} else {
+		position, err = model.PositionBetween(anchorPosition, neighbor)
+	}
+	if err != nil {
+		return utility.InternalServerError("can't decide position of todo", err)
+	}
+
+	r.recordUndo(ctx, id)
+	r.data[target].Position = position
+	r.data[target].UpdatedAt = time.Now()
+	r.data[target].Version++
+	return nil
+}
+
+func (r *onmemoryTodoRepository) Summarize(ctx context.Context, userID string) ([]*model.EffortSummary, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	type group struct {
+		status   model.Status
+		priority model.Priority
+	}
+	summaries := make(map[group]*model.EffortSummary)
+	ret := make([]*model.EffortSummary, 0)
+	for _, t := range r.data {
+		if t.UserID != userID {
+			continue
+		}
+		g := group{t.Status, t.Priority}
+		summary, ok := summaries[g]
+		if !ok {
+			summary = &model.EffortSummary{Status: t.Status, Priority: t.Priority}
+			summaries[g] = summary
+			ret = append(ret, summary)
+		}
+		summary.Count++
+		if t.EstimatePoints != nil {
+			summary.EstimatePoints += *t.EstimatePoints
+		}
+		if t.EstimateMinutes != nil {
+			summary.EstimateMinutes += *t.EstimateMinutes
+		}
+	}
+	return ret, nil
+}
+
+// compareTodo compares todos by the keys, and then by id in ascending order.
+// Todos without the value of a nullable key come last in both orders as the database does.
+func compareTodo(t1, t2 model.Todo, sort []model.SortKey) int {
+	for _, key := range sort {
+		v1, v2 := key.Sorter.Key(&t1), key.Sorter.Key(&t2)
+		if v1 == nil || v2 == nil {
+			switch {
+			case v1 == nil && v2 == nil:
+				continue
+			case v1 == nil:
+				return 1
+			default:
+				return -1
+			}
+		}
+		if cmp := compareValues(v1, v2); cmp != 0 {
+			if key.Order == model.OrderByDESC {
+				return -cmp
+			}
+			return cmp
+		}
+	}
+	return t1.ID - t2.ID
+}
+
+// compareValues compares values of the same type. Strings are compared byte by byte.
+func compareValues(v1, v2 interface{}) int {
+	switch t1 := v1.(type) {
+	case int:
+		t2, _ := v2.(int)
+		return t1 - t2
+	case float64:
+		t2, _ := v2.(float64)
+		if t1 < t2 {
+			return -1
+		} else if t1 > t2 {
+			return 1
+		}
+		return 0
+	case bool:
+		t2, _ := v2.(bool)
+		if !t1 && t2 {
+			return -1
+		} else if t1 && !t2 {
+			return 1
+		}
+		return 0
+	case time.Time:
+		t2, _ := v2.(time.Time)
+		if t1.Before(t2) {
+			return -1
+		} else if t1.After(t2) {
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(fmt.Sprint(v1), fmt.Sprint(v2))
+	}
+}
+
+// inRange reports whether v is within [min, max]. Nil v is out of any range.
+func inRange(v, min, max *int) bool {
+	if min == nil && max == nil {
+		return true
+	}
+	if v == nil {
+		return false
+	}
+	return (min == nil || *v >= *min) && (max == nil || *v <= *max)
+}
+
+func (r *onmemoryTodoRepository) Search(
+	ctx context.Context, userID string, terms []string, limit int,
+) ([]*model.Todo, error) {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	// the index narrows down candidates, and then they are checked to contain the terms as the database does
+	var candidates map[int]struct{}
+	for _, term := range terms {
+		ids := r.index.candidates(term)
+		if candidates == nil {
+			candidates = ids
+			continue
+		}
+		for id := range candidates {
+			if _, ok := ids[id]; !ok {
+				delete(candidates, id)
+			}
+		}
+	}
+
+	ret := make([]*model.Todo, 0)
+	for i := 0; i < len(r.data); i++ {
+		todo := r.data[i]
+		if todo.UserID != userID {
+			continue
+		}
+		if _, ok := candidates[todo.ID]; candidates != nil && !ok {
+			continue
+		}
+		matched := true
+		for _, term := range terms {
+			if !search.Contains(todo.Title, term) && !search.Contains(todo.Description, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			ret = append(ret, &todo)
+		}
+	}
+
+	scores := make(map[int]float64, len(ret))
+	for _, todo := range ret {
+		scores[todo.ID] = search.Score(todo.Title, todo.Description, terms)
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if scores[ret[i].ID] != scores[ret[j].ID] {
+			return scores[ret[i].ID] > scores[ret[j].ID]
+		}
+		if !ret[i].UpdatedAt.Equal(ret[j].UpdatedAt) {
+			return ret[i].UpdatedAt.After(ret[j].UpdatedAt)
+		}
+		return ret[i].ID < ret[j].ID
+	})
+	if len(ret) > limit {
+		ret = ret[:limit]
+	}
+	return ret, nil
+}
+
+func (r *onmemoryTodoRepository) RemoveCustomField(ctx context.Context, userID string, name string) error {
+	r.sync.Lock()
+	defer r.sync.Unlock()
+
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].UserID != userID {
+			continue
+		}
+		if _, ok := r.data[i].CustomFields[name]; !ok {
+			continue
+		}
+		r.recordUndo(ctx, r.data[i].ID)
+		// values are copied, since the map may be shared with todos returned before
+		values := make(model.CustomFieldValues, len(r.data[i].CustomFields))
+		for k, v := range r.data[i].CustomFields {
+			if k != name {
+				values[k] = v
+			}
+		}
+		r.data[i].CustomFields = values
+		r.data[i].Version++
+	}
 	return nil
 }
+
+// recordUndo records restoring the todos to the current ones, or removing them if they don't exist now,
+// in the undo log of the context. Ids are not restored, as sequences of databases are not rolled back.
+// It must be called with the lock held.
+func (r *onmemoryTodoRepository) recordUndo(ctx context.Context, ids ...int) {
+	prev := make(map[int]*model.Todo, len(ids))
+	for _, id := range ids {
+		prev[id] = nil
+	}
+	for i := 0; i < len(r.data); i++ {
+		if _, ok := prev[r.data[i].ID]; ok {
+			todo := r.data[i]
+			prev[todo.ID] = &todo
+		}
+	}
+
+	recordUndo(ctx, func() {
+		r.sync.Lock()
+		defer r.sync.Unlock()
+
+		for id, todo := range prev {
+			r.restore(id, todo)
+		}
+	})
+}
+
+// restore replaces the todo by the previous one, or removes it if prev is nil. A removed todo is inserted
+// back in the order of ids. It must be called with the lock held.
+func (r *onmemoryTodoRepository) restore(id int, prev *model.Todo) {
+	for i := 0; i < len(r.data); i++ {
+		if r.data[i].ID == id {
+			if prev == nil {
+				r.data = r.data[:i+copy(r.data[i:], r.data[i+1:])]
+				r.index.remove(id)
+			} else {
+				r.data[i] = *prev
+				r.index.add(*prev)
+			}
+			return
+		}
+	}
+	if prev == nil {
+		return
+	}
+	i := sort.Search(len(r.data), func(i int) bool { return r.data[i].ID > id })
+	r.data = append(r.data[:i], append([]model.Todo{*prev}, r.data[i:]...)...)
+	r.index.add(*prev)
+}
@@ -0,0 +1,55 @@
+package onmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+)
+
+// undoLog is the functions undoing the changes made in a savepoint, in the order of the changes.
+type undoLog struct {
+	sync  sync.Mutex
+	undos []func()
+}
+
+// undoLogKey is the key of the undo log of the innermost savepoint in contexts.
+type undoLogKey struct{}
+
+// recordUndo adds the function undoing a change to the log of the savepoint of the context, if any.
+// Repositories call it for each change, so that only the changes of the savepoint are undone.
+func recordUndo(ctx context.Context, undo func()) {
+	log, ok := ctx.Value(undoLogKey{}).(*undoLog)
+	if !ok {
+		return
+	}
+	log.sync.Lock()
+	defer log.sync.Unlock()
+
+	log.undos = append(log.undos, undo)
+}
+
+// onmemoryTransactionRepository undoes changes by replaying the undo log of a savepoint in reverse.
+// Changes made by other requests meanwhile are kept, unless they are of the same todos.
+type onmemoryTransactionRepository struct{}
+
+// NewOnmemoryTransactionRepository returns TransactionRepository which undoes changes of the onmemory
+// todo and time entry repositories.
+func NewOnmemoryTransactionRepository() repository.TransactionRepository {
+	return &onmemoryTransactionRepository{}
+}
+
+func (r *onmemoryTransactionRepository) Savepoint(ctx context.Context, fn func(ctx context.Context) error) error {
+	log := &undoLog{}
+	if err := fn(context.WithValue(ctx, undoLogKey{}, log)); err != nil {
+		for i := len(log.undos) - 1; i >= 0; i-- {
+			log.undos[i]()
+		}
+		return err
+	}
+	// the changes are undone as well if the outer savepoint is rolled back
+	for _, undo := range log.undos {
+		recordUndo(ctx, undo)
+	}
+	return nil
+}
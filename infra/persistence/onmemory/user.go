@@ -39,3 +39,12 @@ func (r *onmemoryUserRepository) Create(ctx context.Context, id, password string
 
 	return nil
 }
+
+func (r *onmemoryUserRepository) Exists(ctx context.Context, id string) (bool, error) {
+	for _, u := range r.data {
+		if id == u.UserID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,83 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+// resolverError is the error returned by a usecase, which is reported with the code of the error catalogue
+// in `extensions` of GraphQL errors, such as `{"code": "todo_not_found", "status": 404}`.
+type resolverError struct {
+	err *utility.HTTPError
+}
+
+func newError(err error) error {
+	var httpErr *utility.HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = utility.InternalServerError(err.Error(), err)
+	}
+	return &resolverError{err: httpErr}
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *resolverError) Extensions() map[string]interface{} {
+	ret := map[string]interface{}{
+		"code":   string(e.err.Code()),
+		"status": e.err.ErrCode(),
+	}
+	if errs := utility.FieldErrors(e.err); len(errs) > 0 {
+		fields := make([]map[string]interface{}, 0, len(errs))
+		for _, fe := range errs {
+			field := map[string]interface{}{"field": fe.Field, "code": fe.Code, "message": fe.Message}
+			if fe.Params != nil {
+				field["params"] = fe.Params
+			}
+			fields = append(fields, field)
+		}
+		ret["errors"] = fields
+	}
+	return ret
+}
+
+// mutationErrors is the errors of the mutations of a request. Mutations are resolved serially, so it isn't locked.
+type mutationErrors struct {
+	errs []error
+}
+
+// mutationErrorsKey is the key of mutationErrors in the context of resolvers.
+type mutationErrorsKey struct{}
+
+// first returns the first error of the mutations, or nil if all of them succeeded.
+func (e *mutationErrors) first() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return e.errs[0]
+}
+
+// mutation returns the resolver which records the error in the context, so that Handler undoes the mutations of
+// the request. A panic is recovered here as an error, since graphql-go recovers it without telling Handler.
+func mutation(resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (ret interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				ret, err = nil, newError(fmt.Errorf("panic in resolver: %v", r))
+			}
+			if errs, ok := p.Context.Value(mutationErrorsKey{}).(*mutationErrors); ok && err != nil {
+				errs.errs = append(errs.errs, err)
+			}
+		}()
+		return resolve(p)
+	}
+}
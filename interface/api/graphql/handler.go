@@ -0,0 +1,69 @@
+package graphql
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type Handler interface {
+	// Query processes the request of `POST /graphql`, which runs a query or mutations of todos.
+	Query(c *gin.Context)
+}
+
+type graphqlHandler struct {
+	schema graphql.Schema
+	txRepo repository.TransactionRepository
+}
+
+// NewHandler returns Handler which resolves todos by u, and undoes mutations by txRepo.
+func NewHandler(u usecase.TodoUsecase, txRepo repository.TransactionRepository) Handler {
+	return &graphqlHandler{schema: NewSchema(u), txRepo: txRepo}
+}
+
+// Request is the structure representation of the request body of `POST /graphql`.
+type Request struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query responds 200 even if fields fail, with the errors in `errors` of the result. Mutations in a request
+// are atomic, since they run in a savepoint which is rolled back if any of them fails. Then the result doesn't
+// have data, so that the undone mutations aren't reported as succeeded.
+func (h *graphqlHandler) Query(c *gin.Context) {
+	var req Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, utility.BadRequest(err.Error(), err))
+		return
+	}
+
+	var res *graphql.Result
+	err := h.txRepo.Savepoint(c, func(ctx context.Context) error {
+		failed := &mutationErrors{}
+		res = graphql.Do(graphql.Params{
+			Schema:         h.schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        context.WithValue(ctx, mutationErrorsKey{}, failed),
+		})
+		return failed.first()
+	})
+	if err != nil {
+		// the transaction of the request is rolled back by DBMiddleware as well
+		_ = c.Error(err)
+		if res == nil {
+			problem.Abort(c, err)
+			return
+		}
+		res.Data = nil
+	}
+	c.JSON(http.StatusOK, res)
+}
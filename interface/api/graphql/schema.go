@@ -0,0 +1,480 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// statusType and priorityType are named as SCREAMING_SNAKE_CASE by the convention of GraphQL, such as NOT_READY.
+var statusType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Status",
+	Values: graphql.EnumValueConfigMap{
+		"NOT_READY": {Value: model.StatusNotReady},
+		"READY":     {Value: model.StatusReady},
+		"DOING":     {Value: model.StatusDoing},
+		"DONE":      {Value: model.StatusDone},
+	},
+})
+
+var priorityType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "Priority",
+	Values: graphql.EnumValueConfigMap{
+		"HIGH":   {Value: model.PriorityHigh},
+		"MIDDLE": {Value: model.PriorityMiddle},
+		"LOW":    {Value: model.PriorityLow},
+	},
+})
+
+func init() {
+	// enums build their lookup tables lazily without locks, so they're built before requests are served
+	statusType.Serialize(model.StatusReady)
+	statusType.ParseValue("READY")
+	priorityType.Serialize(model.PriorityHigh)
+	priorityType.ParseValue("HIGH")
+}
+
+// jsonType is arbitrary JSON such as the values of custom fields, which don't have a fixed schema.
+var jsonType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:         "JSON",
+	Description:  "Arbitrary JSON value.",
+	Serialize:    func(v interface{}) interface{} { return v },
+	ParseValue:   func(v interface{}) interface{} { return v },
+	ParseLiteral: parseJSONLiteral,
+})
+
+// parseJSONLiteral converts the literal in a query to the value as decoded from JSON.
+func parseJSONLiteral(v ast.Value) interface{} {
+	switch v := v.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.IntValue:
+		n, _ := strconv.ParseFloat(v.Value, 64)
+		return n
+	case *ast.FloatValue:
+		n, _ := strconv.ParseFloat(v.Value, 64)
+		return n
+	case *ast.ListValue:
+		ret := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			ret = append(ret, parseJSONLiteral(item))
+		}
+		return ret
+	case *ast.ObjectValue:
+		ret := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			ret[f.Name.Value] = parseJSONLiteral(f.Value)
+		}
+		return ret
+	default:
+		// null and enums
+		return nil
+	}
+}
+
+// todoField returns the field of Todo resolved from *model.Todo by get.
+func todoField(t graphql.Output, description string, get func(todo *model.Todo) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type:        t,
+		Description: description,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*model.Todo)), nil
+		},
+	}
+}
+
+var todoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Todo",
+	Fields: graphql.Fields{
+		"id": todoField(graphql.NewNonNull(graphql.ID), "", func(t *model.Todo) interface{} {
+			return strconv.Itoa(t.ID)
+		}),
+		"title": todoField(graphql.NewNonNull(graphql.String), "", func(t *model.Todo) interface{} {
+			return t.Title
+		}),
+		"description": todoField(graphql.NewNonNull(graphql.String), "", func(t *model.Todo) interface{} {
+			return t.Description
+		}),
+		"status": todoField(graphql.NewNonNull(statusType), "", func(t *model.Todo) interface{} {
+			return t.Status
+		}),
+		"priority": todoField(graphql.NewNonNull(priorityType), "", func(t *model.Todo) interface{} {
+			return t.Priority
+		}),
+		"position": todoField(graphql.NewNonNull(graphql.String), "", func(t *model.Todo) interface{} {
+			return t.Position
+		}),
+		"version": todoField(
+			graphql.NewNonNull(graphql.Int), "Incremented whenever the todo is changed.",
+			func(t *model.Todo) interface{} { return t.Version },
+		),
+		"createdAt": todoField(graphql.NewNonNull(graphql.String), "RFC 3339", func(t *model.Todo) interface{} {
+			return t.CreatedAt.Format(time.RFC3339Nano)
+		}),
+		"updatedAt": todoField(graphql.NewNonNull(graphql.String), "RFC 3339", func(t *model.Todo) interface{} {
+			return t.UpdatedAt.Format(time.RFC3339Nano)
+		}),
+		"trackedSeconds": todoField(
+			graphql.NewNonNull(graphql.Int), "Total of time entries, including the running one.",
+			func(t *model.Todo) interface{} { return int(t.TrackedTime / time.Second) },
+		),
+		"estimatePoints": todoField(graphql.Int, "Null if the todo isn't estimated.", func(t *model.Todo) interface{} {
+			return t.EstimatePoints
+		}),
+		"estimateMinutes": todoField(graphql.Int, "Null if the todo isn't estimated.", func(t *model.Todo) interface{} {
+			return t.EstimateMinutes
+		}),
+		"ownerId": todoField(graphql.NewNonNull(graphql.ID), "", func(t *model.Todo) interface{} {
+			return t.UserID
+		}),
+		"assigneeId": todoField(graphql.ID, "Null if nobody is assigned.", func(t *model.Todo) interface{} {
+			return t.AssigneeID
+		}),
+		"customFields": todoField(
+			graphql.NewNonNull(jsonType), "Values of custom fields keyed by name.",
+			func(t *model.Todo) interface{} {
+				ret := make(map[string]interface{}, len(t.CustomFields))
+				for name, v := range t.CustomFields {
+					ret[name] = v
+				}
+				return ret
+			},
+		),
+		"startAt": todoField(graphql.String, "RFC 3339, null unless the todo is snoozed.", func(t *model.Todo) interface{} {
+			if t.StartAt == nil {
+				return nil
+			}
+			return t.StartAt.Format(time.RFC3339Nano)
+		}),
+		"snoozed": todoField(graphql.NewNonNull(graphql.Boolean), "", func(t *model.Todo) interface{} {
+			return t.Snoozed(time.Now())
+		}),
+	},
+})
+
+var todoConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TodoConnection",
+	Fields: graphql.Fields{
+		"items": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType)))},
+		"nextCursor": &graphql.Field{
+			Type:        graphql.String,
+			Description: "Cursor of the next page, null on the last page.",
+		},
+	},
+})
+
+var todoFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "TodoFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"status":         &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(statusType))},
+		"priority":       &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(priorityType))},
+		"includeDone":    &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"includeSnoozed": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"assignee": &graphql.InputObjectFieldConfig{
+			Type: graphql.String, Description: `"me", "none" or id of the user assigned`,
+		},
+		"title":       &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Substring ignoring case."},
+		"description": &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "Substring ignoring case."},
+		"query": &graphql.InputObjectFieldConfig{
+			Type: graphql.String, Description: `Search query such as "status:ready -priority:low"`,
+		},
+		"createdFrom":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"createdTo":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"updatedFrom":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"updatedTo":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"minEstimatePoints":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"maxEstimatePoints":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"minEstimateMinutes": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"maxEstimateMinutes": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"customFields": &graphql.InputObjectFieldConfig{
+			Type: jsonType, Description: "Values of custom fields to be matched, keyed by name.",
+		},
+	},
+})
+
+var createTodoInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateTodoInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"description":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"status":          &graphql.InputObjectFieldConfig{Type: statusType, DefaultValue: model.StatusNotReady},
+		"priority":        &graphql.InputObjectFieldConfig{Type: priorityType, DefaultValue: model.PriorityMiddle},
+		"estimatePoints":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"estimateMinutes": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"assigneeId":      &graphql.InputObjectFieldConfig{Type: graphql.ID},
+		"customFields":    &graphql.InputObjectFieldConfig{Type: jsonType},
+		"startAt":         &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "RFC 3339 or 2006-01-02"},
+	},
+})
+
+// updateTodoInputType has flags to clear estimates, because null can't be told from omitted fields.
+var updateTodoInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UpdateTodoInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"title":                &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"description":          &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"status":               &graphql.InputObjectFieldConfig{Type: statusType},
+		"priority":             &graphql.InputObjectFieldConfig{Type: priorityType},
+		"estimatePoints":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"clearEstimatePoints":  &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"estimateMinutes":      &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"clearEstimateMinutes": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"assigneeId": &graphql.InputObjectFieldConfig{
+			Type: graphql.ID, Description: "Empty string unassigns the todo.",
+		},
+		"customFields": &graphql.InputObjectFieldConfig{
+			Type: jsonType, Description: "Null value removes the field from the todo.",
+		},
+		"startAt": &graphql.InputObjectFieldConfig{
+			Type: graphql.String, Description: "RFC 3339 or 2006-01-02, empty string wakes the todo up.",
+		},
+	},
+})
+
+// resolver resolves the fields of Query and Mutation with the usecase.
+type resolver struct {
+	u usecase.TodoUsecase
+}
+
+// NewSchema returns the schema of todos resolved by u. It panics if the schema is invalid, which is a bug.
+func NewSchema(u usecase.TodoUsecase) graphql.Schema {
+	r := &resolver{u: u}
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"todo": &graphql.Field{
+					Type:    graphql.NewNonNull(todoType),
+					Args:    graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+					Resolve: r.todo,
+				},
+				"todos": &graphql.Field{
+					Type: graphql.NewNonNull(todoConnectionType),
+					Args: graphql.FieldConfigArgument{
+						"filter": {Type: todoFilterType},
+						"sort": {
+							Type:        graphql.String,
+							Description: `Comma separated keys such as "priority,-updatedAt".`,
+						},
+						"limit":  {Type: graphql.Int, Description: "Null lists all todos."},
+						"cursor": {Type: graphql.String, Description: "nextCursor of the previous page."},
+					},
+					Resolve: r.todos,
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"createTodo": &graphql.Field{
+					Type:    graphql.NewNonNull(todoType),
+					Args:    graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(createTodoInputType)}},
+					Resolve: mutation(r.createTodo),
+				},
+				"updateTodo": &graphql.Field{
+					Type: graphql.NewNonNull(todoType),
+					Args: graphql.FieldConfigArgument{
+						"id":      {Type: graphql.NewNonNull(graphql.ID)},
+						"input":   {Type: graphql.NewNonNull(updateTodoInputType)},
+						"ifMatch": {Type: graphql.Int, Description: "The todo is updated only in the version."},
+					},
+					Resolve: mutation(r.updateTodo),
+				},
+				"deleteTodo": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.ID),
+					Description: "Returns the id of the deleted todo.",
+					Args: graphql.FieldConfigArgument{
+						"id":      {Type: graphql.NewNonNull(graphql.ID)},
+						"ifMatch": {Type: graphql.Int, Description: "The todo is deleted only in the version."},
+					},
+					Resolve: mutation(r.deleteTodo),
+				},
+			},
+		}),
+	})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// userID returns the user authenticated by AuthMiddleware.
+func userID(p graphql.ResolveParams) string {
+	id, _ := p.Context.Value(config.UserIDKey).(string)
+	return id
+}
+
+func (r *resolver) todo(p graphql.ResolveParams) (interface{}, error) {
+	todo, err := r.u.Get(p.Context, userID(p), p.Args["id"].(string))
+	if err != nil {
+		return nil, newError(err)
+	}
+	return todo, nil
+}
+
+func (r *resolver) todos(p graphql.ResolveParams) (interface{}, error) {
+	// sort overrides the default order by id, as in `GET /todos`
+	params := usecase.ListTodoParams{SortBy: string(model.SortByID), OrderBy: string(model.OrderByASC)}
+	params.Sort, _ = p.Args["sort"].(string)
+	params.Cursor, _ = p.Args["cursor"].(string)
+	params.Limit = intArg(p.Args, "limit")
+	if filter, ok := p.Args["filter"].(map[string]interface{}); ok {
+		params.Statuses = joinEnums(filter["status"])
+		params.Priorities = joinEnums(filter["priority"])
+		params.IncludeDone, _ = filter["includeDone"].(bool)
+		params.IncludeSnoozed, _ = filter["includeSnoozed"].(bool)
+		params.Assignee, _ = filter["assignee"].(string)
+		params.Title, _ = filter["title"].(string)
+		params.Description, _ = filter["description"].(string)
+		params.Query, _ = filter["query"].(string)
+		params.CreatedFrom, _ = filter["createdFrom"].(string)
+		params.CreatedTo, _ = filter["createdTo"].(string)
+		params.UpdatedFrom, _ = filter["updatedFrom"].(string)
+		params.UpdatedTo, _ = filter["updatedTo"].(string)
+		params.MinEstimatePoints = intArg(filter, "minEstimatePoints")
+		params.MaxEstimatePoints = intArg(filter, "maxEstimatePoints")
+		params.MinEstimateMinutes = intArg(filter, "minEstimateMinutes")
+		params.MaxEstimateMinutes = intArg(filter, "maxEstimateMinutes")
+		if fields, ok := filter["customFields"].(map[string]interface{}); ok {
+			params.CustomFields = make(map[string]string, len(fields))
+			for name, v := range fields {
+				params.CustomFields[name] = jsonString(v)
+			}
+		}
+	}
+
+	todos, next, err := r.u.List(p.Context, userID(p), params)
+	if err != nil {
+		return nil, newError(err)
+	}
+	ret := map[string]interface{}{"items": todos}
+	if next != "" {
+		ret["nextCursor"] = next
+	}
+	return ret, nil
+}
+
+func (r *resolver) createTodo(p graphql.ResolveParams) (interface{}, error) {
+	input := p.Args["input"].(map[string]interface{})
+	params := usecase.CreateTodoParams{
+		Status:          int(input["status"].(model.Status)),
+		Priority:        int(input["priority"].(model.Priority)),
+		EstimatePoints:  intArg(input, "estimatePoints"),
+		EstimateMinutes: intArg(input, "estimateMinutes"),
+		AssigneeID:      stringArg(input, "assigneeId"),
+	}
+	params.Title, _ = input["title"].(string)
+	params.Description, _ = input["description"].(string)
+	params.CustomFields, _ = input["customFields"].(map[string]interface{})
+	params.StartAt, _ = input["startAt"].(string)
+
+	todo, err := r.u.Create(p.Context, userID(p), params)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return todo, nil
+}
+
+func (r *resolver) updateTodo(p graphql.ResolveParams) (interface{}, error) {
+	input := p.Args["input"].(map[string]interface{})
+	params := usecase.UpdateTodoParams{
+		Title:           stringArg(input, "title"),
+		Description:     stringArg(input, "description"),
+		EstimatePoints:  utility.NullableFrom(intArg(input, "estimatePoints")),
+		EstimateMinutes: utility.NullableFrom(intArg(input, "estimateMinutes")),
+		AssigneeID:      stringArg(input, "assigneeId"),
+		StartAt:         stringArg(input, "startAt"),
+		IfMatch:         ifMatch(p.Args),
+	}
+	if status, ok := input["status"].(model.Status); ok {
+		v := int(status)
+		params.Status = &v
+	}
+	if priority, ok := input["priority"].(model.Priority); ok {
+		v := int(priority)
+		params.Priority = &v
+	}
+	if clear, _ := input["clearEstimatePoints"].(bool); clear {
+		params.EstimatePoints = utility.Null[int]()
+	}
+	if clear, _ := input["clearEstimateMinutes"].(bool); clear {
+		params.EstimateMinutes = utility.Null[int]()
+	}
+	params.CustomFields, _ = input["customFields"].(map[string]interface{})
+
+	todo, err := r.u.Update(p.Context, userID(p), p.Args["id"].(string), params)
+	if err != nil {
+		return nil, newError(err)
+	}
+	return todo, nil
+}
+
+func (r *resolver) deleteTodo(p graphql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	if err := r.u.Delete(p.Context, userID(p), id, ifMatch(p.Args)); err != nil {
+		return nil, newError(err)
+	}
+	return id, nil
+}
+
+// intArg returns the integer argument, or nil if it's omitted.
+func intArg(args map[string]interface{}, name string) *int {
+	if v, ok := args[name].(int); ok {
+		return &v
+	}
+	return nil
+}
+
+// stringArg returns the string argument, or nil if it's omitted.
+func stringArg(args map[string]interface{}, name string) *string {
+	if v, ok := args[name].(string); ok {
+		return &v
+	}
+	return nil
+}
+
+// ifMatch returns the version which the todo must be in, or nil for any version.
+func ifMatch(args map[string]interface{}) []int {
+	if v := intArg(args, "ifMatch"); v != nil {
+		return []int{*v}
+	}
+	return nil
+}
+
+// joinEnums returns the list of statuses or priorities as comma separated numbers, such as "1,2".
+func joinEnums(v interface{}) string {
+	values, _ := v.([]interface{})
+	ret := make([]string, 0, len(values))
+	for _, value := range values {
+		switch value := value.(type) {
+		case model.Status:
+			ret = append(ret, strconv.Itoa(int(value)))
+		case model.Priority:
+			ret = append(ret, strconv.Itoa(int(value)))
+		}
+	}
+	return strings.Join(ret, ",")
+}
+
+// jsonString returns the value of a custom field in the format of query parameters.
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// BatchHandler is API interface of batch operations of todos.
+type BatchHandler interface {
+	Run(c *gin.Context)
+}
+
+// batchHandler is a structure that implements BatchHandler.
+type batchHandler struct {
+	u usecase.BatchUsecase
+}
+
+func NewBatchHandler(u usecase.BatchUsecase) BatchHandler {
+	return &batchHandler{u: u}
+}
+
+// BatchRequest is the structure representation of the request body of `POST /todos/batch`.
+type BatchRequest struct {
+	Mode       string                  `json:"mode"` // "atomic" (default) or "bestEffort"
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+// BatchOperationRequest is the structure representation of an operation in the request body of `POST /todos/batch`.
+type BatchOperationRequest struct {
+	Op      string              `json:"op"`                // "create", "update", "delete" or "updateMatching"
+	ID      string              `json:"id,omitempty"`      // the todo to be updated or deleted
+	Version *int                `json:"version,omitempty"` // the version which the todo must be in, as If-Match
+	Todo    *CreateTodoRequest  `json:"todo,omitempty"`    // the todo to be created
+	Fields  *UpdateTodoRequest  `json:"fields,omitempty"`  // the fields to be updated by update and updateMatching
+	Filter  *BatchFilterRequest `json:"filter,omitempty"`  // the todos to be updated by updateMatching
+}
+
+// BatchFilterRequest is the structure representation of the todos updated by `updateMatching` operation.
+// The fields are the same as the query of `GET /todos`.
+type BatchFilterRequest struct {
+	Q              string            `json:"q,omitempty"`
+	CustomFields   map[string]string `json:"cf,omitempty"`
+	Assignee       string            `json:"assignee,omitempty"`
+	IncludeDone    bool              `json:"includeDone,omitempty"`
+	IncludeSnoozed bool              `json:"includeSnoozed,omitempty"`
+}
+
+func (r BatchOperationRequest) toParams() usecase.BatchOperationParams {
+	params := usecase.BatchOperationParams{Op: r.Op, ID: r.ID}
+	if r.Version != nil {
+		params.IfMatch = []int{*r.Version}
+	}
+	if r.Todo != nil {
+		params.Create = r.Todo.toParams()
+	}
+	if r.Fields != nil {
+		params.Update = r.Fields.toParams()
+	}
+	if r.Filter != nil {
+		params.Filter = usecase.ListTodoParams{
+			Query:          r.Filter.Q,
+			CustomFields:   r.Filter.CustomFields,
+			Assignee:       r.Filter.Assignee,
+			IncludeDone:    r.Filter.IncludeDone,
+			IncludeSnoozed: r.Filter.IncludeSnoozed,
+		}
+	}
+	return params
+}
+
+// BatchResponse is the structure representation of the response body of `POST /todos/batch`.
+type BatchResponse struct {
+	Committed bool                  `json:"committed"` // false if an operation failed in atomic mode
+	Results   []BatchResultResponse `json:"results"`   // in the order of the operations
+}
+
+// BatchResultResponse is the structure representation of the result of an operation.
+type BatchResultResponse struct {
+	Result  string                     `json:"result"`            // "succeeded", "failed", "rolledBack" or "skipped"
+	Status  int                        `json:"status,omitempty"`  // status code as if the operation is requested alone
+	Entries []TodoResponse             `json:"entries,omitempty"` // todos created or updated
+	Error   *servermodel.ErrorResponse `json:"error,omitempty"`
+}
+
+func buildBatchResultResponse(result *usecase.BatchResult, version int) BatchResultResponse {
+	if result.Skipped {
+		return BatchResultResponse{Result: "skipped"}
+	}
+	if result.Err != nil {
+		res := problem.ErrorResponse(result.Err)
+		return BatchResultResponse{Result: "failed", Status: res.ErrCode, Error: &res}
+	}
+
+	res := BatchResultResponse{Result: "succeeded", Status: http.StatusOK}
+	if result.RolledBack {
+		res.Result = "rolledBack"
+	}
+	if result.Op == usecase.BatchOpCreate {
+		res.Status = http.StatusCreated
+	}
+	for _, todo := range result.Todos {
+		res.Entries = append(res.Entries, buildTodoResponse(todo, version))
+	}
+	return res
+}
+
+// Run processes the request of `POST /todos/batch`.
+// The response is 200 unless an operation fails in atomic mode, when the status of the operation is responded.
+func (h *batchHandler) Run(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	json := BatchRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+	operations := make([]usecase.BatchOperationParams, 0, len(json.Operations))
+	for _, op := range json.Operations {
+		operations = append(operations, op.toParams())
+	}
+
+	results, err := h.u.Run(c, userID, json.Mode, operations)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	status := http.StatusOK
+	res := BatchResponse{Committed: true, Results: make([]BatchResultResponse, 0, len(results))}
+	for _, result := range results {
+		r := buildBatchResultResponse(result, apiVersion(c))
+		if r.Error != nil && usecase.BatchMode(json.Mode) != usecase.BatchModeBestEffort {
+			// the whole batch is rolled back in atomic mode
+			status = r.Status
+			res.Committed = false
+		}
+		res.Results = append(res.Results, r)
+	}
+	c.JSON(status, batchResponse(apiVersion(c), res, results, c.Request.URL.Path))
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+// etag returns the strong entity tag of the todo, such as `"3-120"`. It consists of the version and the values
+// which change by time without changing the todo, that is the tracked time and whether the todo is snoozed.
+func etag(todo *model.Todo, now time.Time) string {
+	tag := fmt.Sprintf("%d-%d", todo.Version, int64(todo.TrackedTime/time.Second))
+	if todo.Snoozed(now) {
+		tag += "-snoozed"
+	}
+	return `"` + tag + `"`
+}
+
+// listETag returns the strong entity tag of a page of todos, which is the hash of the tags of the todos,
+// so that it's computed without serializing the page.
+func listETag(todos []*model.Todo, nextCursor string, now time.Time) string {
+	h := fnv.New64a()
+	for _, todo := range todos {
+		_, _ = h.Write([]byte(strconv.Itoa(todo.ID) + ":" + etag(todo, now) + ","))
+	}
+	_, _ = h.Write([]byte(nextCursor))
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+// lastModified returns the time when any of the todos was updated last. It doesn't reflect deleted todos
+// nor changes of tracked time, so clients should prefer ETag.
+func lastModified(todos ...*model.Todo) time.Time {
+	ret := time.Time{}
+	for _, todo := range todos {
+		if todo.UpdatedAt.After(ret) {
+			ret = todo.UpdatedAt
+		}
+	}
+	return ret
+}
+
+// checkNotModified sets the validators of the response, and sends 304 Not Modified if the client has
+// the representation already by If-None-Match or If-Modified-Since. It reports whether 304 is sent.
+func checkNotModified(c *gin.Context, tag string, modified time.Time) bool {
+	c.Header("ETag", tag)
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	// responses depend on the user, and must be revalidated since todos can be changed anytime
+	c.Header("Cache-Control", "private, no-cache")
+
+	// If-Modified-Since is ignored if If-None-Match is given, as RFC 9110 says
+	if header := c.GetHeader("If-None-Match"); header != "" {
+		if !matchETag(header, tag) {
+			return false
+		}
+	} else if header := c.GetHeader("If-Modified-Since"); header != "" {
+		since, err := http.ParseTime(header)
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	} else {
+		return false
+	}
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// matchETag reports whether If-None-Match header contains the tag. Weak tags match as well,
+// as If-None-Match uses the weak comparison.
+func matchETag(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIfMatch returns the versions in If-Match header, or nil if the header is missing or `*`.
+// Only versions of tags are compared, so that todos can be updated while their timers are running.
+// Weak tags never match, as If-Match uses the strong comparison.
+func parseIfMatch(c *gin.Context) []int {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return nil
+	}
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil
+		}
+		if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		version := strings.SplitN(tag[1:len(tag)-1], "-", 2)[0]
+		if v, err := strconv.Atoi(version); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
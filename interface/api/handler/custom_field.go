@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// CustomFieldHandler is API interface of custom field service.
+type CustomFieldHandler interface {
+	Create(c *gin.Context)
+	List(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+// customFieldHandler is a structure that implements CustomFieldHandler.
+type customFieldHandler struct {
+	u usecase.CustomFieldUsecase
+}
+
+func NewCustomFieldHandler(u usecase.CustomFieldUsecase) CustomFieldHandler {
+	return &customFieldHandler{u: u}
+}
+
+// CreateCustomFieldRequest is the structure representation of the request body of `POST /custom-fields`.
+type CreateCustomFieldRequest struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`              // "text", "number", "date", "enum" or "bool"
+	Options []string `json:"options,omitempty"` // choices of enum
+}
+
+// CustomFieldResponse is the structure representation of the response of custom field information.
+type CustomFieldResponse struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Options   []string `json:"options"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+func buildCustomFieldResponse(field *model.CustomField) CustomFieldResponse {
+	options := field.Options
+	if options == nil {
+		options = []string{}
+	}
+	return CustomFieldResponse{
+		ID:        strconv.Itoa(field.ID),
+		Name:      field.Name,
+		Type:      string(field.Type),
+		Options:   options,
+		CreatedAt: field.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// Create processes the request of `POST /custom-fields`.
+func (h *customFieldHandler) Create(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	json := CreateCustomFieldRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+
+	field, err := h.u.Create(c, userID, json.Name, json.Type, json.Options)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, buildCustomFieldResponse(field))
+}
+
+// ListCustomFieldResponse is the structure representation of the response body of `GET /custom-fields`.
+type ListCustomFieldResponse struct {
+	Entries []CustomFieldResponse `json:"entries"`
+}
+
+// List processes the request of `GET /custom-fields`.
+func (h *customFieldHandler) List(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	fields, err := h.u.List(c, userID)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	res := make([]CustomFieldResponse, 0, len(fields))
+	for _, field := range fields {
+		res = append(res, buildCustomFieldResponse(field))
+	}
+	c.JSON(http.StatusOK, ListCustomFieldResponse{res})
+}
+
+// Delete processes the request of `DELETE /custom-fields/:id`.
+// Values of the field are removed from all todos as well.
+func (h *customFieldHandler) Delete(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	fieldID := c.Param("id")
+
+	if err := h.u.Delete(c, userID, fieldID); err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, servermodel.MessageResponse{Message: fmt.Sprintf("custom field %s is deleted", fieldID)})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+)
+
+// StatusValue is a status in requests, given as the number such as 2 or the name such as "ready" ignoring case.
+type StatusValue int
+
+func (s *StatusValue) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		// numbers are validated by usecases as before names are supported
+		*s = StatusValue(n)
+		return nil
+	}
+	choices := []string{}
+	for status := model.StatusNotReady; status <= model.StatusDone; status++ {
+		choices = append(choices, fmt.Sprint(int(status)), status.Name())
+	}
+	status, err := unmarshalEnumName(b, "status", choices, func(name string) int {
+		return int(model.ParseStatus(name))
+	})
+	*s = StatusValue(status)
+	return err
+}
+
+// PriorityValue is a priority in requests, given as the number such as 1 or the name such as "high" ignoring case.
+type PriorityValue int
+
+func (p *PriorityValue) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*p = PriorityValue(n)
+		return nil
+	}
+	choices := []string{}
+	for priority := model.PriorityHigh; priority <= model.PriorityLow; priority++ {
+		choices = append(choices, fmt.Sprint(int(priority)), priority.Name())
+	}
+	priority, err := unmarshalEnumName(b, "priority", choices, func(name string) int {
+		return int(model.ParsePriority(name))
+	})
+	*p = PriorityValue(priority)
+	return err
+}
+
+// enumError is an unknown name of an enum. It's reported as the violation of the field named after the enum,
+// since encoding/json doesn't tell the field to errors of UnmarshalJSON.
+type enumError struct {
+	field   string
+	value   string
+	choices []string
+}
+
+func (e *enumError) Error() string {
+	return fmt.Sprintf("%s must be one of %s, but %s", e.field, strings.Join(e.choices, ", "), e.value)
+}
+
+// unmarshalEnumName returns the number of the name parsed by parse, which returns 0 for unknown names.
+func unmarshalEnumName(b []byte, field string, choices []string, parse func(string) int) (int, error) {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		// neither a number nor a string
+		return 0, &json.UnmarshalTypeError{Value: string(b), Type: reflect.TypeOf(name), Field: field}
+	}
+	if n := parse(name); n != 0 {
+		return n, nil
+	}
+	return 0, &enumError{field: field, value: name, choices: choices}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+func init() {
+	// report fields of binding errors by the names in requests instead of Go's
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
+
+// sendBindErrorResponse sends the error of binding a request with the violations of its fields.
+func sendBindErrorResponse(c *gin.Context, err error) {
+	cause := err
+	if errs := bindFieldErrors(err); errs != nil {
+		cause = &utility.ValidationError{Errors: errs}
+	}
+	problem.Abort(c, utility.BadRequest(err.Error(), cause))
+}
+
+// bindFieldErrors converts the error of binding to violations of fields, or nil if fields are unknown.
+func bindFieldErrors(err error) []*utility.FieldError {
+	var enumErr *enumError
+	if errors.As(err, &enumErr) {
+		return []*utility.FieldError{{
+			Field:   enumErr.field,
+			Code:    utility.CodeInvalidChoice,
+			Message: enumErr.Error(),
+			Params:  map[string]interface{}{"choices": enumErr.choices},
+		}}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []*utility.FieldError{{
+			Field:   typeErr.Field,
+			Code:    utility.CodeInvalidType,
+			Message: err.Error(),
+			Params:  map[string]interface{}{"expected": typeErr.Type.String()},
+		}}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+	ret := make([]*utility.FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		// namespace is such as `CreateTodoRequest.title`
+		field := fe.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		res := &utility.FieldError{Field: field, Code: utility.CodeInvalid, Message: fe.Error()}
+		switch fe.Tag() {
+		case "required":
+			res.Code = utility.CodeRequired
+			res.Message = field + " is required"
+		case "oneof":
+			res.Code = utility.CodeInvalidChoice
+			res.Params = map[string]interface{}{"choices": strings.Fields(fe.Param())}
+			res.Message = field + " must be one of " + fe.Param()
+		}
+		ret = append(ret, res)
+	}
+	return ret
+}
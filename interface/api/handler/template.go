@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// TemplateHandler is API interface of todo template service.
+type TemplateHandler interface {
+	Create(c *gin.Context)
+	Get(c *gin.Context)
+	List(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+	Instantiate(c *gin.Context)
+}
+
+// templateHandler is a structure that implements TemplateHandler.
+type templateHandler struct {
+	u usecase.TemplateUsecase
+}
+
+func NewTemplateHandler(u usecase.TemplateUsecase) TemplateHandler {
+	return &templateHandler{u: u}
+}
+
+// TemplateItemRequest is the structure representation of a todo in the request body of templates.
+// Title and description can contain placeholders such as `{{name}}`, and `{{date}}` is today unless specified.
+type TemplateItemRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Status is 1: Not Ready (default), 2: Ready, 3: Doing, 4: Done or the name such as "not-ready".
+	Status *StatusValue `json:"status,omitempty"`
+	// Priority is 1: High, 2: Middle (default), 3: Low or the name such as "high".
+	Priority *PriorityValue `json:"priority,omitempty"`
+
+	EstimatePoints  *int `json:"estimatePoints,omitempty"`
+	EstimateMinutes *int `json:"estimateMinutes,omitempty"`
+}
+
+func (r TemplateItemRequest) toParams() usecase.TemplateItemParams {
+	params := usecase.TemplateItemParams{
+		Title:           r.Title,
+		Description:     r.Description,
+		Status:          int(model.StatusNotReady),
+		Priority:        int(model.PriorityMiddle),
+		EstimatePoints:  r.EstimatePoints,
+		EstimateMinutes: r.EstimateMinutes,
+	}
+	if r.Status != nil {
+		params.Status = int(*r.Status)
+	}
+	if r.Priority != nil {
+		params.Priority = int(*r.Priority)
+	}
+	return params
+}
+
+func toTemplateItemParams(items []TemplateItemRequest) []usecase.TemplateItemParams {
+	if items == nil {
+		return nil
+	}
+	params := make([]usecase.TemplateItemParams, 0, len(items))
+	for _, item := range items {
+		params = append(params, item.toParams())
+	}
+	return params
+}
+
+// CreateTemplateRequest is the structure representation of the request body of `POST /templates`.
+type CreateTemplateRequest struct {
+	Name  string                `json:"name"`
+	Items []TemplateItemRequest `json:"items" binding:"required,dive"`
+}
+
+// TemplateItemResponse is the structure representation of a todo in the response of template information.
+type TemplateItemResponse struct {
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	Status          int    `json:"status"`
+	Priority        int    `json:"priority"`
+	StatusName      string `json:"statusName"`
+	PriorityName    string `json:"priorityName"`
+	EstimatePoints  *int   `json:"estimatePoints"`
+	EstimateMinutes *int   `json:"estimateMinutes"`
+}
+
+// TemplateResponse is the structure representation of the response of template information.
+type TemplateResponse struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Items     []TemplateItemResponse `json:"items"`
+	CreatedAt string                 `json:"createdAt"`
+	UpdatedAt string                 `json:"updatedAt"`
+}
+
+func buildTemplateResponse(template *model.Template) TemplateResponse {
+	items := make([]TemplateItemResponse, 0, len(template.Items))
+	for _, item := range template.Items {
+		items = append(items, TemplateItemResponse{
+			Title:           item.Title,
+			Description:     item.Description,
+			Status:          int(item.Status),
+			Priority:        int(item.Priority),
+			StatusName:      item.Status.Name(),
+			PriorityName:    item.Priority.Name(),
+			EstimatePoints:  item.EstimatePoints,
+			EstimateMinutes: item.EstimateMinutes,
+		})
+	}
+	return TemplateResponse{
+		ID:        strconv.Itoa(template.ID),
+		Name:      template.Name,
+		Items:     items,
+		CreatedAt: template.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt: template.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// Create processes the request of `POST /templates`.
+func (h *templateHandler) Create(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	json := CreateTemplateRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+
+	template, err := h.u.Create(c, userID, json.Name, toTemplateItemParams(json.Items))
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, buildTemplateResponse(template))
+}
+
+// Get processes the request of `GET /templates/:id`.
+func (h *templateHandler) Get(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	templateID := c.Param("id")
+
+	template, err := h.u.Get(c, userID, templateID)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTemplateResponse(template))
+}
+
+// ListTemplateResponse is the structure representation of the response body of `GET /templates`.
+type ListTemplateResponse struct {
+	Entries []TemplateResponse `json:"entries"`
+}
+
+// List processes the request of `GET /templates`.
+func (h *templateHandler) List(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	templates, err := h.u.List(c, userID)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	res := make([]TemplateResponse, 0, len(templates))
+	for _, template := range templates {
+		res = append(res, buildTemplateResponse(template))
+	}
+	c.JSON(http.StatusOK, ListTemplateResponse{res})
+}
+
+// UpdateTemplateRequest is the structure representation of the request body of `PATCH /templates/:id`.
+type UpdateTemplateRequest struct {
+	Name  *string               `json:"name,omitempty"`
+	Items []TemplateItemRequest `json:"items,omitempty" binding:"omitempty,dive"` // replaces all items
+}
+
+// Update processes the request of `PATCH /templates/:id`.
+func (h *templateHandler) Update(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	templateID := c.Param("id")
+
+	json := UpdateTemplateRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+
+	template, err := h.u.Update(c, userID, templateID, json.Name, toTemplateItemParams(json.Items))
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTemplateResponse(template))
+}
+
+// Delete processes the request of `DELETE /templates/:id`.
+func (h *templateHandler) Delete(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	templateID := c.Param("id")
+
+	if err := h.u.Delete(c, userID, templateID); err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, servermodel.MessageResponse{Message: fmt.Sprintf("template %s is deleted", templateID)})
+}
+
+// InstantiateTemplateRequest is the structure representation of the request body of `POST /templates/:id/instantiate`.
+type InstantiateTemplateRequest struct {
+	// Variables is the values of placeholders keyed by name. All placeholders except date must be specified.
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+// Instantiate processes the request of `POST /templates/:id/instantiate`.
+// The response has the todos created for the items of the template, in the same order.
+func (h *templateHandler) Instantiate(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	templateID := c.Param("id")
+
+	json := InstantiateTemplateRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+
+	todos, err := h.u.Instantiate(c, userID, templateID, json.Variables)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	res := make([]TodoResponse, 0, len(todos))
+	for _, todo := range todos {
+		res = append(res, buildTodoResponse(todo, apiVersion(c)))
+	}
+	c.JSON(http.StatusCreated, listTodoResponse(apiVersion(c), res, nil))
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// TimeEntryHandler is API interface of time tracking service.
+type TimeEntryHandler interface {
+	Start(c *gin.Context)
+	Stop(c *gin.Context)
+	List(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+// timeEntryHandler is a structure that implements TimeEntryHandler.
+type timeEntryHandler struct {
+	u usecase.TimeEntryUsecase
+}
+
+func NewTimeEntryHandler(u usecase.TimeEntryUsecase) TimeEntryHandler {
+	return &timeEntryHandler{u: u}
+}
+
+// TimeEntryResponse is the structure representation of the response of time entry information.
+type TimeEntryResponse struct {
+	ID              string `json:"id"`
+	TodoID          string `json:"todoId"`
+	StartedAt       string `json:"startedAt"`
+	StoppedAt       string `json:"stoppedAt,omitempty"` // empty while the timer is running
+	Running         bool   `json:"running"`
+	DurationSeconds int64  `json:"durationSeconds"`
+}
+
+func buildTimeEntryResponse(entry *model.TimeEntry, now time.Time) TimeEntryResponse {
+	res := TimeEntryResponse{
+		ID:              strconv.Itoa(entry.ID),
+		TodoID:          strconv.Itoa(entry.TodoID),
+		StartedAt:       entry.StartedAt.Format(time.RFC3339Nano),
+		Running:         entry.Running(),
+		DurationSeconds: int64(entry.Duration(now) / time.Second),
+	}
+	if entry.StoppedAt != nil {
+		res.StoppedAt = entry.StoppedAt.Format(time.RFC3339Nano)
+	}
+	return res
+}
+
+// Start processes the request of `POST /todos/:id/timer/start`.
+func (h *timeEntryHandler) Start(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	todoID := c.Param("id")
+
+	entry, err := h.u.Start(c, userID, todoID)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTimeEntryResponse(entry, time.Now()))
+}
+
+// Stop processes the request of `POST /todos/:id/timer/stop`.
+func (h *timeEntryHandler) Stop(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	todoID := c.Param("id")
+
+	entry, err := h.u.Stop(c, userID, todoID)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTimeEntryResponse(entry, time.Now()))
+}
+
+// ListTimeEntryRequest is the structure representation of the request query of `GET /time-entries`.
+type ListTimeEntryRequest struct {
+	From string `form:"from"` // RFC 3339 or 2006-01-02, inclusive
+	To   string `form:"to"`   // RFC 3339 (exclusive) or 2006-01-02 (inclusive)
+}
+
+// ListTimeEntryResponse is the structure representation of the response body of `GET /time-entries`.
+type ListTimeEntryResponse struct {
+	Entries      []TimeEntryResponse `json:"entries"`
+	TotalSeconds int64               `json:"totalSeconds"`
+}
+
+// List processes the request of `GET /time-entries`.
+func (h *timeEntryHandler) List(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	query := ListTimeEntryRequest{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+
+	entries, err := h.u.List(c, userID, query.From, query.To)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	now := time.Now()
+	res := ListTimeEntryResponse{Entries: make([]TimeEntryResponse, 0, len(entries))}
+	for _, entry := range entries {
+		e := buildTimeEntryResponse(entry, now)
+		res.Entries = append(res.Entries, e)
+		res.TotalSeconds += e.DurationSeconds
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// UpdateTimeEntryRequest is the structure representation of the request body of `PATCH /time-entries/:id`.
+type UpdateTimeEntryRequest struct {
+	StartedAt *string `json:"startedAt,omitempty"` // RFC 3339
+	StoppedAt *string `json:"stoppedAt,omitempty"` // RFC 3339
+}
+
+// Update processes the request of `PATCH /time-entries/:id`.
+func (h *timeEntryHandler) Update(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	entryID := c.Param("id")
+
+	json := UpdateTimeEntryRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+	entry, err := h.u.Update(c, userID, entryID, json.StartedAt, json.StoppedAt)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTimeEntryResponse(entry, time.Now()))
+}
+
+// Delete processes the request of `DELETE /time-entries/:id`.
+func (h *timeEntryHandler) Delete(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	entryID := c.Param("id")
+
+	if err := h.u.Delete(c, userID, entryID); err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, servermodel.MessageResponse{Message: fmt.Sprintf("time entry %s is deleted", entryID)})
+}
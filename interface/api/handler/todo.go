@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,9 +10,11 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
 	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/markdown"
 )
 
 // TodoHandler is API interface of Todo service.
@@ -22,6 +24,11 @@ type TodoHandler interface {
 	List(c *gin.Context)
 	Update(c *gin.Context)
 	Delete(c *gin.Context)
+	Move(c *gin.Context)
+	Summary(c *gin.Context)
+	Snooze(c *gin.Context)
+	ToggleTask(c *gin.Context)
+	Search(c *gin.Context)
 }
 
 // todoHandler is a structure that implements TodoHandler.
@@ -35,10 +42,45 @@ func NewTodoHandler(u usecase.TodoUsecase) TodoHandler {
 
 // CreateTodoRequest is the structure representation of the request body of `POST /todos`.
 type CreateTodoRequest struct {
-	Title       string `json:"title" binding:"required"`
+	Title       string `json:"title"`
 	Description string `json:"description"`
-	Status      int    `json:"status,omitempty"`   // 1: Not Ready, 2: Ready, 3: Doing, 4: Done
-	Priority    int    `json:"priority,omitempty"` // 1: High, 2: Middle, 3: Low
+	// Status is 1: Not Ready, 2: Ready, 3: Doing, 4: Done or the name such as "not-ready".
+	Status StatusValue `json:"status,omitempty"`
+	// Priority is 1: High, 2: Middle, 3: Low or the name such as "high".
+	Priority PriorityValue `json:"priority,omitempty"`
+
+	EstimatePoints  *int    `json:"estimatePoints,omitempty"`  // story points
+	EstimateMinutes *int    `json:"estimateMinutes,omitempty"` // minutes
+	AssigneeID      *string `json:"assigneeId,omitempty"`
+
+	CustomFields map[string]interface{} `json:"customFields,omitempty"` // keyed by name of custom field
+	StartAt      string                 `json:"startAt,omitempty"`      // RFC 3339 or 2006-01-02, hidden until then
+}
+
+// UnmarshalJSON defaults omitted status and priority, so that todos in the requests of batches are bound
+// as the ones of `POST /todos`, while explicit 0 is validated as other invalid values.
+func (r *CreateTodoRequest) UnmarshalJSON(b []byte) error {
+	type plain CreateTodoRequest // without UnmarshalJSON
+	req := plain{Status: StatusValue(model.StatusNotReady), Priority: PriorityValue(model.PriorityMiddle)}
+	if err := json.Unmarshal(b, &req); err != nil {
+		return err
+	}
+	*r = CreateTodoRequest(req)
+	return nil
+}
+
+func (r CreateTodoRequest) toParams() usecase.CreateTodoParams {
+	return usecase.CreateTodoParams{
+		Title:           r.Title,
+		Description:     r.Description,
+		Status:          int(r.Status),
+		Priority:        int(r.Priority),
+		EstimatePoints:  r.EstimatePoints,
+		EstimateMinutes: r.EstimateMinutes,
+		AssigneeID:      r.AssigneeID,
+		CustomFields:    r.CustomFields,
+		StartAt:         r.StartAt,
+	}
 }
 
 // TodoResponse is the structure representation of the response of Todo information.
@@ -48,44 +90,109 @@ type TodoResponse struct {
 	Description string `json:"description"`
 	Status      int    `json:"status"`   // 1: Not Ready, 2: Ready, 3: Doing, 4: Done
 	Priority    int    `json:"priority"` // 1: High, 2: Middle, 3: Low
-	CreatedAt   string `json:"createAt"`
+	// StatusName and PriorityName are the names of status and priority, such as "not-ready" and "high".
+	StatusName   string `json:"statusName"`
+	PriorityName string `json:"priorityName"`
+	Position     string `json:"position"`
+	Version      int    `json:"version"` // incremented whenever the todo is changed, the first part of ETag
+	// CreatedAt is misspelled as `createAt` in v1 for compatibility, and CreatedAtV2 replaces it since v2.
+	CreatedAt   string `json:"createAt,omitempty" openapi:"v1"`
+	CreatedAtV2 string `json:"createdAt,omitempty" openapi:"v2"`
 	UpdatedAt   string `json:"updatedAt"`
+	// TrackedSeconds is the total of time entries of the todo, including the running one.
+	TrackedSeconds int64 `json:"trackedSeconds"`
+	// estimates are null if the todo isn't estimated.
+	EstimatePoints  *int `json:"estimatePoints"`
+	EstimateMinutes *int `json:"estimateMinutes"`
+	// OwnerID is the user who created the todo, and AssigneeID is null if nobody is assigned.
+	OwnerID    string  `json:"ownerId"`
+	AssigneeID *string `json:"assigneeId"`
+	// CustomFields is the values of custom fields keyed by name, and fields without value are omitted.
+	CustomFields map[string]interface{} `json:"customFields"`
+	// StartAt is null unless the todo is snoozed, and Snoozed is true until the time comes.
+	StartAt *string `json:"startAt"`
+	Snoozed bool    `json:"snoozed"`
+	// DescriptionHTML is the description rendered from Markdown, only if `render=html` is requested.
+	DescriptionHTML *string `json:"descriptionHtml,omitempty"`
 }
 
-func buildTodoResponse(todo *model.Todo) TodoResponse {
-	return TodoResponse{
-		ID:          strconv.Itoa(todo.ID),
-		Title:       todo.Title,
-		Description: todo.Description,
-		Status:      int(todo.Status),
-		Priority:    int(todo.Priority),
-		CreatedAt:   todo.CreatedAt.Format(time.RFC3339Nano),
-		UpdatedAt:   todo.UpdatedAt.Format(time.RFC3339Nano),
+// renderHTML is the value of `render` query to render descriptions as HTML.
+const renderHTML = "html"
+
+// RenderRequest is the structure representation of the request query to render todos.
+type RenderRequest struct {
+	Render string `form:"render" binding:"omitempty,oneof=html"`
+}
+
+// withDescriptionHTML fills DescriptionHTML of the response if it's requested.
+func withDescriptionHTML(res TodoResponse, render string) (TodoResponse, error) {
+	if render != renderHTML {
+		return res, nil
+	}
+	html, err := markdown.RenderHTML(res.Description)
+	if err != nil {
+		return res, err
 	}
+	res.DescriptionHTML = &html
+	return res, nil
+}
+
+func buildTodoResponse(todo *model.Todo, version int) TodoResponse {
+	customFields := make(map[string]interface{}, len(todo.CustomFields))
+	for name, v := range todo.CustomFields {
+		customFields[name] = v
+	}
+	var startAt *string
+	if todo.StartAt != nil {
+		s := todo.StartAt.Format(time.RFC3339Nano)
+		startAt = &s
+	}
+	res := TodoResponse{
+		ID:           strconv.Itoa(todo.ID),
+		Title:        todo.Title,
+		Description:  todo.Description,
+		Status:       int(todo.Status),
+		Priority:     int(todo.Priority),
+		StatusName:   todo.Status.Name(),
+		PriorityName: todo.Priority.Name(),
+		Position:     todo.Position,
+		Version:      todo.Version,
+		UpdatedAt:    todo.UpdatedAt.Format(time.RFC3339Nano),
+
+		TrackedSeconds:  int64(todo.TrackedTime / time.Second),
+		EstimatePoints:  todo.EstimatePoints,
+		EstimateMinutes: todo.EstimateMinutes,
+		OwnerID:         todo.UserID,
+		AssigneeID:      todo.AssigneeID,
+		CustomFields:    customFields,
+		StartAt:         startAt,
+		Snoozed:         todo.Snoozed(time.Now()),
+	}
+	if version < apiV2 {
+		res.CreatedAt = todo.CreatedAt.Format(time.RFC3339Nano)
+	} else {
+		res.CreatedAtV2 = todo.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return res
 }
 
 // Create processes the request of `POST /todos`.
 func (h *todoHandler) Create(c *gin.Context) {
 	userID := c.GetString(config.UserIDKey)
 
-	json := CreateTodoRequest{
-		Status:   int(model.StatusNotReady),
-		Priority: int(model.PriorityMiddle),
-	}
+	json := CreateTodoRequest{}
 	if err := c.ShouldBindJSON(&json); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			servermodel.ErrorResponse{ErrCode: http.StatusBadRequest, Detail: err.Error()},
-		)
+		sendBindErrorResponse(c, err)
 		return
 	}
 
-	newTodo, err := h.u.Create(c, userID, json.Title, json.Description, json.Status, json.Priority)
+	newTodo, err := h.u.Create(c, userID, json.toParams())
 	if err != nil {
 		sendErrorResponse(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, buildTodoResponse(newTodo))
+	c.Header("ETag", etag(newTodo, time.Now()))
+	c.JSON(http.StatusCreated, buildTodoResponse(newTodo, apiVersion(c)))
 }
 
 // Get processes the request of `GET /todos/:id`.
@@ -93,24 +200,76 @@ func (h *todoHandler) Get(c *gin.Context) {
 	userID := c.GetString(config.UserIDKey)
 	todoID := c.Param("id")
 
+	query := RenderRequest{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+
 	todo, err := h.u.Get(c, userID, todoID)
 	if err != nil {
 		sendErrorResponse(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, buildTodoResponse(todo))
+	c.Header("Accept-Patch", mimeMergePatch+", "+mimeJSONPatch)
+	if checkNotModified(c, etag(todo, time.Now()), lastModified(todo)) {
+		return
+	}
+	res, err := withDescriptionHTML(buildTodoResponse(todo, apiVersion(c)), query.Render)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
 }
 
 // ListTodoRequest is the structure representation of the request body of `GET /todos`.
 type ListTodoRequest struct {
-	SortBy      string `form:"sortby"`  // "id" or "priority"
-	OrderBy     string `form:"orderby"` // "asc" or "desc"
+	// "id", "priority", "manual", "status", "title", "createdAt", "updatedAt", "estimatePoints",
+	// "estimateMinutes" or "cf.<name of custom field>"
+	SortBy  string `form:"sortby"`
+	OrderBy string `form:"orderby"` // "asc" or "desc"
+	// Sort is comma separated keys such as "priority,-updatedAt,title", where "-" means descending order.
+	// It overrides sortby and orderby, and ties are broken by id.
+	Sort string `form:"sort"`
+
 	IncludeDone bool   `form:"includeDone"`
+	Assignee    string `form:"assignee"` // "me", "none" or id of the user assigned
+
+	// IncludeSnoozed lists todos whose start time hasn't come yet as well.
+	IncludeSnoozed bool `form:"includeSnoozed"`
+
+	Status      string `form:"status"`      // comma separated numbers or names such as "1,ready", overrides includeDone
+	Priority    string `form:"priority"`    // comma separated numbers or names such as "1,middle"
+	CreatedFrom string `form:"createdFrom"` // RFC 3339 or 2006-01-02, inclusive
+	CreatedTo   string `form:"createdTo"`   // RFC 3339 (exclusive) or 2006-01-02 (inclusive)
+	UpdatedFrom string `form:"updatedFrom"` // RFC 3339 or 2006-01-02, inclusive
+	UpdatedTo   string `form:"updatedTo"`   // RFC 3339 (exclusive) or 2006-01-02 (inclusive)
+	Title       string `form:"title"`       // substring of title, ignoring case
+	Description string `form:"description"` // substring of description, ignoring case
+
+	// Q is the search query such as `status:ready,doing -priority:low "release notes"`.
+	// Conditions on status in it override includeDone.
+	Q string `form:"q"`
+
+	Render string `form:"render" binding:"omitempty,oneof=html"` // "html" fills descriptionHtml
+
+	MinEstimatePoints  *int `form:"minEstimatePoints"`
+	MaxEstimatePoints  *int `form:"maxEstimatePoints"`
+	MinEstimateMinutes *int `form:"minEstimateMinutes"`
+	MaxEstimateMinutes *int `form:"maxEstimateMinutes"`
+
+	Limit  *int   `form:"limit"`  // max number of todos in a page, all todos are listed if omitted
+	Cursor string `form:"cursor"` // nextCursor of the previous page
+
+	// CustomFields is bound from `cf[<name>]=<value>` by QueryMap, since ShouldBindQuery doesn't support it.
+	CustomFields map[string]string `form:"-"`
 }
 
 // ListTodoResponse is the structure representation of the response body of `GET /todos`.
 type ListTodoResponse struct {
-	Entries []TodoResponse
+	Entries    []TodoResponse
+	NextCursor string `json:"nextCursor,omitempty"` // omitted on the last page
 }
 
 // List processes the request of `GET /todos`.
@@ -123,53 +282,131 @@ func (h todoHandler) List(c *gin.Context) {
 		IncludeDone: false,
 	}
 	if err := c.ShouldBindQuery(&query); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			servermodel.ErrorResponse{ErrCode: http.StatusBadRequest, Detail: err.Error()},
-		)
+		sendBindErrorResponse(c, err)
 		return
 	}
+	query.CustomFields = c.QueryMap("cf")
 
-	todos, err := h.u.List(c, userID, query.SortBy, query.OrderBy, query.IncludeDone)
+	params := usecase.ListTodoParams{
+		SortBy:             query.SortBy,
+		OrderBy:            query.OrderBy,
+		Sort:               query.Sort,
+		IncludeDone:        query.IncludeDone,
+		IncludeSnoozed:     query.IncludeSnoozed,
+		Assignee:           query.Assignee,
+		Statuses:           query.Status,
+		Priorities:         query.Priority,
+		CreatedFrom:        query.CreatedFrom,
+		CreatedTo:          query.CreatedTo,
+		UpdatedFrom:        query.UpdatedFrom,
+		UpdatedTo:          query.UpdatedTo,
+		Title:              query.Title,
+		Description:        query.Description,
+		Query:              query.Q,
+		MinEstimatePoints:  query.MinEstimatePoints,
+		MaxEstimatePoints:  query.MaxEstimatePoints,
+		MinEstimateMinutes: query.MinEstimateMinutes,
+		MaxEstimateMinutes: query.MaxEstimateMinutes,
+		CustomFields:       query.CustomFields,
+		Limit:              query.Limit,
+		Cursor:             query.Cursor,
+	}
+	todos, next, err := h.u.List(c, userID, params)
 	if err != nil {
 		sendErrorResponse(c, err)
 		return
 	}
+	if checkNotModified(c, listETag(todos, next, time.Now()), lastModified(todos...)) {
+		return
+	}
 	res := make([]TodoResponse, 0, len(todos))
 	for _, todo := range todos {
-		t := buildTodoResponse(todo)
+		t, err := withDescriptionHTML(buildTodoResponse(todo, apiVersion(c)), query.Render)
+		if err != nil {
+			sendErrorResponse(c, err)
+			return
+		}
 		res = append(res, t)
 	}
-	c.JSON(http.StatusOK, ListTodoResponse{res})
+	pagination := &PaginationResponse{Limit: query.Limit, HasMore: next != ""}
+	if next != "" {
+		pagination.NextCursor = &next
+	}
+	c.JSON(http.StatusOK, listTodoResponse(apiVersion(c), res, pagination))
 }
 
 // UpdateTodoRequest is the structure representation of the request body of `PATCH /todos/:id`.
 type UpdateTodoRequest struct {
-	Title       *string `json:"title,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Status      *int    `json:"status,omitempty"`
-	Priority    *int    `json:"priority,omitempty"`
+	Title       *string        `json:"title,omitempty"`
+	Description *string        `json:"description,omitempty"`
+	Status      *StatusValue   `json:"status,omitempty"`   // the number or the name such as "ready"
+	Priority    *PriorityValue `json:"priority,omitempty"` // the number or the name such as "high"
+
+	EstimatePoints  *int    `json:"estimatePoints,omitempty"`
+	EstimateMinutes *int    `json:"estimateMinutes,omitempty"`
+	AssigneeID      *string `json:"assigneeId,omitempty"` // empty string unassigns the todo
+
+	CustomFields map[string]interface{} `json:"customFields,omitempty"` // null value clears the field
+	StartAt      *string                `json:"startAt,omitempty"`      // empty string wakes the todo up
+}
+
+func (r UpdateTodoRequest) toParams() usecase.UpdateTodoParams {
+	return usecase.UpdateTodoParams{
+		Title:           r.Title,
+		Description:     r.Description,
+		Status:          (*int)(r.Status),
+		Priority:        (*int)(r.Priority),
+		EstimatePoints:  utility.NullableFrom(r.EstimatePoints),
+		EstimateMinutes: utility.NullableFrom(r.EstimateMinutes),
+		AssigneeID:      r.AssigneeID,
+		CustomFields:    r.CustomFields,
+		StartAt:         r.StartAt,
+	}
+}
+
+// media types of patch documents accepted by `PATCH /todos/:id` besides UpdateTodoRequest.
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+)
+
+var patchFormats = map[string]usecase.PatchFormat{
+	mimeMergePatch: usecase.PatchFormatMerge,
+	mimeJSONPatch:  usecase.PatchFormatJSON,
 }
 
-// Update processes the request of `PATCH /todos/:id`.
+// Update processes the request of `PATCH /todos/:id`. The body is UpdateTodoRequest unless Content-Type is
+// JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902), which are applied to the todo as TodoResponse,
+// where null clears optional fields.
 func (h todoHandler) Update(c *gin.Context) {
 	userID := c.GetString(config.UserIDKey)
 	todoID := c.Param("id")
 
-	json := UpdateTodoRequest{}
-	if err := c.ShouldBindJSON(&json); err != nil {
-		c.AbortWithStatusJSON(
-			http.StatusBadRequest,
-			servermodel.ErrorResponse{ErrCode: http.StatusBadRequest, Detail: err.Error()},
-		)
-		return
+	var todo *model.Todo
+	var err error
+	if format, ok := patchFormats[c.ContentType()]; ok {
+		body, readErr := c.GetRawData()
+		if readErr != nil {
+			sendErrorResponse(c, utility.BadRequest("can't read request body", readErr))
+			return
+		}
+		todo, err = h.u.Patch(c, userID, todoID, format, body, parseIfMatch(c))
+	} else {
+		json := UpdateTodoRequest{}
+		if err := c.ShouldBindJSON(&json); err != nil {
+			sendBindErrorResponse(c, err)
+			return
+		}
+		params := json.toParams()
+		params.IfMatch = parseIfMatch(c)
+		todo, err = h.u.Update(c, userID, todoID, params)
 	}
-	todo, err := h.u.Update(c, userID, todoID, json.Title, json.Description, json.Status, json.Priority)
 	if err != nil {
 		sendErrorResponse(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, buildTodoResponse(todo))
+	c.Header("ETag", etag(todo, time.Now()))
+	c.JSON(http.StatusOK, buildTodoResponse(todo, apiVersion(c)))
 }
 
 // Delete processes the request of `DELETE /todos/:id`.
@@ -177,24 +414,197 @@ func (h todoHandler) Delete(c *gin.Context) {
 	userID := c.GetString(config.UserIDKey)
 	todoID := c.Param("id")
 
-	if err := h.u.Delete(c, userID, todoID); err != nil {
+	if err := h.u.Delete(c, userID, todoID, parseIfMatch(c)); err != nil {
 		sendErrorResponse(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, servermodel.MessageResponse{Message: fmt.Sprintf("todo %s is deleted", todoID)})
 }
 
+// MoveTodoRequest is the structure representation of the request body of `POST /todos/:id/move`.
+// Exactly one of Before or After must be specified.
+type MoveTodoRequest struct {
+	Before string `json:"before,omitempty"` // id of the todo to be placed before
+	After  string `json:"after,omitempty"`  // id of the todo to be placed after
+}
+
+// Move processes the request of `POST /todos/:id/move`.
+func (h todoHandler) Move(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	todoID := c.Param("id")
+
+	json := MoveTodoRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+	todo, err := h.u.Move(c, userID, todoID, json.Before, json.After)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTodoResponse(todo, apiVersion(c)))
+}
+
+// EffortResponse is the structure representation of the total of estimates.
+type EffortResponse struct {
+	Count           int `json:"count"`
+	EstimatePoints  int `json:"estimatePoints"`
+	EstimateMinutes int `json:"estimateMinutes"`
+}
+
+// StatusEffortResponse is the structure representation of the total of estimates of a status.
+type StatusEffortResponse struct {
+	Status     int    `json:"status"`
+	StatusName string `json:"statusName"`
+	EffortResponse
+}
+
+// PriorityEffortResponse is the structure representation of the total of estimates of a priority.
+type PriorityEffortResponse struct {
+	Priority     int    `json:"priority"`
+	PriorityName string `json:"priorityName"`
+	EffortResponse
+}
+
+// SummaryResponse is the structure representation of the response body of `GET /todos/summary`.
+type SummaryResponse struct {
+	ByStatus   []StatusEffortResponse   `json:"byStatus"`
+	ByPriority []PriorityEffortResponse `json:"byPriority"`
+	Remaining  EffortResponse           `json:"remaining"`
+	Completed  EffortResponse           `json:"completed"`
+}
+
+func buildEffortResponse(effort model.Effort) EffortResponse {
+	return EffortResponse{
+		Count:           effort.Count,
+		EstimatePoints:  effort.EstimatePoints,
+		EstimateMinutes: effort.EstimateMinutes,
+	}
+}
+
+// SnoozeTodoRequest is the structure representation of the request body of `POST /todos/:id/snooze`.
+type SnoozeTodoRequest struct {
+	// Until is duration such as "30m", "2h", "1d" or "1w", "tomorrow", "next-week", "next-<weekday>" such as
+	// "next-monday", RFC 3339 or 2006-01-02.
+	Until string `json:"until" binding:"required"`
+}
+
+// Snooze processes the request of `POST /todos/:id/snooze`.
+func (h todoHandler) Snooze(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	todoID := c.Param("id")
+
+	json := SnoozeTodoRequest{}
+	if err := c.ShouldBindJSON(&json); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+	todo, err := h.u.Snooze(c, userID, todoID, json.Until)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTodoResponse(todo, apiVersion(c)))
+}
+
+// ToggleTask processes the request of `POST /todos/:id/tasks/:index/toggle`.
+// Index is counted from 0 in the order of task list items such as `- [ ] task` in the description.
+func (h todoHandler) ToggleTask(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+	todoID := c.Param("id")
+	index := c.Param("index")
+
+	todo, err := h.u.ToggleTask(c, userID, todoID, index)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, buildTodoResponse(todo, apiVersion(c)))
+}
+
+// Summary processes the request of `GET /todos/summary`.
+func (h todoHandler) Summary(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	report, err := h.u.Summary(c, userID)
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+
+	// every status and priority is listed even if there is no todo, so that clients can render them as they are
+	res := SummaryResponse{
+		ByStatus:   make([]StatusEffortResponse, 0),
+		ByPriority: make([]PriorityEffortResponse, 0),
+		Remaining:  buildEffortResponse(report.Remaining),
+		Completed:  buildEffortResponse(report.Completed),
+	}
+	for s := model.StatusNotReady; s <= model.StatusDone; s++ {
+		res.ByStatus = append(res.ByStatus, StatusEffortResponse{int(s), s.Name(), buildEffortResponse(report.ByStatus[s])})
+	}
+	for p := model.PriorityHigh; p <= model.PriorityLow; p++ {
+		res.ByPriority = append(res.ByPriority, PriorityEffortResponse{
+			int(p), p.Name(), buildEffortResponse(report.ByPriority[p]),
+		})
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// sendErrorResponse sends the error returned by a usecase in the format negotiated by problem.Abort.
+// The error is also recorded in the context, so that DBMiddleware rolls back the transaction of the request,
+// since the usecase may have written a part of the changes before it failed.
 func sendErrorResponse(c *gin.Context, err error) {
-	var httpErr *utility.HTTPError
-	if errors.As(err, &httpErr) {
-		c.AbortWithStatusJSON(
-			httpErr.ErrCode(),
-			servermodel.ErrorResponse{ErrCode: httpErr.ErrCode(), Detail: httpErr.Error()},
-		)
-		return
-	}
-	c.AbortWithStatusJSON(
-		http.StatusInternalServerError,
-		servermodel.ErrorResponse{ErrCode: http.StatusInternalServerError, Detail: err.Error()},
-	)
+	_ = c.Error(err)
+	problem.Abort(c, err)
+}
+
+// SearchTodoRequest is the structure representation of the request query of `GET /todos/search`.
+type SearchTodoRequest struct {
+	Q     string `form:"q"`     // terms separated by spaces, or quoted phrases such as "release notes"
+	Limit *int   `form:"limit"` // max number of todos, 20 if omitted
+}
+
+// SearchHitResponse is a todo found by full-text search, with its relevance and highlighted matches.
+type SearchHitResponse struct {
+	TodoResponse
+	Score      float64                 `json:"score"`
+	Highlights SearchHighlightResponse `json:"highlights"`
+}
+
+// SearchHighlightResponse is HTML escaped text whose matches are enclosed in <mark>.
+type SearchHighlightResponse struct {
+	Title       string `json:"title"`
+	Description string `json:"description"` // part of description around the first match
+}
+
+// SearchTodoResponse is the structure representation of the response body of `GET /todos/search`.
+type SearchTodoResponse struct {
+	Entries []SearchHitResponse `json:"entries"` // in the order of relevance
+}
+
+// Search processes the request of `GET /todos/search`.
+func (h todoHandler) Search(c *gin.Context) {
+	userID := c.GetString(config.UserIDKey)
+
+	query := SearchTodoRequest{}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		sendBindErrorResponse(c, err)
+		return
+	}
+
+	hits, err := h.u.Search(c, userID, usecase.SearchTodoParams{Query: query.Q, Limit: query.Limit})
+	if err != nil {
+		sendErrorResponse(c, err)
+		return
+	}
+	res := make([]SearchHitResponse, 0, len(hits))
+	for _, hit := range hits {
+		res = append(res, SearchHitResponse{
+			TodoResponse: buildTodoResponse(hit.Todo, apiVersion(c)),
+			Score:        hit.Score,
+			Highlights:   SearchHighlightResponse{Title: hit.Title, Description: hit.Snippet},
+		})
+	}
+	c.JSON(http.StatusOK, SearchTodoResponse{res})
 }
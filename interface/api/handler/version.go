@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// versions of API. Every version shares usecases, and differs only in the schema of responses.
+const (
+	apiV1 = 1
+	apiV2 = 2 // fixes names of fields such as `createdAt` and `entries`, adds pagination, and sends Problem Details
+)
+
+// apiVersion returns the version of API which the request is routed to.
+func apiVersion(c *gin.Context) int {
+	if v := c.GetInt(config.APIVersionKey); v != 0 {
+		return v
+	}
+	return apiV1
+}
+
+// ListTodoResponseV2 is the structure representation of the response body of lists of todos since v2.
+type ListTodoResponseV2 struct {
+	Entries    []TodoResponse      `json:"entries"`
+	Pagination *PaginationResponse `json:"pagination,omitempty"` // omitted unless the list is paginated
+}
+
+// PaginationResponse is the position of a page in the list.
+type PaginationResponse struct {
+	Limit      *int    `json:"limit"`      // null if all todos are listed
+	HasMore    bool    `json:"hasMore"`    // whether there are the next pages
+	NextCursor *string `json:"nextCursor"` // cursor of the next page, null on the last page
+}
+
+// listTodoResponse returns the list of todos in the schema of the version.
+func listTodoResponse(version int, entries []TodoResponse, pagination *PaginationResponse) interface{} {
+	if version < apiV2 {
+		res := ListTodoResponse{Entries: entries}
+		if pagination != nil && pagination.NextCursor != nil {
+			res.NextCursor = *pagination.NextCursor
+		}
+		return res
+	}
+	return ListTodoResponseV2{Entries: entries, Pagination: pagination}
+}
+
+// BatchResponseV2 is the structure representation of the response body of `POST /todos/batch` since v2.
+type BatchResponseV2 struct {
+	Committed bool                    `json:"committed"` // false if an operation failed in atomic mode
+	Results   []BatchResultResponseV2 `json:"results"`   // in the order of the operations
+}
+
+// BatchResultResponseV2 is the structure representation of the result of an operation since v2, whose error
+// is in Problem Details as errors of requests.
+type BatchResultResponseV2 struct {
+	Result  string                       `json:"result"`            // "succeeded", "failed", "rolledBack" or "skipped"
+	Status  int                          `json:"status,omitempty"`  // status code as if the operation is requested alone
+	Entries []TodoResponse               `json:"entries,omitempty"` // todos created or updated
+	Error   *servermodel.ProblemResponse `json:"error,omitempty"`
+}
+
+// batchResponse returns the response of the batch in the schema of the version. Errors of the operations are
+// Problems of instance since v2.
+func batchResponse(
+	version int, res BatchResponse, results []*usecase.BatchResult, instance string,
+) interface{} {
+	if version < apiV2 {
+		return res
+	}
+	ret := BatchResponseV2{Committed: res.Committed, Results: make([]BatchResultResponseV2, 0, len(res.Results))}
+	for i, r := range res.Results {
+		result := BatchResultResponseV2{Result: r.Result, Status: r.Status, Entries: r.Entries}
+		if err := results[i].Err; err != nil {
+			p := problem.Problem(err, instance)
+			result.Error = &p
+		}
+		ret.Results = append(ret.Results, result)
+	}
+	return ret
+}
@@ -6,7 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
-	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
 )
 
@@ -27,26 +28,17 @@ func (m *authMiddleware) NewAuthentication() gin.HandlerFunc {
 		val := c.Request.Header.Get("authorization")
 		pair := strings.SplitN(val, ":", 2)
 		if len(pair) < 2 {
-			c.AbortWithStatusJSON(
-				http.StatusUnauthorized,
-				model.ErrorResponse{ErrCode: http.StatusUnauthorized, Detail: "invalid authentication"},
-			)
+			problem.Abort(c, utility.NewHTTPError(http.StatusUnauthorized, "invalid authentication", nil))
 			return
 		}
 
 		authenticated, err := m.repo.Authenticate(c, pair[0], pair[1])
 		if err != nil {
-			c.AbortWithStatusJSON(
-				http.StatusInternalServerError,
-				model.ErrorResponse{ErrCode: http.StatusInternalServerError, Detail: err.Error()},
-			)
+			problem.Abort(c, utility.InternalServerError(err.Error(), err))
 			return
 		}
 		if !authenticated {
-			c.AbortWithStatusJSON(
-				http.StatusUnauthorized,
-				model.ErrorResponse{ErrCode: http.StatusUnauthorized, Detail: "uset not found or invalid password"},
-			)
+			problem.Abort(c, utility.NewHTTPError(http.StatusUnauthorized, "uset not found or invalid password", nil))
 			return
 		}
 
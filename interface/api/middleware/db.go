@@ -2,10 +2,10 @@ package middleware
 
 import (
 	"log"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
 	"gorm.io/gorm"
 )
@@ -18,20 +18,40 @@ func NewDBMiddleware(db *gorm.DB) *DBMiddleware {
 	return &DBMiddleware{db}
 }
 
+// afterTransactionKey is the key of the functions called after the transaction of the request ends.
+const afterTransactionKey = "AfterTransaction"
+
+// afterTransaction registers fn called with whether the transaction started by NewTransaction is committed,
+// after it ends. fn isn't called if the request panics.
+func afterTransaction(c *gin.Context, fn func(committed bool)) {
+	fns, _ := c.Get(afterTransactionKey)
+	list, _ := fns.([]func(committed bool))
+	c.Set(afterTransactionKey, append(list, fn))
+}
+
+// endTransaction calls the functions registered by afterTransaction.
+func endTransaction(c *gin.Context, committed bool) {
+	fns, _ := c.Get(afterTransactionKey)
+	list, _ := fns.([]func(committed bool))
+	for _, fn := range list {
+		fn(committed)
+	}
+}
+
 func (m *DBMiddleware) NewTransaction() gin.HandlerFunc {
 	if m == nil {
 		return func(c *gin.Context) {
 			c.Next()
+			// changes of the onmemory repositories are always kept
+			endTransaction(c, true)
 		}
 	}
 
 	return func(c *gin.Context) {
 		tx := m.db.Begin()
 		if err := tx.Error; err != nil {
-			c.AbortWithStatusJSON(
-				http.StatusInternalServerError,
-				model.ErrorResponse{ErrCode: http.StatusInternalServerError, Detail: "can't start transaction"},
-			)
+			problem.Abort(c, utility.InternalServerError("can't start transaction", err))
+			return
 		}
 		c.Set(config.DBKey, tx)
 
@@ -45,11 +65,15 @@ func (m *DBMiddleware) NewTransaction() gin.HandlerFunc {
 					// re-throw error to make recoverty handler work
 					panic(r)
 				}
+				endTransaction(c, false)
 			} else {
 				if cerr := tx.Commit().Error; cerr != nil {
 					log.Printf("failed to commit: %v\n", cerr)
 					_ = tx.Rollback()
+					endTransaction(c, false)
+					return
 				}
+				endTransaction(c, true)
 			}
 		}()
 
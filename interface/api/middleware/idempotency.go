@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	domainmodel "github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// idempotencyKeyMaxLength is the max length of Idempotency-Key header.
+const idempotencyKeyMaxLength = 255
+
+type IdempotencyMiddleware interface {
+	// NewIdempotency replays the response of the first request of the user with the same Idempotency-Key header.
+	// It must follow NewTransaction, so that the key is saved together with the changes made by the request,
+	// and the response is sent after they are committed.
+	NewIdempotency() gin.HandlerFunc
+}
+
+type idempotencyMiddleware struct {
+	repo repository.IdempotencyKeyRepository
+	ttl  time.Duration
+}
+
+func NewIdempotencyMiddleware(repo repository.IdempotencyKeyRepository, ttl time.Duration) IdempotencyMiddleware {
+	return &idempotencyMiddleware{repo: repo, ttl: ttl}
+}
+
+func (m *idempotencyMiddleware) NewIdempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyKeyMaxLength {
+			msg := fmt.Sprintf("Idempotency-Key must be at most %d characters", idempotencyKeyMaxLength)
+			problem.Abort(c, utility.BadRequest(msg, nil).WithCode(utility.ErrorCodeInvalidIdempotencyKey))
+			return
+		}
+		body, err := c.GetRawData()
+		if err != nil {
+			problem.Abort(c, utility.BadRequest("can't read request body", err))
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		reserving := domainmodel.IdempotencyKey{
+			UserID:      c.GetString(config.UserIDKey),
+			Key:         key,
+			RequestHash: requestHash(c.Request, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(m.ttl),
+		}
+		saved, reserved, err := m.repo.Reserve(c, reserving)
+		if err != nil {
+			abortWithError(c, err)
+			return
+		}
+		if !reserved {
+			replay(c, saved, reserving.RequestHash)
+			return
+		}
+
+		// the response is sent after the transaction saving it is committed, so that it's never sent without
+		// being replayed on retries
+		writer := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		// restored also when the handler panics, so that the recovery sends the error
+		defer func() { c.Writer = writer.ResponseWriter }()
+		afterTransaction(c, func(committed bool) {
+			if !committed && writer.status < http.StatusBadRequest {
+				// the changes are rolled back with the saved response, so the success must not be sent
+				for _, name := range replayedHeaders {
+					writer.Header().Del(name)
+				}
+				problem.Abort(c, utility.InternalServerError("can't commit the changes of the request", nil))
+				return
+			}
+			writer.flush()
+		})
+		c.Next()
+
+		if writer.status >= http.StatusBadRequest {
+			// failures aren't replayed, so that the request can be retried after the cause is fixed
+			if err := m.repo.Release(c, saved.UserID, saved.Key); err != nil {
+				log.Printf("failed to release idempotency key: %v\n", err)
+			}
+			return
+		}
+		saved.StatusCode = writer.status
+		saved.Header = map[string][]string{}
+		for _, name := range replayedHeaders {
+			if values := writer.Header().Values(name); len(values) > 0 {
+				saved.Header[name] = values
+			}
+		}
+		saved.Body = writer.body.Bytes()
+		if err := m.repo.Complete(c, saved); err != nil {
+			// the error replaces the response, and is sent after the transaction is rolled back
+			writer.body.Reset()
+			abortWithError(c, err)
+		}
+	}
+}
+
+// replayedHeaders are the headers of responses which are saved and replayed with the body.
+var replayedHeaders = []string{"Content-Type", "ETag", "Last-Modified", "Location"}
+
+// replay sends the response saved for the key, unless the key is reused for another request or
+// the first request is still processed.
+func replay(c *gin.Context, key *domainmodel.IdempotencyKey, hash string) {
+	if key.RequestHash != hash {
+		msg := fmt.Sprintf("Idempotency-Key %s is already used for another request", key.Key)
+		problem.Abort(c, utility.NewHTTPError(http.StatusUnprocessableEntity, msg, nil).
+			WithCode(utility.ErrorCodeIdempotencyKeyReused))
+		return
+	}
+	if !key.Completed() {
+		msg := fmt.Sprintf("request with Idempotency-Key %s is being processed", key.Key)
+		problem.Abort(c, utility.Conflict(msg, nil).WithCode(utility.ErrorCodeIdempotencyKeyInProgress))
+		return
+	}
+	for name, values := range key.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+	c.Header("Idempotent-Replayed", "true")
+	// only JSON APIs accept the key, so it's the type of the responses saved without the header
+	contentType := c.Writer.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Data(key.StatusCode, contentType, key.Body)
+	c.Abort()
+}
+
+// requestHash returns the hash of the method, the path and the body of the request.
+// JSON body is compared by its content, ignoring spaces and the order of keys.
+func requestHash(req *http.Request, body []byte) string {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&v); err == nil {
+		if b, err := json.Marshal(v); err == nil {
+			body = b
+		}
+	}
+	h := sha256.New()
+	_, _ = h.Write([]byte(req.Method + " " + req.URL.Path + "\n"))
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// abortWithError sends the error, which also rolls back the transaction started by NewTransaction.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	problem.Abort(c, utility.InternalServerError(err.Error(), err))
+}
+
+// bufferedWriter keeps the response until flush is called.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return false
+}
+
+// flush sends the response kept so far.
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if _, err := w.ResponseWriter.Write(w.body.Bytes()); err != nil {
+		log.Printf("failed to write response: %v\n", err)
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/openapi"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type ValidationMiddleware interface {
+	// NewValidation rejects requests which don't match the OpenAPI document before they reach handlers,
+	// including unknown fields which handlers ignore. It does nothing unless the validation is enabled.
+	NewValidation() gin.HandlerFunc
+}
+
+type validationMiddleware struct {
+	doc     *openapi.Document
+	enabled bool
+}
+
+func NewValidationMiddleware(doc *openapi.Document, enabled bool) ValidationMiddleware {
+	return &validationMiddleware{doc: doc, enabled: enabled}
+}
+
+func (m *validationMiddleware) NewValidation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.enabled {
+			c.Next()
+			return
+		}
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = c.GetRawData(); err != nil {
+				problem.Abort(c, utility.BadRequest("can't read request body", err))
+				return
+			}
+			c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		err := m.doc.ValidateRequest(c.FullPath(), c.Params, c.Request, body)
+		if err == nil {
+			c.Next()
+			return
+		}
+		var httpErr *utility.HTTPError
+		if !errors.As(err, &httpErr) {
+			abortWithError(c, err)
+			return
+		}
+		problem.Abort(c, httpErr)
+	}
+}
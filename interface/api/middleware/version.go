@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// APIVersion sets the version of API which requests are routed to, so that handlers respond in its schema.
+func APIVersion(version int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(config.APIVersionKey, version)
+		c.Next()
+	}
+}
+
+type DeprecationMiddleware interface {
+	// NewDeprecation adds Deprecation (RFC 9745) and Sunset (RFC 8594) headers to the responses of deprecated
+	// paths, with the link to the same path prefixed with successor such as `/v1`.
+	NewDeprecation(successor string) gin.HandlerFunc
+}
+
+type deprecationMiddleware struct {
+	deprecatedAt time.Time
+	sunset       time.Time
+}
+
+func NewDeprecationMiddleware(deprecatedAt, sunset time.Time) DeprecationMiddleware {
+	return &deprecationMiddleware{deprecatedAt: deprecatedAt, sunset: sunset}
+}
+
+func (m *deprecationMiddleware) NewDeprecation(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", m.deprecatedAt.Unix()))
+		c.Header("Sunset", m.sunset.UTC().Format(http.TimeFormat))
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successor, c.Request.URL.Path))
+		c.Next()
+	}
+}
@@ -4,7 +4,31 @@ type MessageResponse struct {
 	Message string `json:"message"`
 }
 
+// ErrorResponse is the legacy format of errors, which is sent as `application/json`.
 type ErrorResponse struct {
 	ErrCode int    `json:"errCode"`
 	Detail  string `json:"detail"`
+	// Errors is the violations of the fields of the request, which is omitted unless the request is invalid.
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a violation of a field of the request.
+type FieldError struct {
+	Field   string                 `json:"field"`   // such as "title" or "items[0].title"
+	Code    string                 `json:"code"`    // such as "required" or "too_long"
+	Message string                 `json:"message"` // human readable message in English
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// ProblemResponse is the format of errors in RFC 7807 Problem Details, which is sent as
+// `application/problem+json`.
+type ProblemResponse struct {
+	Type     string `json:"type"`     // such as "/problems/todo_not_found", which describes the code
+	Title    string `json:"title"`    // summary of the code, which doesn't change by occurrence
+	Status   int    `json:"status"`   // same as the status code of the response
+	Detail   string `json:"detail"`   // human readable explanation of the occurrence
+	Instance string `json:"instance"` // path of the request
+	// Code is the stable machine-readable code of the error such as "todo_not_found".
+	Code   string       `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
 }
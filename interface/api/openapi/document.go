@@ -0,0 +1,409 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+)
+
+// Document is the OpenAPI 3.0 document of the API.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Security   []map[string][]string `json:"security"`
+	Paths      map[string]PathItem   `json:"paths"` // keyed by path such as `/v2/todos/{id}`
+	Components Components            `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem is the operations of a path keyed by lower case method such as `get`.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"` // keyed by status code or `default`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // "path", "query" or "header"
+	Required bool    `json:"required,omitempty"`
+	Style    string  `json:"style,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"` // keyed by media type
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// media types of request and response bodies.
+const (
+	mimeJSON       = "application/json"
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+	mimeProblem    = "application/problem+json"
+)
+
+// securityScheme is the name of the authentication by `Authorization: <user id>:<password>` header.
+const securityScheme = "userPassword"
+
+// JSONPatchOperationRequest is an operation of JSON Patch (RFC 6902), which is documented for
+// `PATCH /todos/:id` although the handler passes patches to the usecase as they are.
+type JSONPatchOperationRequest struct {
+	Op    string      `json:"op" binding:"required,oneof=add remove replace move copy test"`
+	Path  string      `json:"path" binding:"required"` // JSON Pointer to a member of the todo such as `/title`
+	From  string      `json:"from,omitempty"`          // the source of move and copy
+	Value interface{} `json:"value,omitempty"`         // the value of add, replace and test
+}
+
+// operation is the definition of an operation in the syntax of gin, which is documented in every version.
+type operation struct {
+	method  string
+	path    string // such as `/todos/:id`
+	id      string // operationId without version
+	summary string
+	query   interface{}            // the structure bound from the query
+	headers []string               // headers which change the behavior, such as If-Match
+	body    map[string]interface{} // the structures of request bodies keyed by media type
+	status  int
+	// response is the structure of the response body, and responseV2 replaces it since v2 if not nil.
+	response   interface{}
+	responseV2 interface{}
+	// failure is the structure of the response body of errors besides ErrorResponse, and failureV2 replaces it
+	// since v2 if not nil.
+	failure   interface{}
+	failureV2 interface{}
+}
+
+// operations are the operations routed by api.Route.
+var operations = []operation{
+	{
+		method: http.MethodPost, path: "/todos", id: "createTodo", summary: "Create a todo",
+		headers: []string{"Idempotency-Key"},
+		body:    map[string]interface{}{mimeJSON: handler.CreateTodoRequest{}},
+		status:  http.StatusCreated, response: handler.TodoResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/todos", id: "listTodos", summary: "List todos",
+		query: handler.ListTodoRequest{}, headers: []string{"If-None-Match", "If-Modified-Since"},
+		status: http.StatusOK, response: handler.ListTodoResponse{}, responseV2: handler.ListTodoResponseV2{},
+	},
+	{
+		method: http.MethodGet, path: "/todos/summary", id: "summarizeTodos", summary: "Summarize estimates of todos",
+		status: http.StatusOK, response: handler.SummaryResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/todos/search", id: "searchTodos", summary: "Search todos by full text",
+		query:  handler.SearchTodoRequest{},
+		status: http.StatusOK, response: handler.SearchTodoResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/todos/batch", id: "batchTodos", summary: "Run operations of todos at once",
+		body:   map[string]interface{}{mimeJSON: handler.BatchRequest{}},
+		status: http.StatusOK, response: handler.BatchResponse{}, responseV2: handler.BatchResponseV2{},
+		// with the status of the failed operation in atomic mode
+		failure: handler.BatchResponse{}, failureV2: handler.BatchResponseV2{},
+	},
+	{
+		method: http.MethodGet, path: "/todos/:id", id: "getTodo", summary: "Get a todo",
+		query: handler.RenderRequest{}, headers: []string{"If-None-Match", "If-Modified-Since"},
+		status: http.StatusOK, response: handler.TodoResponse{},
+	},
+	{
+		method: http.MethodPatch, path: "/todos/:id", id: "updateTodo", summary: "Update a todo",
+		headers: []string{"If-Match"},
+		body: map[string]interface{}{
+			mimeJSON: handler.UpdateTodoRequest{},
+			// members of the todo as TodoResponse, where null clears optional fields
+			mimeMergePatch: map[string]interface{}{},
+			mimeJSONPatch:  []JSONPatchOperationRequest{},
+		},
+		status: http.StatusOK, response: handler.TodoResponse{},
+	},
+	{
+		method: http.MethodDelete, path: "/todos/:id", id: "deleteTodo", summary: "Delete a todo",
+		headers: []string{"If-Match"},
+		status:  http.StatusOK, response: servermodel.MessageResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/todos/:id/move", id: "moveTodo", summary: "Move a todo in the order",
+		body:   map[string]interface{}{mimeJSON: handler.MoveTodoRequest{}},
+		status: http.StatusOK, response: handler.TodoResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/todos/:id/snooze", id: "snoozeTodo", summary: "Hide a todo until the time",
+		body:   map[string]interface{}{mimeJSON: handler.SnoozeTodoRequest{}},
+		status: http.StatusOK, response: handler.TodoResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/todos/:id/tasks/:index/toggle", id: "toggleTask",
+		summary: "Check or uncheck a task list item in the description",
+		status:  http.StatusOK, response: handler.TodoResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/todos/:id/timer/start", id: "startTimer", summary: "Start the timer of a todo",
+		status: http.StatusOK, response: handler.TimeEntryResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/todos/:id/timer/stop", id: "stopTimer", summary: "Stop the timer of a todo",
+		status: http.StatusOK, response: handler.TimeEntryResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/time-entries", id: "listTimeEntries", summary: "List time entries",
+		query:  handler.ListTimeEntryRequest{},
+		status: http.StatusOK, response: handler.ListTimeEntryResponse{},
+	},
+	{
+		method: http.MethodPatch, path: "/time-entries/:id", id: "updateTimeEntry", summary: "Update a time entry",
+		body:   map[string]interface{}{mimeJSON: handler.UpdateTimeEntryRequest{}},
+		status: http.StatusOK, response: handler.TimeEntryResponse{},
+	},
+	{
+		method: http.MethodDelete, path: "/time-entries/:id", id: "deleteTimeEntry", summary: "Delete a time entry",
+		status: http.StatusOK, response: servermodel.MessageResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/custom-fields", id: "createCustomField", summary: "Create a custom field",
+		body:   map[string]interface{}{mimeJSON: handler.CreateCustomFieldRequest{}},
+		status: http.StatusCreated, response: handler.CustomFieldResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/custom-fields", id: "listCustomFields", summary: "List custom fields",
+		status: http.StatusOK, response: handler.ListCustomFieldResponse{},
+	},
+	{
+		method: http.MethodDelete, path: "/custom-fields/:id", id: "deleteCustomField",
+		summary: "Delete a custom field and its values",
+		status:  http.StatusOK, response: servermodel.MessageResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/templates", id: "createTemplate", summary: "Create a template",
+		body:   map[string]interface{}{mimeJSON: handler.CreateTemplateRequest{}},
+		status: http.StatusCreated, response: handler.TemplateResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/templates", id: "listTemplates", summary: "List templates",
+		status: http.StatusOK, response: handler.ListTemplateResponse{},
+	},
+	{
+		method: http.MethodGet, path: "/templates/:id", id: "getTemplate", summary: "Get a template",
+		status: http.StatusOK, response: handler.TemplateResponse{},
+	},
+	{
+		method: http.MethodPatch, path: "/templates/:id", id: "updateTemplate", summary: "Update a template",
+		body:   map[string]interface{}{mimeJSON: handler.UpdateTemplateRequest{}},
+		status: http.StatusOK, response: handler.TemplateResponse{},
+	},
+	{
+		method: http.MethodDelete, path: "/templates/:id", id: "deleteTemplate", summary: "Delete a template",
+		status: http.StatusOK, response: servermodel.MessageResponse{},
+	},
+	{
+		method: http.MethodPost, path: "/templates/:id/instantiate", id: "instantiateTemplate",
+		summary: "Create todos from a template",
+		body:    map[string]interface{}{mimeJSON: handler.InstantiateTemplateRequest{}},
+		status:  http.StatusCreated, response: handler.ListTodoResponse{}, responseV2: handler.ListTodoResponseV2{},
+	},
+}
+
+// mounts are the prefixes which the operations are routed under, in the same order as api.Route.
+var mounts = []struct {
+	prefix     string
+	version    int
+	suffix     string // of operationId
+	deprecated bool
+}{
+	{prefix: "", version: 1, suffix: "Unversioned", deprecated: true},
+	{prefix: "/v1", version: 1, suffix: "V1"},
+	{prefix: "/v2", version: 2, suffix: "V2"},
+}
+
+// pathParamPattern matches parameters in paths of gin such as `:id`.
+var pathParamPattern = regexp.MustCompile(`:(\w+)`)
+
+// Path converts the path of gin such as `/v2/todos/:id` to the one in the document such as `/v2/todos/{id}`.
+func Path(ginPath string) string {
+	return pathParamPattern.ReplaceAllString(ginPath, "{$1}")
+}
+
+// NewDocument generates the document from the structures of handlers.
+func NewDocument() *Document {
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:   "Todo API",
+			Version: "2",
+			Description: "Every operation is served under `/v1` and `/v2`, which differ only in the schema of responses. " +
+				"The unprefixed paths are deprecated aliases of `/v1`.",
+		},
+		Security: []map[string][]string{{securityScheme: {}}},
+		Paths:    map[string]PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+			SecuritySchemes: map[string]*SecurityScheme{
+				securityScheme: {
+					Type: "apiKey", In: "header", Name: "Authorization",
+					Description: "`<user id>:<password>`",
+				},
+			},
+		},
+	}
+	for _, m := range mounts {
+		g := newGenerator(m.version, doc.Components.Schemas)
+		for _, op := range operations {
+			path := Path(m.prefix + op.path)
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = PathItem{}
+			}
+			doc.Paths[path][strings.ToLower(op.method)] = g.operation(op, m.suffix, m.deprecated)
+		}
+	}
+	return doc
+}
+
+// operation returns the operation object of op in the version of g.
+func (g *generator) operation(op operation, suffix string, deprecated bool) *Operation {
+	ret := &Operation{
+		OperationID: op.id + suffix,
+		Summary:     op.summary,
+		Tags:        []string{strings.SplitN(strings.TrimPrefix(op.path, "/"), "/", 2)[0]},
+		Deprecated:  deprecated,
+		Responses:   map[string]*Response{},
+	}
+
+	for _, name := range pathParamPattern.FindAllStringSubmatch(op.path, -1) {
+		param := &Parameter{Name: name[1], In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		if name[1] == "index" {
+			param.Schema = &Schema{Type: "integer"}
+		}
+		ret.Parameters = append(ret.Parameters, param)
+	}
+	if op.query != nil {
+		query := g.object(reflect.TypeOf(op.query))
+		for _, name := range sortedKeys(query.Properties) {
+			ret.Parameters = append(ret.Parameters, &Parameter{
+				Name: name, In: "query", Required: contains(query.Required, name), Schema: query.Properties[name],
+			})
+		}
+		if _, ok := op.query.(handler.ListTodoRequest); ok {
+			// bound by QueryMap as `cf[<name>]=<value>`
+			ret.Parameters = append(ret.Parameters, &Parameter{
+				Name: "cf", In: "query", Style: "deepObject",
+				Schema: &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			})
+		}
+	}
+	for _, name := range op.headers {
+		ret.Parameters = append(ret.Parameters, &Parameter{Name: name, In: "header", Schema: &Schema{Type: "string"}})
+	}
+
+	if op.body != nil {
+		ret.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{}}
+		for mime, body := range op.body {
+			ret.RequestBody.Content[mime] = &MediaType{Schema: g.schema(reflect.TypeOf(body))}
+		}
+	}
+
+	response := op.response
+	if op.responseV2 != nil && g.version >= 2 {
+		response = op.responseV2
+	}
+	ret.Responses[strconv.Itoa(op.status)] = &Response{
+		Description: http.StatusText(op.status),
+		Content:     map[string]*MediaType{mimeJSON: {Schema: g.schema(reflect.TypeOf(response))}},
+	}
+	if contains(op.headers, "If-None-Match") {
+		ret.Responses[strconv.Itoa(http.StatusNotModified)] = &Response{Description: http.StatusText(http.StatusNotModified)}
+	}
+	failure := g.schema(reflect.TypeOf(servermodel.ErrorResponse{}))
+	opFailure := op.failure
+	if op.failureV2 != nil && g.version >= 2 {
+		opFailure = op.failureV2
+	}
+	if opFailure != nil {
+		failure = &Schema{OneOf: []*Schema{failure, g.schema(reflect.TypeOf(opFailure))}}
+	}
+	// the media type of errors is negotiated by Accept header
+	ret.Responses["default"] = &Response{
+		Description: "Error",
+		Content: map[string]*MediaType{
+			mimeJSON:    {Schema: failure},
+			mimeProblem: {Schema: g.schema(reflect.TypeOf(servermodel.ProblemResponse{}))},
+		},
+	}
+	return ret
+}
+
+// Operation returns the operation of the method and the path of gin, or nil if it isn't documented.
+func (d *Document) Operation(method, ginPath string) *Operation {
+	return d.Paths[Path(ginPath)][strings.ToLower(method)]
+}
+
+// resolve returns the schema which s refers to, or s itself if it isn't a reference.
+func (d *Document) resolve(s *Schema) *Schema {
+	if s.Ref == "" {
+		return s
+	}
+	ret, ok := d.Components.Schemas[strings.TrimPrefix(s.Ref, refPrefix)]
+	if !ok {
+		panic(fmt.Sprintf("unknown schema: %s", s.Ref))
+	}
+	return ret
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// docsHTML is the page which renders the document and sends requests from the browser like Swagger UI.
+// It's self-contained, so that the docs are available without access to CDNs.
+//
+//go:embed docs.html
+var docsHTML []byte
+
+type Handler interface {
+	// Document processes the request of `GET /openapi.json`.
+	Document(c *gin.Context)
+	// Docs processes the request of `GET /docs`.
+	Docs(c *gin.Context)
+}
+
+type openapiHandler struct {
+	doc *Document
+}
+
+func NewHandler(doc *Document) Handler {
+	return &openapiHandler{doc: doc}
+}
+
+func (h *openapiHandler) Document(c *gin.Context) {
+	c.JSON(http.StatusOK, h.doc)
+}
+
+func (h *openapiHandler) Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsHTML)
+}
@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+)
+
+// refPrefix is the prefix of references to the schemas in components.
+const refPrefix = "#/components/schemas/"
+
+// Schema is the Schema Object of OpenAPI 3.0, limited to the keywords used by this API.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Deprecated  bool               `json:"deprecated,omitempty"`
+	Enum        []interface{}      `json:"enum,omitempty"`
+	OneOf       []*Schema          `json:"oneOf,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	// AdditionalProperties is false for structures, or the schema of values for maps.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+}
+
+// enumSchemas are the schemas of the types which are unmarshaled from either numbers or names.
+var enumSchemas = map[reflect.Type]func() *Schema{
+	reflect.TypeOf(handler.StatusValue(0)): func() *Schema {
+		numbers, names := []interface{}{}, []string{}
+		for s := model.StatusNotReady; s <= model.StatusDone; s++ {
+			numbers = append(numbers, int(s))
+			names = append(names, s.Name())
+		}
+		return enumSchema(numbers, names)
+	},
+	reflect.TypeOf(handler.PriorityValue(0)): func() *Schema {
+		numbers, names := []interface{}{}, []string{}
+		for p := model.PriorityHigh; p <= model.PriorityLow; p++ {
+			numbers = append(numbers, int(p))
+			names = append(names, p.Name())
+		}
+		return enumSchema(numbers, names)
+	},
+}
+
+// enumSchema returns the schema of an enum given as the number or the name. Names aren't listed in enum,
+// since they are compared ignoring case, spaces, hyphens and underscores.
+func enumSchema(numbers []interface{}, names []string) *Schema {
+	return &Schema{OneOf: []*Schema{
+		{Type: "integer", Enum: numbers},
+		{Type: "string", Description: "one of " + strings.Join(names, ", ") + ", ignoring case"},
+	}}
+}
+
+// generator generates the schemas of the structures of handlers in a version of API. Structures are
+// registered in components by name, and the ones which differ by version are suffixed with the version,
+// such as `TodoResponseV1` and `TodoResponseV2`.
+//
+// Fields are named by json or form tag. Pointers are nullable, and `binding:"required"` and
+// `binding:"oneof=..."` are reflected to required and enum. Fields of requests, whose names end with
+// `Request`, are optional unless they are bound as required, while fields of responses are required
+// unless they are omitempty. Fields tagged with `openapi:"v1"` or `openapi:"v2"` exist only in the version.
+type generator struct {
+	version    int
+	components map[string]*Schema
+	versioned  map[reflect.Type]bool
+}
+
+func newGenerator(version int, components map[string]*Schema) *generator {
+	return &generator{version: version, components: components, versioned: map[reflect.Type]bool{}}
+}
+
+// schema returns the schema of t, which refers to the component if t is a named structure.
+func (g *generator) schema(t reflect.Type) *Schema {
+	if f, ok := enumSchemas[t]; ok {
+		return f()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := g.componentName(t)
+		if _, ok := g.components[name]; !ok {
+			// registered before the fields, so that recursive structures refer to themselves
+			g.components[name] = &Schema{}
+			*g.components[name] = *g.object(t)
+		}
+		return &Schema{Ref: refPrefix + name}
+	default:
+		// interface{} accepts any value
+		return &Schema{}
+	}
+}
+
+// componentName returns the name of the structure in components.
+func (g *generator) componentName(t reflect.Type) string {
+	if !g.isVersioned(t) {
+		return t.Name()
+	}
+	return fmt.Sprintf("%sV%d", strings.TrimSuffix(t.Name(), "V2"), g.version)
+}
+
+// isVersioned reports whether t has fields which exist only in a version of API.
+func (g *generator) isVersioned(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	if v, ok := g.versioned[t]; ok {
+		return v
+	}
+	g.versioned[t] = false // until found, for recursive structures
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("openapi") != "" || g.isVersioned(f.Type) {
+			g.versioned[t] = true
+			return true
+		}
+	}
+	return false
+}
+
+// object returns the schema of the fields of the structure t.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	g.addFields(s, t, strings.HasSuffix(t.Name(), "Request"))
+	return s
+}
+
+// addFields adds the fields of t to s, flattening embedded structures as encoding/json does.
+func (g *generator) addFields(s *Schema, t reflect.Type, request bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			g.addFields(s, f.Type, request)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		only := f.Tag.Get("openapi")
+		if only != "" && only != fmt.Sprintf("v%d", g.version) {
+			continue
+		}
+		name, omitempty, ok := fieldName(f)
+		if !ok {
+			continue
+		}
+
+		field := g.schema(f.Type)
+		binding := strings.Split(f.Tag.Get("binding"), ",")
+		// fields only in a version are omitempty just to be omitted in the other versions
+		required := !request && (!omitempty || only != "")
+		for _, rule := range binding {
+			if rule == "required" {
+				required = true
+			}
+			if choices := strings.TrimPrefix(rule, "oneof="); choices != rule {
+				for _, choice := range strings.Fields(choices) {
+					field.Enum = append(field.Enum, choice)
+				}
+			}
+		}
+		s.Properties[name] = field
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// fieldName returns the name of the field in JSON or queries, and whether it's omitted if empty.
+// It returns false if the field isn't serialized.
+func fieldName(f reflect.StructField) (string, bool, bool) {
+	for _, key := range []string{"json", "form"} {
+		tag, ok := f.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if parts[0] == "-" {
+			return "", false, false
+		}
+		omitempty := false
+		for _, opt := range parts[1:] {
+			if opt == "omitempty" {
+				omitempty = true
+			}
+		}
+		if parts[0] == "" {
+			return f.Name, omitempty, true
+		}
+		return parts[0], omitempty, true
+	}
+	return f.Name, false, true
+}
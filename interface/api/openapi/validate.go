@@ -0,0 +1,321 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+// ValidateRequest validates the path parameters, the query and the body of the request routed to the path
+// of gin such as `/v2/todos/:id`. It returns BadRequest with the violations, or 415 Unsupported Media Type if
+// the body isn't in the documented media types. Undocumented operations aren't validated.
+func (d *Document) ValidateRequest(ginPath string, params gin.Params, req *http.Request, body []byte) error {
+	op := d.Operation(req.Method, ginPath)
+	if op == nil {
+		return nil
+	}
+
+	verr := &utility.ValidationError{}
+	query := req.URL.Query()
+	for _, param := range op.Parameters {
+		var value string
+		switch {
+		case param.In == "path":
+			value = params.ByName(param.Name)
+		case param.In == "query" && param.Style == "":
+			value = query.Get(param.Name)
+		default:
+			continue
+		}
+		if value == "" {
+			if param.Required {
+				verr.Add(utility.NewFieldError(param.Name, utility.CodeRequired, nil, "%s is required", param.Name))
+			}
+			continue
+		}
+		d.validate(param.Schema, parseParameter(param.Schema, value), param.Name, verr)
+	}
+
+	if op.RequestBody != nil {
+		media, err := d.validateBody(op.RequestBody, req.Header.Get("Content-Type"), body, verr)
+		if err != nil {
+			return err
+		}
+		if media == nil {
+			names := make([]string, 0, len(op.RequestBody.Content))
+			for name := range op.RequestBody.Content {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return utility.NewHTTPError(
+				http.StatusUnsupportedMediaType,
+				fmt.Sprintf("Content-Type must be one of %s", strings.Join(names, ", ")),
+				nil,
+			)
+		}
+	}
+	return verr.Err()
+}
+
+// validateBody validates body in the media type of contentType, which is JSON if omitted. It returns nil
+// media type if contentType isn't accepted.
+func (d *Document) validateBody(
+	reqBody *RequestBody, contentType string, body []byte, verr *utility.ValidationError,
+) (*MediaType, error) {
+	mimeType := mimeJSON
+	if contentType != "" {
+		var err error
+		if mimeType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, utility.BadRequest("Content-Type is invalid", err)
+		}
+	}
+	media, ok := reqBody.Content[mimeType]
+	if !ok {
+		return nil, nil
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if reqBody.Required {
+			verr.Add(utility.NewFieldError("", utility.CodeRequired, nil, "request body is required"))
+		}
+		return media, nil
+	}
+	v, err := decode(body)
+	if err != nil {
+		return nil, utility.BadRequest("request body is invalid JSON", err)
+	}
+	d.validate(media.Schema, v, "", verr)
+	return media, nil
+}
+
+// ValidateResponse validates the JSON body of the response of the operation with the status code and
+// Content-Type, so that tests can check the document against the handlers. It returns the violations, or nil
+// if the body is valid.
+func (d *Document) ValidateResponse(method, ginPath string, status int, contentType string, body []byte) error {
+	op := d.Operation(method, ginPath)
+	if op == nil {
+		return fmt.Errorf("%s %s isn't documented", method, ginPath)
+	}
+	res, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		res = op.Responses["default"]
+	}
+	mimeType := mimeJSON
+	if contentType != "" {
+		var err error
+		if mimeType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return err
+		}
+	}
+	media, ok := res.Content[mimeType]
+	if !ok {
+		if len(res.Content) != 0 && len(body) != 0 {
+			return fmt.Errorf("response %d of %s %s isn't documented in %s", status, method, ginPath, mimeType)
+		}
+		if len(body) != 0 {
+			return fmt.Errorf("response %d of %s %s has no body", status, method, ginPath)
+		}
+		return nil
+	}
+	v, err := decode(body)
+	if err != nil {
+		return err
+	}
+	verr := &utility.ValidationError{}
+	d.validate(media.Schema, v, "", verr)
+	if len(verr.Errors) == 0 {
+		return nil
+	}
+	return verr
+}
+
+// decode decodes JSON keeping numbers as json.Number, so that integers are told from other numbers.
+func decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// parseParameter converts the value of a parameter to the type of the schema, or returns it as it is if it
+// can't be converted, which is reported as invalid_type by validate.
+func parseParameter(s *Schema, value string) interface{} {
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		// same as the binding of gin
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// validate adds the violations of v against s to verr, where field is the name of v such as `items[0].title`.
+func (d *Document) validate(s *Schema, v interface{}, field string, verr *utility.ValidationError) {
+	s = d.resolve(s)
+	if v == nil {
+		if !s.Nullable && (s.Type != "" || len(s.OneOf) > 0) {
+			verr.Add(invalidType(field, d.types(s)))
+		}
+		return
+	}
+
+	if len(s.OneOf) > 0 {
+		for _, alt := range s.OneOf {
+			altErr := &utility.ValidationError{}
+			d.validate(alt, v, field, altErr)
+			if len(altErr.Errors) == 0 {
+				return
+			}
+		}
+		// the violations of the alternative in the same type tell more than invalid_type
+		for _, alt := range s.OneOf {
+			if typeMatches(d.resolve(alt).Type, v) {
+				d.validate(alt, v, field, verr)
+				return
+			}
+		}
+		verr.Add(invalidType(field, d.types(s)))
+		return
+	}
+
+	if !typeMatches(s.Type, v) {
+		verr.Add(invalidType(field, d.types(s)))
+		return
+	}
+	if len(s.Enum) > 0 && !enumContains(s.Enum, v) {
+		choices := make([]string, 0, len(s.Enum))
+		for _, e := range s.Enum {
+			choices = append(choices, fmt.Sprint(e))
+		}
+		verr.Add(utility.NewFieldError(
+			field, utility.CodeInvalidChoice, map[string]interface{}{"choices": choices},
+			"%s must be one of %s", fieldLabel(field), strings.Join(choices, " "),
+		))
+		return
+	}
+
+	switch v := v.(type) {
+	case []interface{}:
+		for i, item := range v {
+			d.validate(s.Items, item, fmt.Sprintf("%s[%d]", field, i), verr)
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				verr.Add(utility.NewFieldError(
+					join(field, name), utility.CodeRequired, nil, "%s is required", join(field, name),
+				))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				d.validate(prop, v[name], join(field, name), verr)
+				continue
+			}
+			switch additional := s.AdditionalProperties.(type) {
+			case *Schema:
+				d.validate(additional, v[name], join(field, name), verr)
+			case bool:
+				if !additional {
+					verr.Add(utility.NewFieldError(
+						join(field, name), utility.CodeNotAllowed, nil, "%s is unknown field", join(field, name),
+					))
+				}
+			}
+		}
+	}
+}
+
+// types returns the types which s accepts, such as `integer or string`.
+func (d *Document) types(s *Schema) string {
+	s = d.resolve(s)
+	if len(s.OneOf) == 0 {
+		return s.Type
+	}
+	types := make([]string, 0, len(s.OneOf))
+	for _, alt := range s.OneOf {
+		types = append(types, d.types(alt))
+	}
+	return strings.Join(types, " or ")
+}
+
+func invalidType(field, expected string) *utility.FieldError {
+	return utility.NewFieldError(
+		field, utility.CodeInvalidType, map[string]interface{}{"expected": expected},
+		"%s must be %s", fieldLabel(field), expected,
+	)
+}
+
+// typeMatches reports whether v decoded by decode is in the type of schema. Empty type matches any value.
+func typeMatches(typ string, v interface{}) bool {
+	switch typ {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+func enumContains(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// join returns the name of the member of the field, such as `todo.title`.
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// fieldLabel returns the field to be shown in messages, where the root is the request body.
+func fieldLabel(field string) string {
+	if field == "" {
+		return "request body"
+	}
+	return field
+}
@@ -0,0 +1,57 @@
+package problem
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type Handler interface {
+	// List processes the request of `GET /problems`.
+	List(c *gin.Context)
+	// Get processes the request of `GET /problems/:code`, which is the type of problems with the code.
+	Get(c *gin.Context)
+}
+
+type problemHandler struct{}
+
+func NewHandler() Handler {
+	return &problemHandler{}
+}
+
+// TypeResponse is the structure representation of the description of an error code.
+type TypeResponse struct {
+	Type   string `json:"type"`
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+}
+
+// ListTypeResponse is the structure representation of the response body of `GET /problems`.
+type ListTypeResponse struct {
+	Entries []TypeResponse `json:"entries"` // in alphabetical order of codes
+}
+
+func buildTypeResponse(code utility.ErrorCode) TypeResponse {
+	return TypeResponse{Type: TypePrefix + string(code), Code: string(code), Title: code.Title(), Status: code.Status()}
+}
+
+func (h *problemHandler) List(c *gin.Context) {
+	codes := utility.ErrorCodes()
+	res := ListTypeResponse{Entries: make([]TypeResponse, 0, len(codes))}
+	for _, code := range codes {
+		res.Entries = append(res.Entries, buildTypeResponse(code))
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *problemHandler) Get(c *gin.Context) {
+	code := utility.ErrorCode(c.Param("code"))
+	if code.Title() == "" {
+		Abort(c, utility.NotFound(fmt.Sprintf("error code %s is not found", code), nil))
+		return
+	}
+	c.JSON(http.StatusOK, buildTypeResponse(code))
+}
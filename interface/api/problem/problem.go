@@ -0,0 +1,105 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+)
+
+// media types of errors.
+const (
+	MIMEProblem = "application/problem+json"
+	MIMELegacy  = "application/json"
+)
+
+// TypePrefix is the prefix of the type of problems, which is followed by the code and served by Handler.
+const TypePrefix = "/problems/"
+
+// Abort sends err as the response and aborts the request. The format is negotiated by Accept header, and
+// it's Problem Details (RFC 7807) by default since v2, while the legacy ErrorResponse is kept by default in v1
+// and the unprefixed paths.
+func Abort(c *gin.Context, err error) {
+	if !acceptsProblem(c) {
+		res := ErrorResponse(err)
+		c.AbortWithStatusJSON(res.ErrCode, res)
+		return
+	}
+	res := Problem(err, c.Request.URL.Path)
+	// gin doesn't overwrite Content-Type set already
+	c.Header("Content-Type", MIMEProblem)
+	c.AbortWithStatusJSON(res.Status, res)
+}
+
+// acceptsProblem reports whether the client prefers Problem Details to the legacy format.
+func acceptsProblem(c *gin.Context) bool {
+	problemByDefault := c.GetInt(config.APIVersionKey) >= 2
+	offered := []string{MIMELegacy, MIMEProblem}
+	if problemByDefault {
+		offered = []string{MIMEProblem, MIMELegacy}
+	}
+	switch c.NegotiateFormat(offered...) {
+	case MIMEProblem:
+		return true
+	case MIMELegacy:
+		return false
+	default:
+		// the errors are sent even if the client doesn't accept JSON
+		return problemByDefault
+	}
+}
+
+// ErrorResponse converts err to the legacy format.
+func ErrorResponse(err error) servermodel.ErrorResponse {
+	var httpErr *utility.HTTPError
+	if errors.As(err, &httpErr) {
+		return servermodel.ErrorResponse{
+			ErrCode: httpErr.ErrCode(),
+			Detail:  httpErr.Error(),
+			Errors:  toFieldErrorResponses(utility.FieldErrors(err)),
+		}
+	}
+	return servermodel.ErrorResponse{ErrCode: http.StatusInternalServerError, Detail: err.Error()}
+}
+
+// Problem converts err to Problem Details of the request to instance.
+func Problem(err error, instance string) servermodel.ProblemResponse {
+	var httpErr *utility.HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = utility.InternalServerError("", err)
+	}
+	code := httpErr.Code()
+	title := code.Title()
+	if title == "" {
+		title = http.StatusText(httpErr.ErrCode())
+	}
+	return servermodel.ProblemResponse{
+		Type:     TypePrefix + string(code),
+		Title:    title,
+		Status:   httpErr.ErrCode(),
+		Detail:   httpErr.Error(),
+		Instance: instance,
+		Code:     string(code),
+		Errors:   toFieldErrorResponses(utility.FieldErrors(err)),
+	}
+}
+
+// toFieldErrorResponses converts violations found by usecases to the response.
+func toFieldErrorResponses(errs []*utility.FieldError) []servermodel.FieldError {
+	if len(errs) == 0 {
+		return nil
+	}
+	ret := make([]servermodel.FieldError, 0, len(errs))
+	for _, fe := range errs {
+		ret = append(ret, servermodel.FieldError{
+			Field:   fe.Field,
+			Code:    fe.Code,
+			Message: fe.Message,
+			Params:  fe.Params,
+		})
+	}
+	return ret
+}
@@ -2,47 +2,193 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/graphql"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/middleware"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/openapi"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
 )
 
 func Route(
 	auth middleware.AuthMiddleware,
 	//dbMiddleware middleware.DBMiddleware,
 	dbMiddleware *middleware.DBMiddleware,
+	idempotency middleware.IdempotencyMiddleware,
+	deprecation middleware.DeprecationMiddleware,
+	validation middleware.ValidationMiddleware,
 	handler handler.TodoHandler,
+	timeEntryHandler handler.TimeEntryHandler,
+	customFieldHandler handler.CustomFieldHandler,
+	templateHandler handler.TemplateHandler,
+	batchHandler handler.BatchHandler,
+	openapiHandler openapi.Handler,
+	problemHandler problem.Handler,
+	graphqlHandler graphql.Handler,
 ) *gin.Engine {
 
 	r := gin.Default()
 
-	todoAPIGroup := r.Group("/todos")
-	todoAPIGroup.Use(auth.NewAuthentication())
+	r.GET("/openapi.json", openapiHandler.Document)
+	r.GET("/docs", openapiHandler.Docs)
+	r.GET("/problems", problemHandler.List)
+	r.GET("/problems/:code", problemHandler.Get)
 
-	todoAPIGroup.POST(
-		"",
+	// GraphQL isn't versioned, and shares the authentication and the transaction with the REST API
+	r.POST(
+		"/graphql",
+		auth.NewAuthentication(),
 		dbMiddleware.NewTransaction(),
-		handler.Create,
-	)
-	todoAPIGroup.GET(
-		"",
-		dbMiddleware.NewDB(),
-		handler.List,
-	)
-	todoAPIGroup.GET(
-		"/:id",
-		dbMiddleware.NewDB(),
-		handler.Get,
-	)
-	todoAPIGroup.PATCH(
-		"/:id",
-		dbMiddleware.NewTransaction(),
-		handler.Update,
-	)
-	todoAPIGroup.DELETE(
-		"/:id",
-		dbMiddleware.NewTransaction(),
-		handler.Delete,
+		graphqlHandler.Query,
 	)
 
+	// every version shares the handlers, which respond in the schema of the version
+	route := func(r *gin.RouterGroup) {
+		todoAPIGroup := r.Group("/todos")
+		todoAPIGroup.Use(auth.NewAuthentication(), validation.NewValidation())
+
+		todoAPIGroup.POST(
+			"",
+			dbMiddleware.NewTransaction(),
+			idempotency.NewIdempotency(),
+			handler.Create,
+		)
+		todoAPIGroup.GET(
+			"",
+			dbMiddleware.NewDB(),
+			handler.List,
+		)
+		todoAPIGroup.GET(
+			"/summary",
+			dbMiddleware.NewDB(),
+			handler.Summary,
+		)
+		todoAPIGroup.GET(
+			"/search",
+			dbMiddleware.NewDB(),
+			handler.Search,
+		)
+		todoAPIGroup.POST(
+			"/batch",
+			dbMiddleware.NewTransaction(),
+			batchHandler.Run,
+		)
+		todoAPIGroup.GET(
+			"/:id",
+			dbMiddleware.NewDB(),
+			handler.Get,
+		)
+		todoAPIGroup.PATCH(
+			"/:id",
+			dbMiddleware.NewTransaction(),
+			handler.Update,
+		)
+		todoAPIGroup.DELETE(
+			"/:id",
+			dbMiddleware.NewTransaction(),
+			handler.Delete,
+		)
+		todoAPIGroup.POST(
+			"/:id/move",
+			dbMiddleware.NewTransaction(),
+			handler.Move,
+		)
+		todoAPIGroup.POST(
+			"/:id/snooze",
+			dbMiddleware.NewTransaction(),
+			handler.Snooze,
+		)
+		todoAPIGroup.POST(
+			"/:id/tasks/:index/toggle",
+			dbMiddleware.NewTransaction(),
+			handler.ToggleTask,
+		)
+		todoAPIGroup.POST(
+			"/:id/timer/start",
+			dbMiddleware.NewTransaction(),
+			timeEntryHandler.Start,
+		)
+		todoAPIGroup.POST(
+			"/:id/timer/stop",
+			dbMiddleware.NewTransaction(),
+			timeEntryHandler.Stop,
+		)
+
+		timeEntryAPIGroup := r.Group("/time-entries")
+		timeEntryAPIGroup.Use(auth.NewAuthentication(), validation.NewValidation())
+
+		timeEntryAPIGroup.GET(
+			"",
+			dbMiddleware.NewDB(),
+			timeEntryHandler.List,
+		)
+		timeEntryAPIGroup.PATCH(
+			"/:id",
+			dbMiddleware.NewTransaction(),
+			timeEntryHandler.Update,
+		)
+		timeEntryAPIGroup.DELETE(
+			"/:id",
+			dbMiddleware.NewTransaction(),
+			timeEntryHandler.Delete,
+		)
+
+		customFieldAPIGroup := r.Group("/custom-fields")
+		customFieldAPIGroup.Use(auth.NewAuthentication(), validation.NewValidation())
+
+		customFieldAPIGroup.POST(
+			"",
+			dbMiddleware.NewTransaction(),
+			customFieldHandler.Create,
+		)
+		customFieldAPIGroup.GET(
+			"",
+			dbMiddleware.NewDB(),
+			customFieldHandler.List,
+		)
+		customFieldAPIGroup.DELETE(
+			"/:id",
+			dbMiddleware.NewTransaction(),
+			customFieldHandler.Delete,
+		)
+
+		templateAPIGroup := r.Group("/templates")
+		templateAPIGroup.Use(auth.NewAuthentication(), validation.NewValidation())
+
+		templateAPIGroup.POST(
+			"",
+			dbMiddleware.NewTransaction(),
+			templateHandler.Create,
+		)
+		templateAPIGroup.GET(
+			"",
+			dbMiddleware.NewDB(),
+			templateHandler.List,
+		)
+		templateAPIGroup.GET(
+			"/:id",
+			dbMiddleware.NewDB(),
+			templateHandler.Get,
+		)
+		templateAPIGroup.PATCH(
+			"/:id",
+			dbMiddleware.NewTransaction(),
+			templateHandler.Update,
+		)
+		templateAPIGroup.DELETE(
+			"/:id",
+			dbMiddleware.NewTransaction(),
+			templateHandler.Delete,
+		)
+		templateAPIGroup.POST(
+			"/:id/instantiate",
+			dbMiddleware.NewTransaction(),
+			templateHandler.Instantiate,
+		)
+	}
+	// the unprefixed paths are the first contract of the API, kept as deprecated aliases of v1
+	route(r.Group("", deprecation.NewDeprecation("/v1"), middleware.APIVersion(1)))
+	route(r.Group("/v1", middleware.APIVersion(1)))
+	route(r.Group("/v2", middleware.APIVersion(2)))
+
 	return r
 }
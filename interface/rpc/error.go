@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of ErrorInfo in the details of statuses, whose reason is the code of the error
+// catalogue such as `todo_not_found`.
+const errorDomain = "golang-todo-api-sample"
+
+// grpcCodes maps the statuses of HTTPError to gRPC codes, following the mapping of google.rpc.Code.
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:           codes.InvalidArgument,
+	http.StatusUnauthorized:         codes.Unauthenticated,
+	http.StatusForbidden:            codes.PermissionDenied,
+	http.StatusNotFound:             codes.NotFound,
+	http.StatusConflict:             codes.Aborted,
+	http.StatusPreconditionFailed:   codes.FailedPrecondition,
+	http.StatusUnsupportedMediaType: codes.InvalidArgument,
+	http.StatusUnprocessableEntity:  codes.InvalidArgument,
+	http.StatusTooManyRequests:      codes.ResourceExhausted,
+	http.StatusInternalServerError:  codes.Internal,
+}
+
+// grpcCodesOfErrors overrides grpcCodes for conflicts which retrying the call doesn't resolve.
+var grpcCodesOfErrors = map[utility.ErrorCode]codes.Code{
+	utility.ErrorCodeCustomFieldAlreadyExists: codes.AlreadyExists,
+	utility.ErrorCodeUserAlreadyExists:        codes.AlreadyExists,
+	utility.ErrorCodeTimerAlreadyRunning:      codes.FailedPrecondition,
+	utility.ErrorCodeTimerNotRunning:          codes.FailedPrecondition,
+	utility.ErrorCodePatchConflict:            codes.FailedPrecondition,
+}
+
+// toStatus converts the error returned by a usecase to the status, with the code of the error catalogue and
+// the violations of fields in the details.
+func toStatus(err error) error {
+	var httpErr *utility.HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = utility.InternalServerError(err.Error(), err)
+	}
+	code, ok := grpcCodesOfErrors[httpErr.Code()]
+	if !ok {
+		if code, ok = grpcCodes[httpErr.ErrCode()]; !ok {
+			code = codes.Unknown
+		}
+	}
+
+	st := status.New(code, httpErr.Error())
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(httpErr.Code()), Domain: errorDomain})
+	if err != nil {
+		return st.Err()
+	}
+	if errs := utility.FieldErrors(httpErr); len(errs) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(errs))
+		for _, fe := range errs {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: fe.Field, Description: fe.Message})
+		}
+		if st, err := withDetails.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+			withDetails = st
+		}
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// contextStream is the stream whose context is replaced by interceptors.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+type AuthInterceptor interface {
+	// NewUnaryAuthentication and NewStreamAuthentication authenticate the user by `authorization` metadata in
+	// the format of `<user id>:<password>`, as AuthMiddleware does with the header.
+	NewUnaryAuthentication() grpc.UnaryServerInterceptor
+	NewStreamAuthentication() grpc.StreamServerInterceptor
+}
+
+type authInterceptor struct {
+	repo repository.UserRepository
+}
+
+func NewAuthInterceptor(repo repository.UserRepository) AuthInterceptor {
+	return &authInterceptor{repo: repo}
+}
+
+func (i *authInterceptor) NewUnaryAuthentication() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, err := i.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *authInterceptor) NewStreamAuthentication() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate returns the context with the id of the authenticated user.
+func (i *authInterceptor) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authentication")
+	}
+	pair := strings.SplitN(values[0], ":", 2)
+	if len(pair) < 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authentication")
+	}
+
+	authenticated, err := i.repo.Authenticate(ctx, pair[0], pair[1])
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if !authenticated {
+		return nil, status.Error(codes.Unauthenticated, "user not found or invalid password")
+	}
+	return context.WithValue(ctx, config.UserIDKey, pair[0]), nil
+}
+
+// DBInterceptor sets the database to the context of calls as DBMiddleware does. Nil DBInterceptor does nothing,
+// which is used with the onmemory repositories.
+type DBInterceptor struct {
+	db *gorm.DB
+}
+
+func NewDBInterceptor(db *gorm.DB) *DBInterceptor {
+	return &DBInterceptor{db: db}
+}
+
+// NewUnaryTransaction runs each unary call in a transaction, which is rolled back if the call fails.
+func (i *DBInterceptor) NewUnaryTransaction() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (res interface{}, err error) {
+		if i == nil {
+			return handler(ctx, req)
+		}
+		tx := i.db.Begin()
+		if err := tx.Error; err != nil {
+			return nil, status.Error(codes.Internal, "can't start transaction")
+		}
+
+		defer func() {
+			if r := recover(); r != nil || err != nil {
+				if rerr := tx.Rollback().Error; rerr != nil {
+					log.Printf("failed to rollback: %v\n", rerr)
+				}
+				if r != nil {
+					panic(r)
+				}
+			} else {
+				if cerr := tx.Commit().Error; cerr != nil {
+					log.Printf("failed to commit: %v\n", cerr)
+					_ = tx.Rollback()
+				}
+			}
+		}()
+
+		return handler(context.WithValue(ctx, config.DBKey, tx), req)
+	}
+}
+
+// NewStreamDB sets the database without transactions to streaming calls, which may last long.
+func (i *DBInterceptor) NewStreamDB() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if i == nil {
+			return handler(srv, ss)
+		}
+		ctx := context.WithValue(ss.Context(), config.DBKey, i.db)
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
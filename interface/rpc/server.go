@@ -0,0 +1,17 @@
+package rpc
+
+import (
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/rpc/todopb"
+	"google.golang.org/grpc"
+)
+
+// NewServer returns the gRPC server of todos, which shares the authentication and the database handling with
+// the REST API. The database is set before the authentication, which reads users from it.
+func NewServer(auth AuthInterceptor, dbInterceptor *DBInterceptor, todoServer todopb.TodoServiceServer) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(dbInterceptor.NewUnaryTransaction(), auth.NewUnaryAuthentication()),
+		grpc.ChainStreamInterceptor(dbInterceptor.NewStreamDB(), auth.NewStreamAuthentication()),
+	)
+	todopb.RegisterTodoServiceServer(s, todoServer)
+	return s
+}
@@ -0,0 +1,343 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/rpc/todopb"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// todoServer is a structure that implements todopb.TodoServiceServer.
+type todoServer struct {
+	todopb.UnimplementedTodoServiceServer
+
+	u usecase.TodoUsecase
+	// watchInterval is the interval of listing todos to find the changes streamed by WatchTodos.
+	watchInterval time.Duration
+	// maxWatches is the max number of streams of WatchTodos of a user, since every stream lists todos.
+	maxWatches int
+
+	sync    sync.Mutex
+	watches map[string]int // the number of streams keyed by user
+}
+
+// NewTodoServer returns the server of TodoService.
+func NewTodoServer(u usecase.TodoUsecase, watchInterval time.Duration, maxWatches int) todopb.TodoServiceServer {
+	return &todoServer{u: u, watchInterval: watchInterval, maxWatches: maxWatches, watches: map[string]int{}}
+}
+
+// userID returns the user authenticated by AuthInterceptor.
+func userID(ctx context.Context) string {
+	id, _ := ctx.Value(config.UserIDKey).(string)
+	return id
+}
+
+func buildTodo(todo *model.Todo) (*todopb.Todo, error) {
+	customFields, err := structpb.NewStruct(todo.CustomFields)
+	if err != nil {
+		return nil, utility.InternalServerError("can't convert custom fields", err)
+	}
+	res := &todopb.Todo{
+		Id:              strconv.Itoa(todo.ID),
+		Title:           todo.Title,
+		Description:     todo.Description,
+		Status:          todopb.Status(todo.Status),
+		Priority:        todopb.Priority(todo.Priority),
+		Position:        todo.Position,
+		Version:         int64(todo.Version),
+		CreatedAt:       timestamppb.New(todo.CreatedAt),
+		UpdatedAt:       timestamppb.New(todo.UpdatedAt),
+		TrackedSeconds:  int64(todo.TrackedTime / time.Second),
+		EstimatePoints:  toInt32(todo.EstimatePoints),
+		EstimateMinutes: toInt32(todo.EstimateMinutes),
+		OwnerId:         todo.UserID,
+		AssigneeId:      todo.AssigneeID,
+		CustomFields:    customFields,
+		Snoozed:         todo.Snoozed(time.Now()),
+	}
+	if todo.StartAt != nil {
+		res.StartAt = timestamppb.New(*todo.StartAt)
+	}
+	return res, nil
+}
+
+func (s *todoServer) CreateTodo(ctx context.Context, req *todopb.CreateTodoRequest) (*todopb.Todo, error) {
+	params := usecase.CreateTodoParams{
+		Title:           req.Title,
+		Description:     req.Description,
+		Status:          int(req.Status),
+		Priority:        int(req.Priority),
+		EstimatePoints:  toInt(req.EstimatePoints),
+		EstimateMinutes: toInt(req.EstimateMinutes),
+		AssigneeID:      req.AssigneeId,
+		CustomFields:    req.CustomFields.AsMap(),
+		StartAt:         req.StartAt,
+	}
+	// unspecified values are the defaults as omitted fields of `POST /todos`
+	if req.Status == todopb.Status_STATUS_UNSPECIFIED {
+		params.Status = int(model.StatusNotReady)
+	}
+	if req.Priority == todopb.Priority_PRIORITY_UNSPECIFIED {
+		params.Priority = int(model.PriorityMiddle)
+	}
+
+	todo, err := s.u.Create(ctx, userID(ctx), params)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.respond(todo)
+}
+
+func (s *todoServer) GetTodo(ctx context.Context, req *todopb.GetTodoRequest) (*todopb.Todo, error) {
+	todo, err := s.u.Get(ctx, userID(ctx), req.Id)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.respond(todo)
+}
+
+func (s *todoServer) ListTodos(ctx context.Context, req *todopb.ListTodosRequest) (*todopb.ListTodosResponse, error) {
+	params := listParams(req.Statuses, req.Priorities, req.IncludeDone, req.IncludeSnoozed, req.Assignee, req.Query)
+	params.Sort = req.Sort
+	params.Limit = toInt(req.Limit)
+	params.Cursor = req.Cursor
+
+	todos, next, err := s.u.List(ctx, userID(ctx), params)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	res := &todopb.ListTodosResponse{Todos: make([]*todopb.Todo, 0, len(todos)), NextCursor: next}
+	for _, todo := range todos {
+		t, err := buildTodo(todo)
+		if err != nil {
+			return nil, toStatus(err)
+		}
+		res.Todos = append(res.Todos, t)
+	}
+	return res, nil
+}
+
+func (s *todoServer) UpdateTodo(ctx context.Context, req *todopb.UpdateTodoRequest) (*todopb.Todo, error) {
+	params := usecase.UpdateTodoParams{
+		Title:           req.Title,
+		Description:     req.Description,
+		EstimatePoints:  utility.NullableFrom(toInt(req.EstimatePoints)),
+		EstimateMinutes: utility.NullableFrom(toInt(req.EstimateMinutes)),
+		AssigneeID:      req.AssigneeId,
+		StartAt:         req.StartAt,
+		IfMatch:         ifMatch(req.IfMatch),
+	}
+	if req.Status != nil {
+		v := int(*req.Status)
+		params.Status = &v
+	}
+	if req.Priority != nil {
+		v := int(*req.Priority)
+		params.Priority = &v
+	}
+	if req.ClearEstimatePoints {
+		params.EstimatePoints = utility.Null[int]()
+	}
+	if req.ClearEstimateMinutes {
+		params.EstimateMinutes = utility.Null[int]()
+	}
+	if req.CustomFields != nil {
+		params.CustomFields = req.CustomFields.AsMap()
+	}
+
+	todo, err := s.u.Update(ctx, userID(ctx), req.Id, params)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return s.respond(todo)
+}
+
+func (s *todoServer) DeleteTodo(ctx context.Context, req *todopb.DeleteTodoRequest) (*todopb.DeleteTodoResponse, error) {
+	if err := s.u.Delete(ctx, userID(ctx), req.Id, ifMatch(req.IfMatch)); err != nil {
+		return nil, toStatus(err)
+	}
+	return &todopb.DeleteTodoResponse{}, nil
+}
+
+// WatchTodos lists the todos every watchInterval, and sends the differences from the previous list. It's
+// based on the list rather than notifications, so that the changes by other instances of the server and
+// the transactions rolled back are handled as well. Streams of a user are limited to maxWatches, so that
+// a user can't make the server list todos without limit.
+func (s *todoServer) WatchTodos(req *todopb.WatchTodosRequest, stream todopb.TodoService_WatchTodosServer) error {
+	ctx := stream.Context()
+	if !s.startWatch(userID(ctx)) {
+		return toStatus(utility.NewHTTPError(
+			http.StatusTooManyRequests, fmt.Sprintf("user can't watch todos by more than %d streams", s.maxWatches), nil,
+		).WithCode(utility.ErrorCodeTooManyWatches))
+	}
+	defer s.stopWatch(userID(ctx))
+
+	params := listParams(req.Statuses, req.Priorities, req.IncludeDone, req.IncludeSnoozed, req.Assignee, req.Query)
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	var known map[int]*model.Todo
+	for {
+		todos, _, err := s.u.List(ctx, userID(ctx), params)
+		if err != nil {
+			return toStatus(err)
+		}
+		events, err := diffTodos(known, todos)
+		if err != nil {
+			return toStatus(err)
+		}
+		if known != nil || req.SendInitial {
+			for _, event := range events {
+				if err := stream.Send(event); err != nil {
+					return err
+				}
+			}
+		}
+		known = make(map[int]*model.Todo, len(todos))
+		for _, todo := range todos {
+			known[todo.ID] = todo
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// startWatch counts a stream of the user, unless the user has maxWatches streams already.
+// It reports whether the stream is counted.
+func (s *todoServer) startWatch(userID string) bool {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+
+	if s.watches[userID] >= s.maxWatches {
+		return false
+	}
+	s.watches[userID]++
+	return true
+}
+
+// stopWatch uncounts a stream of the user.
+func (s *todoServer) stopWatch(userID string) {
+	s.sync.Lock()
+	defer s.sync.Unlock()
+
+	if s.watches[userID]--; s.watches[userID] <= 0 {
+		delete(s.watches, userID)
+	}
+}
+
+// diffTodos returns the events of the changes from known to todos, in the order of todos and then the order
+// of ids of the removed todos.
+func diffTodos(known map[int]*model.Todo, todos []*model.Todo) ([]*todopb.TodoEvent, error) {
+	var events []*todopb.TodoEvent
+	add := func(typ todopb.TodoEvent_Type, todo *model.Todo) error {
+		t, err := buildTodo(todo)
+		if err != nil {
+			return err
+		}
+		events = append(events, &todopb.TodoEvent{Type: typ, Todo: t})
+		return nil
+	}
+
+	listed := make(map[int]bool, len(todos))
+	for _, todo := range todos {
+		listed[todo.ID] = true
+		prev, ok := known[todo.ID]
+		var err error
+		switch {
+		case !ok:
+			err = add(todopb.TodoEvent_TYPE_ADDED, todo)
+		case prev.Version != todo.Version:
+			err = add(todopb.TodoEvent_TYPE_MODIFIED, todo)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	removed := make([]*model.Todo, 0)
+	for id, todo := range known {
+		if !listed[id] {
+			removed = append(removed, todo)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].ID < removed[j].ID })
+	for _, todo := range removed {
+		if err := add(todopb.TodoEvent_TYPE_REMOVED, todo); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+func (s *todoServer) respond(todo *model.Todo) (*todopb.Todo, error) {
+	res, err := buildTodo(todo)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return res, nil
+}
+
+// listParams returns the conditions of todos shared by ListTodos and WatchTodos, ordered by id by default.
+func listParams(
+	statuses []todopb.Status, priorities []todopb.Priority, includeDone, includeSnoozed bool, assignee, query string,
+) usecase.ListTodoParams {
+	statusValues := make([]string, 0, len(statuses))
+	for _, s := range statuses {
+		statusValues = append(statusValues, strconv.Itoa(int(s)))
+	}
+	priorityValues := make([]string, 0, len(priorities))
+	for _, p := range priorities {
+		priorityValues = append(priorityValues, strconv.Itoa(int(p)))
+	}
+	return usecase.ListTodoParams{
+		SortBy:         string(model.SortByID),
+		OrderBy:        string(model.OrderByASC),
+		IncludeDone:    includeDone,
+		IncludeSnoozed: includeSnoozed,
+		Assignee:       assignee,
+		Statuses:       strings.Join(statusValues, ","),
+		Priorities:     strings.Join(priorityValues, ","),
+		Query:          query,
+	}
+}
+
+func toInt(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	ret := int(*v)
+	return &ret
+}
+
+func toInt32(v *int) *int32 {
+	if v == nil {
+		return nil
+	}
+	ret := int32(*v)
+	return &ret
+}
+
+// ifMatch returns the version which the todo must be in, or nil for any version.
+func ifMatch(v *int64) []int {
+	if v == nil {
+		return nil
+	}
+	return []int{int(*v)}
+}
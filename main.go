@@ -2,20 +2,27 @@ package main
 
 import (
 	"log"
+	"net"
 
 	"github.com/gin-gonic/gin"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/infra/persistence/database"
 
 	// "github.com/seiro-ogasawara/golang-todo-api-sample/infra/persistence/onmemory"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/graphql"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/middleware"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/openapi"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/rpc"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+	"google.golang.org/grpc"
 )
 
-func Route() *gin.Engine {
-
+// Route returns the REST API and the gRPC server, which share the usecases.
+func Route(cfg *config.Config) (*gin.Engine, *grpc.Server) {
 	db, err := db.GetDBFromEnvironmentVariables()
 	if err != nil {
 		log.Fatalf("failed to access database: %v\n", err)
@@ -23,18 +30,66 @@ func Route() *gin.Engine {
 
 	//todoRepo := onmemory.NewOnmemoryTodoRepository()
 	//userRepo := onmemory.NewOnmemoryUserRepository()
+	//timeEntryRepo, _ := onmemory.NewOnmemoryTimeEntryRepository(todoRepo)
+	//customFieldRepo := onmemory.NewOnmemoryCustomFieldRepository()
+	//templateRepo := onmemory.NewOnmemoryTemplateRepository()
+	//txRepo := onmemory.NewOnmemoryTransactionRepository()
+	//idempotencyKeyRepo := onmemory.NewOnmemoryIdempotencyKeyRepository()
 	todoRepo := database.NewDatabaseTodoRepository()
 	userRepo := database.NewDatabaseUserRepository()
-	usecase := usecase.NewTodoUsecase(todoRepo)
-	handler := handler.NewTodoHandler(usecase)
+	timeEntryRepo := database.NewDatabaseTimeEntryRepository()
+	customFieldRepo := database.NewDatabaseCustomFieldRepository()
+	templateRepo := database.NewDatabaseTemplateRepository()
+	txRepo := database.NewDatabaseTransactionRepository()
+	idempotencyKeyRepo := database.NewDatabaseIdempotencyKeyRepository()
+	limits := usecase.Limits{TitleMaxLength: cfg.TitleMaxLength, DescriptionMaxLength: cfg.DescriptionMaxLength}
+	todoOptions := usecase.TodoOptions{AutoTimeTracking: cfg.AutoTimeTracking, Limits: limits}
+	todoUsecase := usecase.NewTodoUsecase(todoRepo, timeEntryRepo, userRepo, customFieldRepo, todoOptions)
+	timeEntryUsecase := usecase.NewTimeEntryUsecase(timeEntryRepo, todoRepo)
+	customFieldUsecase := usecase.NewCustomFieldUsecase(customFieldRepo, todoRepo)
+	templateUsecase := usecase.NewTemplateUsecase(templateRepo, todoUsecase, limits)
+	batchUsecase := usecase.NewBatchUsecase(txRepo, todoUsecase)
+	todoHandler := handler.NewTodoHandler(todoUsecase)
+	timeEntryHandler := handler.NewTimeEntryHandler(timeEntryUsecase)
+	customFieldHandler := handler.NewCustomFieldHandler(customFieldUsecase)
+	templateHandler := handler.NewTemplateHandler(templateUsecase)
+	batchHandler := handler.NewBatchHandler(batchUsecase)
+	doc := openapi.NewDocument()
+	openapiHandler := openapi.NewHandler(doc)
+	problemHandler := problem.NewHandler()
+	graphqlHandler := graphql.NewHandler(todoUsecase, txRepo)
 	authMiddleware := middleware.NewAuthMiddleware(userRepo)
 	dbMiddleware := middleware.NewDBMiddleware(db)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyKeyRepo, cfg.IdempotencyKeyTTL)
+	deprecationMiddleware := middleware.NewDeprecationMiddleware(cfg.UnversionedAPIDeprecatedAt, cfg.UnversionedAPISunset)
+	validationMiddleware := middleware.NewValidationMiddleware(doc, cfg.OpenAPIValidation)
+
+	todoServer := rpc.NewTodoServer(todoUsecase, cfg.GRPCWatchInterval, cfg.GRPCMaxWatchesPerUser)
+	authInterceptor := rpc.NewAuthInterceptor(userRepo)
+	dbInterceptor := rpc.NewDBInterceptor(db)
 
-	return api.Route(authMiddleware, dbMiddleware, handler)
+	return api.Route(authMiddleware, dbMiddleware, idempotencyMiddleware, deprecationMiddleware, validationMiddleware, todoHandler, timeEntryHandler, customFieldHandler, templateHandler, batchHandler, openapiHandler, problemHandler, graphqlHandler),
+		rpc.NewServer(authInterceptor, dbInterceptor, todoServer)
 }
 
 func main() {
-	r := Route()
+	cfg, err := config.GetConfigFromEnvironmentVariables()
+	if err != nil {
+		log.Fatalf("failed to read config: %v\n", err)
+	}
+
+	r, grpcServer := Route(cfg)
+	if cfg.GRPCAddress != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddress)
+		if err != nil {
+			log.Fatalf("failed to listen %s: %v\n", cfg.GRPCAddress, err)
+		}
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				panic(err)
+			}
+		}()
+	}
 	if err := r.Run(); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,2 @@
+DROP INDEX todos_user_id_position_idx;
+ALTER TABLE todos DROP COLUMN position;
@@ -0,0 +1,6 @@
+-- positions are compared byte by byte, so the collation must be "C"
+ALTER TABLE todos ADD COLUMN position TEXT COLLATE "C";
+UPDATE todos SET position = lpad(to_hex(id), 8, '0');
+ALTER TABLE todos ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX todos_user_id_position_idx ON todos (user_id, position);
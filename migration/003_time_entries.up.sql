@@ -0,0 +1,14 @@
+CREATE TABLE time_entries (
+	id SERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	todo_id INT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	stopped_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- a user can run only one timer at a time
+CREATE UNIQUE INDEX time_entries_running_idx ON time_entries (user_id) WHERE stopped_at IS NULL;
+CREATE INDEX time_entries_user_id_started_at_idx ON time_entries (user_id, started_at);
+CREATE INDEX time_entries_todo_id_idx ON time_entries (todo_id);
@@ -0,0 +1,2 @@
+ALTER TABLE todos DROP COLUMN estimate_minutes;
+ALTER TABLE todos DROP COLUMN estimate_points;
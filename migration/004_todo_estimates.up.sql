@@ -0,0 +1,2 @@
+ALTER TABLE todos ADD COLUMN estimate_points INT;
+ALTER TABLE todos ADD COLUMN estimate_minutes INT;
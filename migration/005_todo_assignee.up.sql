@@ -0,0 +1,3 @@
+ALTER TABLE todos ADD COLUMN assignee_id TEXT REFERENCES users(user_id) ON DELETE SET NULL;
+
+CREATE INDEX todos_assignee_id_idx ON todos (assignee_id);
@@ -0,0 +1,3 @@
+DROP INDEX todos_custom_fields_idx;
+ALTER TABLE todos DROP COLUMN custom_fields;
+DROP TABLE custom_fields;
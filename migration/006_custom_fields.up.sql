@@ -0,0 +1,14 @@
+CREATE TABLE custom_fields (
+	id SERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	options JSONB NOT NULL DEFAULT '[]',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	UNIQUE (user_id, name)
+);
+
+ALTER TABLE todos ADD COLUMN custom_fields JSONB NOT NULL DEFAULT '{}';
+
+-- for the containment filter `custom_fields @> ?`
+CREATE INDEX todos_custom_fields_idx ON todos USING GIN (custom_fields jsonb_path_ops);
@@ -0,0 +1,10 @@
+CREATE TABLE templates (
+	id SERIAL PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	items JSONB NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX templates_user_id_idx ON templates (user_id);
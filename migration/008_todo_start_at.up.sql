@@ -0,0 +1 @@
+ALTER TABLE todos ADD COLUMN start_at TIMESTAMP WITH TIME ZONE;
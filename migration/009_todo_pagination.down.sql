@@ -0,0 +1 @@
+DROP INDEX todos_user_id_priority_id_idx;
@@ -0,0 +1,2 @@
+-- pages are fetched by keyset on the sort key and id
+CREATE INDEX todos_user_id_priority_id_idx ON todos (user_id, priority, id);
@@ -0,0 +1,2 @@
+DROP INDEX todos_description_trgm_idx;
+DROP INDEX todos_title_trgm_idx;
@@ -0,0 +1,5 @@
+-- trigrams of title and description make substring search by ILIKE fast (replaced by bigrams in 013)
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX todos_title_trgm_idx ON todos USING GIN (title gin_trgm_ops);
+CREATE INDEX todos_description_trgm_idx ON todos USING GIN (description gin_trgm_ops);
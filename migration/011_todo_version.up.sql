@@ -0,0 +1,2 @@
+-- version is compared on update, so that concurrent changes aren't overwritten
+ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+-- responses of requests with Idempotency-Key header, which are replayed on retries until they expire
+CREATE TABLE idempotency_keys (
+	user_id TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	key TEXT NOT NULL,
+	request_hash TEXT NOT NULL,
+	status_code INT NOT NULL,
+	body BYTEA,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (user_id, key)
+);
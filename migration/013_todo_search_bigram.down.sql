@@ -0,0 +1,5 @@
+DROP INDEX todos_description_bigm_idx;
+DROP INDEX todos_title_bigm_idx;
+
+CREATE INDEX todos_title_trgm_idx ON todos USING GIN (title gin_trgm_ops);
+CREATE INDEX todos_description_trgm_idx ON todos USING GIN (description gin_trgm_ops);
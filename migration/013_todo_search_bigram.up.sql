@@ -0,0 +1,9 @@
+-- bigrams of lower-cased title and description make substring search fast, including one or two characters of
+-- Japanese text which have no trigrams. pg_bigm doesn't support ILIKE, so text is matched by LIKE in lower case.
+CREATE EXTENSION IF NOT EXISTS pg_bigm;
+
+DROP INDEX todos_description_trgm_idx;
+DROP INDEX todos_title_trgm_idx;
+
+CREATE INDEX todos_title_bigm_idx ON todos USING GIN (lower(title) gin_bigm_ops);
+CREATE INDEX todos_description_bigm_idx ON todos USING GIN (lower(description) gin_bigm_ops);
@@ -0,0 +1,2 @@
+-- expired keys are purged on reservations, which find them by the time of expiry
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,2 @@
+-- headers of the responses such as ETag, which are replayed with the bodies
+ALTER TABLE idempotency_keys ADD COLUMN header JSONB;
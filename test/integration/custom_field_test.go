@@ -0,0 +1,180 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCustomFieldWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testCustomField(t, router, db, userRepo)
+}
+
+func TestCustomFieldWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testCustomField(t, router, db, userRepo)
+}
+
+func TestCustomFieldWithPostgresRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithPostgresRepository(t)
+	testCustomField(t, router, db, userRepo)
+}
+
+func testCustomField(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	var sprint handler.CustomFieldResponse
+	t.Run("create custom fields", func(t *testing.T) {
+		body := handler.CreateCustomFieldRequest{Name: "sprint", Type: "enum", Options: []string{"s1", "s2"}}
+		w := sendRequest(t, router, "POST", "/custom-fields", "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		decodeResponse(t, w, &sprint)
+		assert.Equal(t, "sprint", sprint.Name)
+		assert.Equal(t, "enum", sprint.Type)
+		assert.Equal(t, []string{"s1", "s2"}, sprint.Options)
+
+		for _, body := range []handler.CreateCustomFieldRequest{
+			{Name: "cost", Type: "number"},
+			{Name: "due", Type: "date"},
+			{Name: "memo", Type: "text"},
+			{Name: "billable", Type: "bool"},
+		} {
+			w := sendRequest(t, router, "POST", "/custom-fields", "userid:password", body)
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		}
+
+		w = sendRequest(t, router, "GET", "/custom-fields", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.ListCustomFieldResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 5, len(actual.Entries))
+
+		w = sendRequest(t, router, "GET", "/custom-fields", "userid2:password2", nil)
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 0, len(actual.Entries))
+	})
+
+	t.Run("create invalid custom fields", func(t *testing.T) {
+		tests := []struct {
+			body handler.CreateCustomFieldRequest
+			code int
+		}{
+			{handler.CreateCustomFieldRequest{Name: "sprint", Type: "text"}, http.StatusConflict},
+			{handler.CreateCustomFieldRequest{Name: "1st", Type: "text"}, http.StatusBadRequest},
+			{handler.CreateCustomFieldRequest{Name: "a-b", Type: "text"}, http.StatusBadRequest},
+			{handler.CreateCustomFieldRequest{Name: "kind", Type: "unknown"}, http.StatusBadRequest},
+			{handler.CreateCustomFieldRequest{Name: "kind", Type: "enum"}, http.StatusBadRequest},
+			{handler.CreateCustomFieldRequest{Name: "kind", Type: "enum", Options: []string{"a", "a"}}, http.StatusBadRequest},
+			{handler.CreateCustomFieldRequest{Name: "kind", Type: "text", Options: []string{"a"}}, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			w := sendRequest(t, router, "POST", "/custom-fields", "userid:password", tt.body)
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		}
+	})
+
+	var todo1, todo2 handler.TodoResponse
+	t.Run("create todos with custom fields", func(t *testing.T) {
+		todo1 = createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+			Title: "t1",
+			CustomFields: map[string]interface{}{
+				"sprint": "s1", "cost": 3.5, "due": "2022-06-01", "memo": "m", "billable": true,
+			},
+		})
+		assert.Equal(t, map[string]interface{}{
+			"sprint": "s1", "cost": 3.5, "due": "2022-06-01", "memo": "m", "billable": true,
+		}, todo1.CustomFields)
+
+		todo2 = createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+			Title:        "t2",
+			CustomFields: map[string]interface{}{"sprint": "s2", "cost": 1},
+		})
+		todo3 := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "t3"})
+		assert.Equal(t, map[string]interface{}{}, todo3.CustomFields)
+	})
+
+	t.Run("create todos with invalid custom fields", func(t *testing.T) {
+		for _, fields := range []map[string]interface{}{
+			{"unknown": "x"},
+			{"sprint": "s3"},
+			{"cost": "1"},
+			{"due": "2022/06/01"},
+			{"billable": "true"},
+			{"memo": 1},
+		} {
+			body := handler.CreateTodoRequest{Title: "t", CustomFields: fields}
+			w := sendRequest(t, router, "POST", "/todos", "userid:password", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, fmt.Sprintf("%v: %s", fields, w.Body.String()))
+		}
+
+		// custom fields of another user can't be used
+		body := handler.CreateTodoRequest{Title: "t", CustomFields: map[string]interface{}{"sprint": "s1"}}
+		w := sendRequest(t, router, "POST", "/todos", "userid2:password2", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("filter and sort by custom fields", func(t *testing.T) {
+		actuals := listTodos(t, router, "userid:password", "cf[sprint]=s2")
+		assert.Equal(t, 1, len(actuals.Entries))
+		assert.Equal(t, todo2.ID, actuals.Entries[0].ID)
+
+		actuals = listTodos(t, router, "userid:password", "cf[billable]=true&cf[cost]=3.5")
+		assert.Equal(t, 1, len(actuals.Entries))
+		assert.Equal(t, todo1.ID, actuals.Entries[0].ID)
+
+		actuals = listTodos(t, router, "userid:password", "sortby=cf.cost&orderby=asc")
+		assert.Equal(t, 3, len(actuals.Entries))
+		assert.Equal(t, todo2.ID, actuals.Entries[0].ID)
+		assert.Equal(t, todo1.ID, actuals.Entries[1].ID)
+
+		actuals = listTodos(t, router, "userid:password", "sortby=cf.cost&orderby=desc")
+		assert.Equal(t, todo1.ID, actuals.Entries[0].ID)
+		assert.Equal(t, todo2.ID, actuals.Entries[1].ID)
+
+		for _, query := range []string{"cf[unknown]=x", "cf[cost]=abc", "cf[sprint]=s3", "sortby=cf.unknown"} {
+			w := sendRequest(t, router, "GET", "/todos?"+query, "userid:password", nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code, fmt.Sprintf("%s: %s", query, w.Body.String()))
+		}
+	})
+
+	t.Run("update custom fields", func(t *testing.T) {
+		body := handler.UpdateTodoRequest{CustomFields: map[string]interface{}{"sprint": "s2", "memo": nil}}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo1.ID), "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TodoResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, map[string]interface{}{
+			"sprint": "s2", "cost": 3.5, "due": "2022-06-01", "billable": true,
+		}, actual.CustomFields)
+
+		body = handler.UpdateTodoRequest{CustomFields: map[string]interface{}{"sprint": "s3"}}
+		w = sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo1.ID), "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("delete custom field removes its values", func(t *testing.T) {
+		w := sendRequest(t, router, "DELETE", fmt.Sprintf("/custom-fields/%s", sprint.ID), "userid2:password2", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+		w = sendRequest(t, router, "DELETE", fmt.Sprintf("/custom-fields/%s", sprint.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		actuals := listTodos(t, router, "userid:password", "")
+		for _, actual := range actuals.Entries {
+			assert.NotContains(t, actual.CustomFields, "sprint")
+		}
+
+		w = sendRequest(t, router, "GET", "/todos?cf[sprint]=s2", "userid:password", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+}
@@ -0,0 +1,249 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/graphql"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestGraphQLWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testGraphQL(t, router, db, userRepo)
+}
+
+func TestGraphQLWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testGraphQL(t, router, db, userRepo)
+}
+
+// panickingTodoRepository panics on updating a todo to "panic", as a bug of a resolver.
+type panickingTodoRepository struct {
+	repository.TodoRepository
+}
+
+func (r panickingTodoRepository) Update(ctx context.Context, todo *model.Todo) error {
+	if todo.Title == "panic" {
+		panic("update panicked")
+	}
+	return r.TodoRepository.Update(ctx, todo)
+}
+
+func TestGraphQLPanicWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithWrappedOnmemoryRepository(t, usecase.TodoOptions{}, false,
+		func(r repository.TodoRepository) repository.TodoRepository { return panickingTodoRepository{r} })
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "todo", Priority: 2, Status: 1})
+
+	w := sendRequest(t, router, "POST", "/graphql", "userid:password", graphql.Request{
+		Query: `mutation Panic($id: ID!) {
+			createTodo(input: {title: "created"}) { id }
+			updateTodo(id: $id, input: {title: "panic"}) { id }
+		}`,
+		Variables: map[string]interface{}{"id": todo.ID},
+	})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res graphQLResponse
+	decodeResponse(t, w, &res)
+	assert.Nil(t, res.Data)
+	if assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, http.StatusInternalServerError, res.Errors[0].Extensions.Status)
+	}
+	entries := listTodos(t, router, "userid:password", "").Entries
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "todo", entries[0].Title)
+	}
+}
+
+type graphQLError struct {
+	Message    string
+	Extensions struct {
+		Code   string
+		Status int
+		Errors []struct {
+			Field string
+			Code  string
+		}
+	}
+}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage
+	Errors []graphQLError
+}
+
+func testGraphQL(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	send := func(t *testing.T, query string, variables map[string]interface{}) graphQLResponse {
+		t.Helper()
+		w := sendRequest(t, router, "POST", "/graphql", "userid:password", graphql.Request{Query: query, Variables: variables})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res graphQLResponse
+		decodeResponse(t, w, &res)
+		return res
+	}
+	decodeField := func(t *testing.T, res graphQLResponse, name string, v interface{}) {
+		t.Helper()
+		if assert.Empty(t, res.Errors) && assert.Contains(t, res.Data, name) {
+			assert.NoError(t, json.Unmarshal(res.Data[name], v))
+		}
+	}
+
+	var created map[string]interface{}
+	t.Run("create", func(t *testing.T) {
+		res := send(t, `mutation Create($input: CreateTodoInput!) {
+			createTodo(input: $input) { id title status priority estimatePoints customFields version }
+		}`, map[string]interface{}{
+			"input": map[string]interface{}{"title": "graphql", "status": "READY", "estimatePoints": 3},
+		})
+		decodeField(t, res, "createTodo", &created)
+		assert.Equal(t, "graphql", created["title"])
+		assert.Equal(t, "READY", created["status"])
+		assert.Equal(t, "MIDDLE", created["priority"])
+		assert.Equal(t, float64(3), created["estimatePoints"])
+		assert.Equal(t, map[string]interface{}{}, created["customFields"])
+		assert.Equal(t, float64(1), created["version"])
+	})
+	id := created["id"]
+
+	t.Run("only the selected fields are responded", func(t *testing.T) {
+		res := send(t, `query Get($id: ID!) { todo(id: $id) { title } }`, map[string]interface{}{"id": id})
+		var todo map[string]interface{}
+		decodeField(t, res, "todo", &todo)
+		assert.Equal(t, map[string]interface{}{"title": "graphql"}, todo)
+	})
+
+	t.Run("todos with filters and pagination", func(t *testing.T) {
+		for _, title := range []string{"second", "third"} {
+			res := send(t, `mutation { createTodo(input: {title: "`+title+`", priority: HIGH}) { id } }`, nil)
+			assert.Empty(t, res.Errors)
+		}
+
+		var page struct {
+			Items []struct {
+				Title    string
+				Priority string
+			}
+			NextCursor *string
+		}
+		query := `query List($cursor: String) {
+			todos(filter: {priority: [HIGH]}, sort: "title", limit: 1, cursor: $cursor) {
+				items { title priority }
+				nextCursor
+			}
+		}`
+		decodeField(t, send(t, query, nil), "todos", &page)
+		if assert.Len(t, page.Items, 1) && assert.NotNil(t, page.NextCursor) {
+			assert.Equal(t, "second", page.Items[0].Title)
+			assert.Equal(t, "HIGH", page.Items[0].Priority)
+
+			decodeField(t, send(t, query, map[string]interface{}{"cursor": *page.NextCursor}), "todos", &page)
+			if assert.Len(t, page.Items, 1) {
+				assert.Equal(t, "third", page.Items[0].Title)
+			}
+			assert.Nil(t, page.NextCursor)
+		}
+
+		decodeField(t, send(t, `{ todos(filter: {status: [READY]}) { items { title } } }`, nil), "todos", &page)
+		if assert.Len(t, page.Items, 1) {
+			assert.Equal(t, "graphql", page.Items[0].Title)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		res := send(t, `mutation Update($id: ID!) {
+			updateTodo(id: $id, input: {title: "renamed", status: DOING, clearEstimatePoints: true}, ifMatch: 1) {
+				title status estimatePoints version
+			}
+		}`, map[string]interface{}{"id": id})
+		var todo map[string]interface{}
+		decodeField(t, res, "updateTodo", &todo)
+		assert.Equal(t, map[string]interface{}{
+			"title": "renamed", "status": "DOING", "estimatePoints": nil, "version": float64(2),
+		}, todo)
+	})
+
+	t.Run("errors are reported with codes", func(t *testing.T) {
+		res := send(t, `mutation Update($id: ID!) {
+			updateTodo(id: $id, input: {title: "stale"}, ifMatch: 1) { title }
+		}`, map[string]interface{}{"id": id})
+		assert.Nil(t, res.Data)
+		if assert.Len(t, res.Errors, 1) {
+			assert.Equal(t, "version_mismatch", res.Errors[0].Extensions.Code)
+			assert.Equal(t, http.StatusPreconditionFailed, res.Errors[0].Extensions.Status)
+		}
+
+		res = send(t, `mutation { createTodo(input: {title: ""}) { id } }`, nil)
+		if assert.Len(t, res.Errors, 1) {
+			assert.Equal(t, "validation_failed", res.Errors[0].Extensions.Code)
+			if assert.Len(t, res.Errors[0].Extensions.Errors, 1) {
+				assert.Equal(t, "title", res.Errors[0].Extensions.Errors[0].Field)
+			}
+		}
+
+		res = send(t, `{ todo(id: "abc") { id } }`, nil)
+		if assert.Len(t, res.Errors, 1) {
+			assert.Equal(t, "invalid_id", res.Errors[0].Extensions.Code)
+		}
+
+		res = send(t, `{ todo(id: 1) { unknown } }`, nil)
+		assert.Nil(t, res.Data)
+		assert.Len(t, res.Errors, 1)
+	})
+
+	t.Run("mutations are undone if any of them fails", func(t *testing.T) {
+		res := send(t, `mutation Partial($id: ID!) {
+			first: createTodo(input: {title: "first"}) { id }
+			renamed: updateTodo(id: $id, input: {title: "partial"}) { title }
+			stale: updateTodo(id: $id, input: {title: "stale"}, ifMatch: 1) { title }
+			last: createTodo(input: {title: "last"}) { id }
+		}`, map[string]interface{}{"id": id})
+		assert.Nil(t, res.Data)
+		if assert.Len(t, res.Errors, 1) {
+			assert.Equal(t, "version_mismatch", res.Errors[0].Extensions.Code)
+		}
+
+		var page struct {
+			Items []struct{ Title string }
+		}
+		decodeField(t, send(t, `{ todos(sort: "title") { items { title } } }`, nil), "todos", &page)
+		titles := []string{}
+		for _, item := range page.Items {
+			titles = append(titles, item.Title)
+		}
+		assert.Equal(t, []string{"renamed", "second", "third"}, titles)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		res := send(t, `mutation Delete($id: ID!) { deleteTodo(id: $id) }`, map[string]interface{}{"id": id})
+		var deleted string
+		decodeField(t, res, "deleteTodo", &deleted)
+		assert.Equal(t, id, deleted)
+
+		res = send(t, `query Get($id: ID!) { todo(id: $id) { id } }`, map[string]interface{}{"id": id})
+		if assert.Len(t, res.Errors, 1) {
+			assert.Equal(t, "todo_not_found", res.Errors[0].Extensions.Code)
+			assert.Equal(t, http.StatusNotFound, res.Errors[0].Extensions.Status)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", "/graphql", "", graphql.Request{Query: "{ todos { items { id } } }"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+
+		w = sendRequest(t, router, "POST", "/graphql", "userid:password", json.RawMessage(`{"variables": {}}`))
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+}
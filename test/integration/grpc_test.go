@@ -0,0 +1,295 @@
+package integration
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/infra/persistence/onmemory"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/rpc"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/rpc/todopb"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gorm.io/gorm"
+)
+
+func TestGRPCWithOnmemoryRepository(t *testing.T) {
+	client, userRepo := createGRPCClient(t, nil)
+	testGRPC(t, client, nil, userRepo)
+}
+
+func TestGRPCWithDatabaseRepository(t *testing.T) {
+	db := db.GetTestDBConn(t)
+	client, userRepo := createGRPCClient(t, db)
+	testGRPC(t, client, db, userRepo)
+}
+
+// createGRPCClient returns the client of the gRPC server in memory, which sets db to calls unless it's nil.
+func createGRPCClient(t *testing.T, db *gorm.DB) (todopb.TodoServiceClient, repository.UserRepository) {
+	t.Helper()
+
+	todoRepo := onmemory.NewOnmemoryTodoRepository()
+	userRepo := onmemory.NewOnmemoryUserRepository()
+	timeEntryRepo, err := onmemory.NewOnmemoryTimeEntryRepository(todoRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customFieldRepo := onmemory.NewOnmemoryCustomFieldRepository()
+	todoUsecase := usecase.NewTodoUsecase(todoRepo, timeEntryRepo, userRepo, customFieldRepo, usecase.TodoOptions{})
+	var dbInterceptor *rpc.DBInterceptor
+	if db != nil {
+		dbInterceptor = rpc.NewDBInterceptor(db)
+	}
+	server := rpc.NewServer(
+		rpc.NewAuthInterceptor(userRepo), dbInterceptor, rpc.NewTodoServer(todoUsecase, 10*time.Millisecond, 2),
+	)
+
+	lis := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(lis)
+	}()
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+	return todopb.NewTodoServiceClient(conn), userRepo
+}
+
+// assertStatus asserts the code of the status and the reason of its ErrorInfo, and returns the violations of
+// fields in its BadRequest.
+func assertStatus(t *testing.T, err error, code codes.Code, reason string) []*errdetails.BadRequest_FieldViolation {
+	t.Helper()
+
+	st, ok := status.FromError(err)
+	if !assert.True(t, ok, err) {
+		return nil
+	}
+	assert.Equal(t, code, st.Code(), st.Message())
+	var violations []*errdetails.BadRequest_FieldViolation
+	actualReason := ""
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			actualReason = detail.Reason
+		case *errdetails.BadRequest:
+			violations = detail.FieldViolations
+		}
+	}
+	assert.Equal(t, reason, actualReason)
+	return violations
+}
+
+func testGRPC(t *testing.T, client todopb.TodoServiceClient, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "userid:password")
+
+	t.Run("authentication", func(t *testing.T) {
+		_, err := client.ListTodos(context.Background(), &todopb.ListTodosRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		wrong := metadata.AppendToOutgoingContext(context.Background(), "authorization", "userid:wrong")
+		_, err = client.ListTodos(wrong, &todopb.ListTodosRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	var created *todopb.Todo
+	t.Run("create and get", func(t *testing.T) {
+		customFields, _ := structpb.NewStruct(map[string]interface{}{})
+		var err error
+		created, err = client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: "grpc", EstimatePoints: proto.Int32(3)})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, "grpc", created.Title)
+		assert.Equal(t, todopb.Status_STATUS_NOT_READY, created.Status)
+		assert.Equal(t, todopb.Priority_PRIORITY_MIDDLE, created.Priority)
+		assert.Equal(t, int32(3), created.GetEstimatePoints())
+		assert.Nil(t, created.EstimateMinutes)
+		assert.Nil(t, created.AssigneeId)
+		assert.Nil(t, created.StartAt)
+		assert.True(t, proto.Equal(customFields, created.CustomFields))
+		assert.Equal(t, int64(1), created.Version)
+		assert.Equal(t, "userid", created.OwnerId)
+
+		got, err := client.GetTodo(ctx, &todopb.GetTodoRequest{Id: created.Id})
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(created, got), got)
+	})
+
+	t.Run("errors are mapped to codes", func(t *testing.T) {
+		_, err := client.GetTodo(ctx, &todopb.GetTodoRequest{Id: "0"})
+		assertStatus(t, err, codes.NotFound, "todo_not_found")
+
+		_, err = client.GetTodo(ctx, &todopb.GetTodoRequest{Id: "abc"})
+		assertStatus(t, err, codes.InvalidArgument, "invalid_id")
+
+		_, err = client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: ""})
+		violations := assertStatus(t, err, codes.InvalidArgument, "validation_failed")
+		if assert.Len(t, violations, 1) {
+			assert.Equal(t, "title", violations[0].Field)
+		}
+
+		_, err = client.UpdateTodo(ctx, &todopb.UpdateTodoRequest{Id: created.Id, Title: proto.String("stale"), IfMatch: proto.Int64(9)})
+		assertStatus(t, err, codes.FailedPrecondition, "version_mismatch")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		status := todopb.Status_STATUS_READY
+		updated, err := client.UpdateTodo(ctx, &todopb.UpdateTodoRequest{
+			Id: created.Id, Title: proto.String("renamed"), Status: &status, ClearEstimatePoints: true,
+			IfMatch: proto.Int64(1),
+		})
+		if assert.NoError(t, err) {
+			assert.Equal(t, "renamed", updated.Title)
+			assert.Equal(t, todopb.Status_STATUS_READY, updated.Status)
+			assert.Nil(t, updated.EstimatePoints)
+			assert.Equal(t, int64(2), updated.Version)
+		}
+	})
+
+	t.Run("list with filters and pagination", func(t *testing.T) {
+		for _, title := range []string{"second", "third"} {
+			_, err := client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: title, Priority: todopb.Priority_PRIORITY_HIGH})
+			assert.NoError(t, err)
+		}
+
+		res, err := client.ListTodos(ctx, &todopb.ListTodosRequest{
+			Priorities: []todopb.Priority{todopb.Priority_PRIORITY_HIGH}, Sort: "-title", Limit: proto.Int32(1),
+		})
+		if assert.NoError(t, err) && assert.Len(t, res.Todos, 1) {
+			assert.Equal(t, "third", res.Todos[0].Title)
+			assert.NotEmpty(t, res.NextCursor)
+
+			res, err = client.ListTodos(ctx, &todopb.ListTodosRequest{
+				Priorities: []todopb.Priority{todopb.Priority_PRIORITY_HIGH}, Sort: "-title", Limit: proto.Int32(1),
+				Cursor: res.NextCursor,
+			})
+			if assert.NoError(t, err) && assert.Len(t, res.Todos, 1) {
+				assert.Equal(t, "second", res.Todos[0].Title)
+				assert.Empty(t, res.NextCursor)
+			}
+		}
+
+		res, err = client.ListTodos(ctx, &todopb.ListTodosRequest{Statuses: []todopb.Status{todopb.Status_STATUS_READY}})
+		if assert.NoError(t, err) && assert.Len(t, res.Todos, 1) {
+			assert.Equal(t, "renamed", res.Todos[0].Title)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		_, err := client.DeleteTodo(ctx, &todopb.DeleteTodoRequest{Id: created.Id, IfMatch: proto.Int64(1)})
+		assertStatus(t, err, codes.FailedPrecondition, "version_mismatch")
+
+		_, err = client.DeleteTodo(ctx, &todopb.DeleteTodoRequest{Id: created.Id})
+		assert.NoError(t, err)
+		_, err = client.GetTodo(ctx, &todopb.GetTodoRequest{Id: created.Id})
+		assertStatus(t, err, codes.NotFound, "todo_not_found")
+	})
+
+	t.Run("watch", func(t *testing.T) {
+		watchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		stream, err := client.WatchTodos(watchCtx, &todopb.WatchTodosRequest{
+			Priorities: []todopb.Priority{todopb.Priority_PRIORITY_HIGH}, SendInitial: true,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		recv := func(t *testing.T, typ todopb.TodoEvent_Type, title string) *todopb.Todo {
+			t.Helper()
+			event, err := stream.Recv()
+			if !assert.NoError(t, err) {
+				t.FailNow()
+			}
+			assert.Equal(t, typ, event.Type)
+			assert.Equal(t, title, event.Todo.Title)
+			return event.Todo
+		}
+
+		recv(t, todopb.TodoEvent_TYPE_ADDED, "second")
+		third := recv(t, todopb.TodoEvent_TYPE_ADDED, "third")
+
+		// todos which don't match the filter aren't watched
+		_, err = client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: "low", Priority: todopb.Priority_PRIORITY_LOW})
+		assert.NoError(t, err)
+		fourth, err := client.CreateTodo(ctx, &todopb.CreateTodoRequest{Title: "fourth", Priority: todopb.Priority_PRIORITY_HIGH})
+		assert.NoError(t, err)
+		recv(t, todopb.TodoEvent_TYPE_ADDED, "fourth")
+
+		_, err = client.UpdateTodo(ctx, &todopb.UpdateTodoRequest{Id: fourth.Id, Title: proto.String("modified")})
+		assert.NoError(t, err)
+		modified := recv(t, todopb.TodoEvent_TYPE_MODIFIED, "modified")
+		assert.Equal(t, int64(2), modified.Version)
+
+		low := todopb.Priority_PRIORITY_LOW
+		_, err = client.UpdateTodo(ctx, &todopb.UpdateTodoRequest{Id: third.Id, Priority: &low})
+		assert.NoError(t, err)
+		recv(t, todopb.TodoEvent_TYPE_REMOVED, "third")
+
+		_, err = client.DeleteTodo(ctx, &todopb.DeleteTodoRequest{Id: fourth.Id})
+		assert.NoError(t, err)
+		recv(t, todopb.TodoEvent_TYPE_REMOVED, "modified")
+
+		cancel()
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	})
+
+	t.Run("streams of watch are limited per user", func(t *testing.T) {
+		// watch returns the error of the first receive, which is after the server counts the stream
+		watch := func(ctx context.Context) error {
+			stream, err := client.WatchTodos(ctx, &todopb.WatchTodosRequest{SendInitial: true})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}
+		// open retries watching until the streams closed before are uncounted, which the server does
+		// asynchronously, and returns the function to close the stream
+		open := func(t *testing.T) context.CancelFunc {
+			t.Helper()
+			var cancel context.CancelFunc
+			assert.Eventually(t, func() bool {
+				var watchCtx context.Context
+				watchCtx, cancel = context.WithCancel(ctx)
+				if err := watch(watchCtx); err != nil {
+					cancel()
+					return false
+				}
+				return true
+			}, 5*time.Second, 10*time.Millisecond)
+			return cancel
+		}
+
+		cancelFirst := open(t)
+		cancelSecond := open(t)
+		defer cancelSecond()
+		assertStatus(t, watch(ctx), codes.ResourceExhausted, "too_many_watches")
+
+		cancelFirst()
+		open(t)()
+	})
+}
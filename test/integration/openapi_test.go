@@ -0,0 +1,252 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/openapi"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestOpenAPIWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testOpenAPI(t, router, db, userRepo)
+}
+
+func TestOpenAPIWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testOpenAPI(t, router, db, userRepo)
+}
+
+func testOpenAPI(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	doc := openapi.NewDocument()
+
+	t.Run("every route is documented", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/openapi.json", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var served openapi.Document
+		decodeResponse(t, w, &served)
+		assert.Equal(t, "3.0.3", served.OpenAPI)
+
+		documented := 0
+		for _, item := range served.Paths {
+			documented += len(item)
+		}
+		routed := 0
+		for _, route := range router.Routes() {
+			if route.Path == "/openapi.json" || route.Path == "/docs" || strings.HasPrefix(route.Path, "/problems") ||
+				route.Path == "/graphql" {
+				continue
+			}
+			routed++
+			assert.NotNil(t, doc.Operation(route.Method, route.Path), "%s %s", route.Method, route.Path)
+			item := served.Paths[openapi.Path(route.Path)]
+			assert.NotNil(t, item[strings.ToLower(route.Method)], "%s %s", route.Method, route.Path)
+		}
+		assert.Equal(t, routed, documented)
+		assert.True(t, served.Paths["/todos"]["get"].Deprecated)
+		assert.False(t, served.Paths["/v2/todos"]["get"].Deprecated)
+	})
+
+	t.Run("docs page", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/docs", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "/openapi.json")
+	})
+
+	t.Run("responses match the document", func(t *testing.T) {
+		send := func(method, url, ginPath string, body interface{}) *httptest.ResponseRecorder {
+			t.Helper()
+			w := sendRequest(t, router, method, url, "userid:password", body)
+			err := doc.ValidateResponse(method, ginPath, w.Code, w.Header().Get("Content-Type"), w.Body.Bytes())
+			assert.NoError(t, err, "%s %s", method, url)
+			return w
+		}
+
+		for _, prefix := range []string{"", "/v1", "/v2"} {
+			w := send("POST", prefix+"/todos", prefix+"/todos", handler.CreateTodoRequest{
+				Title: "title", Description: "- [ ] task", Status: 2, EstimatePoints: ptr(3),
+			})
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			var todo handler.TodoResponse
+			decodeResponse(t, w, &todo)
+
+			send("GET", prefix+"/todos/"+todo.ID+"?render=html", prefix+"/todos/:id", nil)
+			send("GET", prefix+"/todos?limit=1", prefix+"/todos", nil)
+			send("GET", prefix+"/todos/search?q=title", prefix+"/todos/search", nil)
+			send("GET", prefix+"/todos/summary", prefix+"/todos/summary", nil)
+			send("PATCH", prefix+"/todos/"+todo.ID, prefix+"/todos/:id", handler.UpdateTodoRequest{Title: ptr("new")})
+			send("POST", prefix+"/todos/"+todo.ID+"/tasks/0/toggle", prefix+"/todos/:id/tasks/:index/toggle", nil)
+			send("POST", prefix+"/todos/"+todo.ID+"/snooze", prefix+"/todos/:id/snooze", handler.SnoozeTodoRequest{Until: "1d"})
+			send("POST", prefix+"/todos/"+todo.ID+"/timer/start", prefix+"/todos/:id/timer/start", nil)
+			send("POST", prefix+"/todos/"+todo.ID+"/timer/stop", prefix+"/todos/:id/timer/stop", nil)
+			send("GET", prefix+"/time-entries", prefix+"/time-entries", nil)
+			w = send("POST", prefix+"/todos/batch", prefix+"/todos/batch", handler.BatchRequest{
+				Operations: []handler.BatchOperationRequest{{Op: "delete", ID: todo.ID}, {Op: "delete", ID: "0"}},
+			})
+			assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+			send("POST", prefix+"/todos/batch", prefix+"/todos/batch", handler.BatchRequest{
+				Mode:       "bestEffort",
+				Operations: []handler.BatchOperationRequest{{Op: "delete", ID: todo.ID}, {Op: "delete", ID: "0"}},
+			})
+			w = send("GET", prefix+"/todos/"+todo.ID, prefix+"/todos/:id", nil)
+			assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+
+			w = send("POST", prefix+"/templates", prefix+"/templates", handler.CreateTemplateRequest{
+				Name: "template" + prefix, Items: []handler.TemplateItemRequest{{Title: "{{date}}"}},
+			})
+			var template handler.TemplateResponse
+			decodeResponse(t, w, &template)
+			send("GET", prefix+"/templates", prefix+"/templates", nil)
+			send(
+				"POST", prefix+"/templates/"+template.ID+"/instantiate", prefix+"/templates/:id/instantiate",
+				handler.InstantiateTemplateRequest{},
+			)
+			send("DELETE", prefix+"/templates/"+template.ID, prefix+"/templates/:id", nil)
+
+			w = send("POST", prefix+"/custom-fields", prefix+"/custom-fields", handler.CreateCustomFieldRequest{
+				Name: "field" + strings.TrimPrefix(prefix, "/"), Type: "enum", Options: []string{"a"},
+			})
+			assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+			send("GET", prefix+"/custom-fields", prefix+"/custom-fields", nil)
+		}
+	})
+
+	t.Run("requests aren't validated by default", func(t *testing.T) {
+		body := json.RawMessage(`{"title": "unknown field is ignored", "titel": "typo"}`)
+		w := sendRequest(t, router, "POST", "/v2/todos", "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	})
+}
+
+func TestOpenAPIValidationWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepositoryAndValidation(t, usecase.TodoOptions{}, true)
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	assertFieldErrors := func(t *testing.T, w *httptest.ResponseRecorder, expected ...servermodel.FieldError) {
+		t.Helper()
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var actual servermodel.ProblemResponse
+		if w.Header().Get("Content-Type") == problem.MIMEProblem {
+			decodeResponse(t, w, &actual)
+			assert.Equal(t, string(utility.ErrorCodeValidationFailed), actual.Code)
+		} else {
+			var legacy servermodel.ErrorResponse
+			decodeResponse(t, w, &legacy)
+			assert.Equal(t, http.StatusBadRequest, legacy.ErrCode)
+			actual.Errors = legacy.Errors
+		}
+		for i := range actual.Errors {
+			actual.Errors[i].Message = ""
+		}
+		assert.Equal(t, expected, actual.Errors)
+	}
+
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "valid", Status: 2})
+
+	t.Run("valid requests", func(t *testing.T) {
+		body := json.RawMessage(`{"title": "names", "status": "Ready", "priority": 1, "estimatePoints": null}`)
+		w := sendRequest(t, router, "POST", "/v2/todos", "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		w = sendRequest(t, router, "GET", "/v2/todos?limit=1&includeDone=true", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = sendRequestWithHeader(
+			t, router, "PATCH", "/v2/todos/"+todo.ID, "userid:password",
+			map[string]string{"Content-Type": "application/merge-patch+json"}, json.RawMessage(`{"estimatePoints": null}`),
+		)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("authentication precedes validation", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", "/v2/todos", "", json.RawMessage(`{"titel": "typo"}`))
+		assert.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+	})
+
+	t.Run("unknown fields and types", func(t *testing.T) {
+		body := json.RawMessage(`{"title": 1, "titel": "typo", "status": true, "priority": 9}`)
+		w := sendRequest(t, router, "POST", "/v2/todos", "userid:password", body)
+		assertFieldErrors(t, w,
+			servermodel.FieldError{
+				Field: "priority", Code: "invalid_choice",
+				Params: map[string]interface{}{"choices": []interface{}{"1", "2", "3"}},
+			},
+			servermodel.FieldError{
+				Field: "status", Code: "invalid_type", Params: map[string]interface{}{"expected": "integer or string"},
+			},
+			servermodel.FieldError{Field: "titel", Code: "not_allowed"},
+			servermodel.FieldError{Field: "title", Code: "invalid_type", Params: map[string]interface{}{"expected": "string"}},
+		)
+	})
+
+	t.Run("nested fields", func(t *testing.T) {
+		body := json.RawMessage(`{"operations": [{"op": "create", "todo": {"title": "a", "estimatePoints": 1.5}}]}`)
+		w := sendRequest(t, router, "POST", "/v2/todos/batch", "userid:password", body)
+		assertFieldErrors(t, w, servermodel.FieldError{
+			Field: "operations[0].todo.estimatePoints", Code: "invalid_type",
+			Params: map[string]interface{}{"expected": "integer"},
+		})
+
+		w = sendRequest(t, router, "POST", "/v1/templates", "userid:password", json.RawMessage(`{"name": "t"}`))
+		assertFieldErrors(t, w, servermodel.FieldError{Field: "items", Code: "required"})
+	})
+
+	t.Run("required fields", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", "/v2/todos/"+todo.ID+"/snooze", "userid:password", json.RawMessage(`{}`))
+		assertFieldErrors(t, w, servermodel.FieldError{Field: "until", Code: "required"})
+
+		w = sendRequest(t, router, "POST", "/v2/todos/"+todo.ID+"/move", "userid:password", nil)
+		assertFieldErrors(t, w, servermodel.FieldError{Code: "required"})
+	})
+
+	t.Run("query and path parameters", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/v2/todos?limit=ten", "userid:password", nil)
+		assertFieldErrors(t, w, servermodel.FieldError{
+			Field: "limit", Code: "invalid_type", Params: map[string]interface{}{"expected": "integer"},
+		})
+
+		w = sendRequest(t, router, "GET", "/todos/"+todo.ID+"?render=pdf", "userid:password", nil)
+		assertFieldErrors(t, w, servermodel.FieldError{
+			Field: "render", Code: "invalid_choice", Params: map[string]interface{}{"choices": []interface{}{"html"}},
+		})
+
+		w = sendRequest(t, router, "POST", "/v2/todos/"+todo.ID+"/tasks/first/toggle", "userid:password", nil)
+		assertFieldErrors(t, w, servermodel.FieldError{
+			Field: "index", Code: "invalid_type", Params: map[string]interface{}{"expected": "integer"},
+		})
+	})
+
+	t.Run("media types", func(t *testing.T) {
+		w := sendRequestWithHeader(
+			t, router, "PATCH", "/v2/todos/"+todo.ID, "userid:password",
+			map[string]string{"Content-Type": "text/plain"}, json.RawMessage(`{"title": "text"}`),
+		)
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code, w.Body.String())
+
+		w = sendRequestWithHeader(
+			t, router, "PATCH", "/v2/todos/"+todo.ID, "userid:password",
+			map[string]string{"Content-Type": "application/json-patch+json"},
+			json.RawMessage(`[{"op": "rename", "path": "/title"}]`),
+		)
+		assertFieldErrors(t, w, servermodel.FieldError{
+			Field: "[0].op", Code: "invalid_choice",
+			Params: map[string]interface{}{"choices": []interface{}{"add", "remove", "replace", "move", "copy", "test"}},
+		})
+	})
+}
@@ -0,0 +1,149 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProblemWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testProblem(t, router, db, userRepo)
+}
+
+func TestProblemWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testProblem(t, router, db, userRepo)
+}
+
+func testProblem(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "title", Status: 2})
+
+	t.Run("v1 responds in the legacy format by default", func(t *testing.T) {
+		for _, prefix := range []string{"", "/v1"} {
+			w := sendRequest(t, router, "GET", prefix+"/todos/0", "userid:password", nil)
+			assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+			assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+			var actual servermodel.ErrorResponse
+			decodeResponse(t, w, &actual)
+			assert.Equal(t, servermodel.ErrorResponse{ErrCode: http.StatusNotFound, Detail: actual.Detail}, actual)
+			assert.NotEmpty(t, actual.Detail)
+		}
+	})
+
+	t.Run("v2 responds problem details by default", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/v2/todos/0", "userid:password", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		assert.Equal(t, problem.MIMEProblem, w.Header().Get("Content-Type"))
+		var actual servermodel.ProblemResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, servermodel.ProblemResponse{
+			Type:     "/problems/todo_not_found",
+			Title:    "Todo is not found",
+			Status:   http.StatusNotFound,
+			Detail:   actual.Detail,
+			Instance: "/v2/todos/0",
+			Code:     "todo_not_found",
+		}, actual)
+		assert.NotEmpty(t, actual.Detail)
+	})
+
+	t.Run("format is negotiated by Accept header", func(t *testing.T) {
+		w := sendRequestWithHeader(
+			t, router, "GET", "/v1/todos/0", "userid:password", map[string]string{"Accept": problem.MIMEProblem}, nil,
+		)
+		assert.Equal(t, problem.MIMEProblem, w.Header().Get("Content-Type"))
+		var actual servermodel.ProblemResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, "todo_not_found", actual.Code)
+		assert.Equal(t, "/v1/todos/0", actual.Instance)
+
+		w = sendRequestWithHeader(
+			t, router, "GET", "/v2/todos/0", "userid:password", map[string]string{"Accept": "application/json"}, nil,
+		)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		var legacy servermodel.ErrorResponse
+		decodeResponse(t, w, &legacy)
+		assert.Equal(t, http.StatusNotFound, legacy.ErrCode)
+
+		// the errors are sent in the default format even if no format is acceptable
+		w = sendRequestWithHeader(
+			t, router, "GET", "/v2/todos/0", "userid:password", map[string]string{"Accept": "text/html"}, nil,
+		)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, problem.MIMEProblem, w.Header().Get("Content-Type"))
+	})
+
+	t.Run("codes of errors", func(t *testing.T) {
+		send := func(method, url, auth string, header map[string]string, body interface{}) servermodel.ProblemResponse {
+			t.Helper()
+			w := sendRequestWithHeader(t, router, method, url, auth, header, body)
+			var actual servermodel.ProblemResponse
+			decodeResponse(t, w, &actual)
+			assert.Equal(t, w.Code, actual.Status, w.Body.String())
+			return actual
+		}
+
+		actual := send("GET", "/v2/todos", "", nil, nil)
+		assert.Equal(t, "unauthorized", actual.Code)
+		assert.Equal(t, http.StatusUnauthorized, actual.Status)
+
+		actual = send("GET", "/v2/todos/abc", "userid:password", nil, nil)
+		assert.Equal(t, "invalid_id", actual.Code)
+
+		actual = send("POST", "/v2/todos", "userid:password", nil, json.RawMessage(`{"status": 2}`))
+		assert.Equal(t, "validation_failed", actual.Code)
+		assert.Equal(t, "/problems/validation_failed", actual.Type)
+		if assert.Len(t, actual.Errors, 1) {
+			assert.Equal(t, "title", actual.Errors[0].Field)
+		}
+
+		actual = send(
+			"PATCH", "/v2/todos/"+todo.ID, "userid:password", map[string]string{"If-Match": `"9-0"`},
+			handler.UpdateTodoRequest{Title: ptr("renamed")},
+		)
+		assert.Equal(t, "version_mismatch", actual.Code)
+		assert.Equal(t, http.StatusPreconditionFailed, actual.Status)
+
+		actual = send(
+			"POST", "/v2/todos/"+todo.ID+"/timer/stop", "userid:password", nil, nil,
+		)
+		assert.Equal(t, "timer_not_running", actual.Code)
+	})
+
+	t.Run("catalogue of codes", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/problems", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var list problem.ListTypeResponse
+		decodeResponse(t, w, &list)
+		assert.Contains(t, list.Entries, problem.TypeResponse{
+			Type: "/problems/todo_not_found", Code: "todo_not_found", Title: "Todo is not found", Status: http.StatusNotFound,
+		})
+		for i := 1; i < len(list.Entries); i++ {
+			assert.Less(t, list.Entries[i-1].Code, list.Entries[i].Code)
+		}
+
+		w = sendRequest(t, router, "GET", "/problems/validation_failed", "", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual problem.TypeResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, problem.TypeResponse{
+			Type: "/problems/validation_failed", Code: "validation_failed", Title: "Request has invalid fields",
+			Status: http.StatusBadRequest,
+		}, actual)
+
+		w = sendRequest(t, router, "GET", "/problems/unknown", "", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+}
@@ -0,0 +1,134 @@
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTemplateWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTemplate(t, router, db, userRepo)
+}
+
+func TestTemplateWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTemplate(t, router, db, userRepo)
+}
+
+func testTemplate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	var release handler.TemplateResponse
+	t.Run("create template", func(t *testing.T) {
+		body := handler.CreateTemplateRequest{
+			Name: "release",
+			Items: []handler.TemplateItemRequest{
+				{Title: "release {{version}} on {{date}}", Description: "tag {{ version }}", Priority: ptr[handler.PriorityValue](1)},
+				{Title: "announce {{version}}", EstimateMinutes: ptr(30)},
+			},
+		}
+		w := sendRequest(t, router, "POST", "/templates", "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		decodeResponse(t, w, &release)
+		assert.Equal(t, "release", release.Name)
+		assert.Equal(t, 2, len(release.Items))
+		assert.Equal(t, 1, release.Items[0].Priority)
+		assert.Equal(t, 2, release.Items[1].Priority)
+		assert.Equal(t, 1, release.Items[1].Status)
+
+		w = sendRequest(t, router, "GET", "/templates", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.ListTemplateResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 1, len(actual.Entries))
+
+		w = sendRequest(t, router, "GET", fmt.Sprintf("/templates/%s", release.ID), "userid2:password2", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+
+	t.Run("create invalid template", func(t *testing.T) {
+		for _, body := range []handler.CreateTemplateRequest{
+			{Name: "empty", Items: []handler.TemplateItemRequest{}},
+			{Name: "no title", Items: []handler.TemplateItemRequest{{Description: "d"}}},
+			{Name: "bad status", Items: []handler.TemplateItemRequest{{Title: "t", Status: ptr[handler.StatusValue](9)}}},
+			{Name: "", Items: []handler.TemplateItemRequest{{Title: "t"}}},
+		} {
+			w := sendRequest(t, router, "POST", "/templates", "userid:password", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, fmt.Sprintf("%s: %s", body.Name, w.Body.String()))
+		}
+	})
+
+	t.Run("instantiate template", func(t *testing.T) {
+		body := handler.InstantiateTemplateRequest{Variables: map[string]string{"version": "v1.2"}}
+		url := fmt.Sprintf("/templates/%s/instantiate", release.ID)
+		w := sendRequest(t, router, "POST", url, "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var actual handler.ListTodoResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 2, len(actual.Entries))
+		today := time.Now().Format("2006-01-02")
+		assert.Equal(t, fmt.Sprintf("release v1.2 on %s", today), actual.Entries[0].Title)
+		assert.Equal(t, "tag v1.2", actual.Entries[0].Description)
+		assert.Equal(t, 1, actual.Entries[0].Priority)
+		assert.Equal(t, "announce v1.2", actual.Entries[1].Title)
+		assert.Equal(t, 30, *actual.Entries[1].EstimateMinutes)
+
+		body = handler.InstantiateTemplateRequest{Variables: map[string]string{"version": "v2", "date": "next week"}}
+		w = sendRequest(t, router, "POST", url, "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, "release v2 on next week", actual.Entries[0].Title)
+
+		todos := listTodos(t, router, "userid:password", "")
+		assert.Equal(t, 4, len(todos.Entries))
+	})
+
+	t.Run("instantiate template with invalid variables", func(t *testing.T) {
+		url := fmt.Sprintf("/templates/%s/instantiate", release.ID)
+		for _, variables := range []map[string]string{
+			nil,
+			{"version": "v1", "unknown": "x"},
+			{"version": "a long version which makes the title exceed the limit of its length"},
+		} {
+			body := handler.InstantiateTemplateRequest{Variables: variables}
+			w := sendRequest(t, router, "POST", url, "userid:password", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, fmt.Sprintf("%v: %s", variables, w.Body.String()))
+		}
+		// nothing is created from an invalid instantiation
+		todos := listTodos(t, router, "userid:password", "")
+		assert.Equal(t, 4, len(todos.Entries))
+
+		body := handler.InstantiateTemplateRequest{Variables: map[string]string{"version": "v1"}}
+		w := sendRequest(t, router, "POST", url, "userid2:password2", body)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+
+	t.Run("update and delete template", func(t *testing.T) {
+		body := handler.UpdateTemplateRequest{
+			Name:  ptr("onboarding"),
+			Items: []handler.TemplateItemRequest{{Title: "welcome {{name}}"}},
+		}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/templates/%s", release.ID), "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TemplateResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, "onboarding", actual.Name)
+		assert.Equal(t, 1, len(actual.Items))
+
+		w = sendRequest(t, router, "DELETE", fmt.Sprintf("/templates/%s", release.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = sendRequest(t, router, "GET", fmt.Sprintf("/templates/%s", release.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+}
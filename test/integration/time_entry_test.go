@@ -0,0 +1,216 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/infra/persistence/onmemory"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTimeEntryWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTimeEntry(t, router, db, userRepo)
+}
+
+func TestTimeEntryWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTimeEntry(t, router, db, userRepo)
+}
+
+func testTimeEntry(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	todo1 := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "t1"})
+	todo2 := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "t2"})
+
+	var entry1 handler.TimeEntryResponse
+	t.Run("start timer", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/timer/start", todo1.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		decodeResponse(t, w, &entry1)
+		assert.Equal(t, todo1.ID, entry1.TodoID)
+		assert.True(t, entry1.Running)
+	})
+
+	t.Run("starting another timer stops the running one", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/timer/start", todo2.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		entries := listTimeEntries(t, router, "userid:password", "")
+		assert.Equal(t, 2, len(entries.Entries))
+		assert.False(t, entries.Entries[0].Running)
+		assert.True(t, entries.Entries[1].Running)
+	})
+
+	t.Run("stop timer which isn't running", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/timer/stop", todo1.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	})
+
+	t.Run("stop timer", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/timer/stop", todo2.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TimeEntryResponse
+		decodeResponse(t, w, &actual)
+		assert.False(t, actual.Running)
+		assert.NotEmpty(t, actual.StoppedAt)
+	})
+
+	t.Run("others todo", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/timer/start", todo1.ID), "userid2:password2", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+
+	t.Run("edit entry", func(t *testing.T) {
+		stoppedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+		startedAt := stoppedAt.Add(-90 * time.Minute)
+		body := handler.UpdateTimeEntryRequest{
+			StartedAt: ptr(startedAt.Format(time.RFC3339)),
+			StoppedAt: ptr(stoppedAt.Format(time.RFC3339)),
+		}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/time-entries/%s", entry1.ID), "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TimeEntryResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, int64(90*60), actual.DurationSeconds)
+
+		// tracked time is included in todo
+		w = sendRequest(t, router, "GET", fmt.Sprintf("/todos/%s", todo1.ID), "userid:password", nil)
+		var todo handler.TodoResponse
+		decodeResponse(t, w, &todo)
+		assert.Equal(t, int64(90*60), todo.TrackedSeconds)
+	})
+
+	t.Run("fail, stopped before started", func(t *testing.T) {
+		body := handler.UpdateTimeEntryRequest{
+			StoppedAt: ptr(time.Now().Add(-24 * time.Hour).Format(time.RFC3339)),
+		}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/time-entries/%s", entry1.ID), "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("filter by date range", func(t *testing.T) {
+		today := time.Now().UTC().Format("2006-01-02")
+		yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")
+		entries := listTimeEntries(t, router, "userid:password", fmt.Sprintf("from=%s&to=%s", yesterday, today))
+		assert.Equal(t, 2, len(entries.Entries))
+
+		tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+		entries = listTimeEntries(t, router, "userid:password", fmt.Sprintf("from=%s", tomorrow))
+		assert.Equal(t, 0, len(entries.Entries))
+
+		w := sendRequest(t, router, "GET", fmt.Sprintf("/time-entries?from=%s&to=%s", today, yesterday), "userid:password", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("delete entry", func(t *testing.T) {
+		w := sendRequest(t, router, "DELETE", fmt.Sprintf("/time-entries/%s", entry1.ID), "userid2:password2", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		w = sendRequest(t, router, "DELETE", fmt.Sprintf("/time-entries/%s", entry1.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		entries := listTimeEntries(t, router, "userid:password", "")
+		assert.Equal(t, 1, len(entries.Entries))
+	})
+
+	t.Run("entries are deleted with todo", func(t *testing.T) {
+		w := sendRequest(t, router, "DELETE", fmt.Sprintf("/todos/%s", todo2.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		entries := listTimeEntries(t, router, "userid:password", "")
+		assert.Equal(t, 0, len(entries.Entries))
+	})
+}
+
+func TestTimeEntryAutoTrackingWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepositoryAndOption(t, usecase.TodoOptions{AutoTimeTracking: true})
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "t1"})
+
+	body := handler.UpdateTodoRequest{Status: ptr(handler.StatusValue(model.StatusDoing))}
+	w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo.ID), "userid:password", body)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	entries := listTimeEntries(t, router, "userid:password", "")
+	assert.Equal(t, 1, len(entries.Entries))
+	assert.Equal(t, todo.ID, entries.Entries[0].TodoID)
+	assert.True(t, entries.Entries[0].Running)
+
+	body = handler.UpdateTodoRequest{Status: ptr(handler.StatusValue(model.StatusDone))}
+	w = sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo.ID), "userid:password", body)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	entries = listTimeEntries(t, router, "userid:password", "")
+	assert.Equal(t, 1, len(entries.Entries))
+	assert.False(t, entries.Entries[0].Running)
+}
+
+func TestTimeEntryRepositoryRejectsOtherTodoRepository(t *testing.T) {
+	// a wrapped repository can't delete the entries with its todos
+	_, err := onmemory.NewOnmemoryTimeEntryRepository(failingTodoRepository{onmemory.NewOnmemoryTodoRepository()})
+	assert.Error(t, err)
+}
+
+func listTimeEntries(t *testing.T, router *gin.Engine, auth string, query string) handler.ListTimeEntryResponse {
+	t.Helper()
+
+	w := sendRequest(t, router, "GET", "/time-entries?"+query, auth, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var ret handler.ListTimeEntryResponse
+	decodeResponse(t, w, &ret)
+	return ret
+}
+
+func sendRequest(
+	t *testing.T, router *gin.Engine, method, url, auth string, reqBody interface{},
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return sendRequestWithHeader(t, router, method, url, auth, nil, reqBody)
+}
+
+func sendRequestWithHeader(
+	t *testing.T, router *gin.Engine, method, url, auth string, header map[string]string, reqBody interface{},
+) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body io.Reader
+	if reqBody != nil {
+		b, _ := json.Marshal(reqBody)
+		body = bytes.NewBuffer(b)
+	}
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, body)
+	req.Header.Set("Authorization", auth)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatal(err)
+	}
+}
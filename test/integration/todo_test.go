@@ -4,22 +4,32 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/infra/persistence/database"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/infra/persistence/onmemory"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/graphql"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/handler"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/middleware"
+	servermodel "github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/openapi"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/interface/api/problem"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/usecase"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/config"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/db"
 	"github.com/stretchr/testify/assert"
@@ -57,8 +67,8 @@ func testTodoCreate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 			body: handler.CreateTodoRequest{
 				Title:       "title string",
 				Description: "description string",
-				Status:      int(model.StatusNotReady),
-				Priority:    int(model.PriorityHigh),
+				Status:      handler.StatusValue(model.StatusNotReady),
+				Priority:    handler.PriorityValue(model.PriorityHigh),
 			},
 			expectStatus: http.StatusCreated,
 			expectResponse: handler.TodoResponse{
@@ -113,7 +123,7 @@ func testTodoCreate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 			body: handler.CreateTodoRequest{
 				Title:       "title string",
 				Description: "description string",
-				Status:      int(model.StatusDone) + 1,
+				Status:      handler.StatusValue(model.StatusDone) + 1,
 			},
 			expectStatus: http.StatusBadRequest,
 		},
@@ -123,7 +133,7 @@ func testTodoCreate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 			body: handler.CreateTodoRequest{
 				Title:       "title string",
 				Description: "description string",
-				Priority:    int(model.PriorityLow) + 1,
+				Priority:    handler.PriorityValue(model.PriorityLow) + 1,
 			},
 			expectStatus: http.StatusBadRequest,
 		},
@@ -196,8 +206,8 @@ func testTodoGet(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo reposit
 		reqBody := handler.CreateTodoRequest{
 			Title:       "title string",
 			Description: "description string",
-			Status:      int(model.StatusNotReady),
-			Priority:    int(model.PriorityHigh),
+			Status:      handler.StatusValue(model.StatusNotReady),
+			Priority:    handler.PriorityValue(model.PriorityHigh),
 		}
 		existingTodo = createTodo(t, router, "userid:password", reqBody)
 	}
@@ -285,11 +295,11 @@ func testTodoList(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo reposi
 	// prepare todo
 	userTodos := make([]handler.TodoResponse, 0)
 	tParams := []handler.CreateTodoRequest{
-		{Title: "t11", Description: "d11", Status: int(model.StatusNotReady), Priority: int(model.PriorityHigh)},
-		{Title: "t12", Description: "d12", Status: int(model.StatusReady), Priority: int(model.PriorityMiddle)},
-		{Title: "t13", Description: "d13", Status: int(model.StatusDoing), Priority: int(model.PriorityLow)},
-		{Title: "t14", Description: "d14", Status: int(model.StatusDone), Priority: int(model.PriorityHigh)},
-		{Title: "t15", Description: "d15", Status: int(model.StatusNotReady), Priority: int(model.PriorityMiddle)},
+		{Title: "t11", Description: "d11", Status: handler.StatusValue(model.StatusNotReady), Priority: handler.PriorityValue(model.PriorityHigh)},
+		{Title: "t12", Description: "d12", Status: handler.StatusValue(model.StatusReady), Priority: handler.PriorityValue(model.PriorityMiddle)},
+		{Title: "t13", Description: "d13", Status: handler.StatusValue(model.StatusDoing), Priority: handler.PriorityValue(model.PriorityLow)},
+		{Title: "t14", Description: "d14", Status: handler.StatusValue(model.StatusDone), Priority: handler.PriorityValue(model.PriorityHigh)},
+		{Title: "t15", Description: "d15", Status: handler.StatusValue(model.StatusNotReady), Priority: handler.PriorityValue(model.PriorityMiddle)},
 	}
 	for _, tp := range tParams {
 		td := createTodo(t, router, "userid:password", tp)
@@ -297,7 +307,7 @@ func testTodoList(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo reposi
 	}
 
 	tParams2 := []handler.CreateTodoRequest{
-		{Title: "t21", Description: "d21", Status: int(model.StatusNotReady), Priority: int(model.PriorityHigh)},
+		{Title: "t21", Description: "d21", Status: handler.StatusValue(model.StatusNotReady), Priority: handler.PriorityValue(model.PriorityHigh)},
 	}
 	for _, tp := range tParams2 {
 		_ = createTodo(t, router, "userid2:password2", tp)
@@ -438,8 +448,8 @@ func testTodoUpdate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 		reqBody := handler.CreateTodoRequest{
 			Title:       "title string",
 			Description: "description string",
-			Status:      int(model.StatusNotReady),
-			Priority:    int(model.PriorityHigh),
+			Status:      handler.StatusValue(model.StatusNotReady),
+			Priority:    handler.PriorityValue(model.PriorityHigh),
 		}
 		existingTodo = createTodo(t, router, "userid:password", reqBody)
 	}
@@ -500,7 +510,7 @@ func testTodoUpdate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 			id:   id,
 			auth: "userid:password",
 			body: handler.UpdateTodoRequest{
-				Status: ptr(int(model.StatusDone + 1)),
+				Status: ptr(handler.StatusValue(model.StatusDone + 1)),
 			},
 			expectStatus: http.StatusBadRequest,
 		},
@@ -509,7 +519,7 @@ func testTodoUpdate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 			id:   id,
 			auth: "userid:password",
 			body: handler.UpdateTodoRequest{
-				Priority: ptr(int(model.PriorityLow + 1)),
+				Priority: ptr(handler.PriorityValue(model.PriorityLow + 1)),
 			},
 			expectStatus: http.StatusBadRequest,
 		},
@@ -536,8 +546,8 @@ func testTodoUpdate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 			body: handler.UpdateTodoRequest{
 				Title:       ptr("updated title2"),
 				Description: ptr("updated description"),
-				Status:      ptr(int(model.StatusDone)),
-				Priority:    ptr(int(model.PriorityMiddle)),
+				Status:      ptr(handler.StatusValue(model.StatusDone)),
+				Priority:    ptr(handler.PriorityValue(model.PriorityMiddle)),
 			},
 			expectStatus: http.StatusOK,
 			expect: handler.TodoResponse{
@@ -608,8 +618,8 @@ func testTodoDelete(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 		reqBody := handler.CreateTodoRequest{
 			Title:       "title string",
 			Description: "description string",
-			Status:      int(model.StatusNotReady),
-			Priority:    int(model.PriorityHigh),
+			Status:      handler.StatusValue(model.StatusNotReady),
+			Priority:    handler.PriorityValue(model.PriorityHigh),
 		}
 		existingTodo = createTodo(t, router, "userid:password", reqBody)
 	}
@@ -671,57 +681,2357 @@ func testTodoDelete(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repo
 	}
 }
 
-func createRouterWithDatabaseRepository(t *testing.T) (*gin.Engine, *gorm.DB, repository.UserRepository) {
-	db := db.GetTestDBConn(t)
-	todoRepo := onmemory.NewOnmemoryTodoRepository()
-	userRepo := onmemory.NewOnmemoryUserRepository()
-	usecase := usecase.NewTodoUsecase(todoRepo)
-	handler := handler.NewTodoHandler(usecase)
-	authMiddleware := middleware.NewAuthMiddleware(userRepo)
-	dbMiddleware := middleware.NewDBMiddleware(db)
-	return api.Route(authMiddleware, dbMiddleware, handler), db, userRepo
+func TestTodoMoveWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoMove(t, router, db, userRepo)
 }
 
-func createRouterWithOnmemoryRepository(t *testing.T) (*gin.Engine, *gorm.DB, repository.UserRepository) {
-	todoRepo := onmemory.NewOnmemoryTodoRepository()
-	userRepo := onmemory.NewOnmemoryUserRepository()
-	usecase := usecase.NewTodoUsecase(todoRepo)
-	handler := handler.NewTodoHandler(usecase)
-	authMiddleware := middleware.NewAuthMiddleware(userRepo)
-	return api.Route(authMiddleware, nil, handler), nil, userRepo
+func TestTodoMoveWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoMove(t, router, db, userRepo)
 }
 
-func getContext(t *testing.T, db *gorm.DB) context.Context {
+func testTodoMove(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
 	t.Helper()
 
-	return context.WithValue(context.TODO(), config.DBKey, db) //nolint:staticcheck
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	// prepare todo
+	ids := make([]string, 0)
+	for _, title := range []string{"t1", "t2", "t3", "t4"} {
+		td := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: title})
+		ids = append(ids, td.ID)
+	}
+	others := createTodo(t, router, "userid2:password2", handler.CreateTodoRequest{Title: "t21"})
+
+	cases := []struct {
+		name         string
+		id           string
+		auth         string
+		body         handler.MoveTodoRequest
+		expectStatus int
+		expectOrder  []string
+	}{
+		{
+			name:         "success, move to the top",
+			id:           ids[3],
+			auth:         "userid:password",
+			body:         handler.MoveTodoRequest{Before: ids[0]},
+			expectStatus: http.StatusOK,
+			expectOrder:  []string{ids[3], ids[0], ids[1], ids[2]},
+		},
+		{
+			name:         "success, move to the bottom",
+			id:           ids[0],
+			auth:         "userid:password",
+			body:         handler.MoveTodoRequest{After: ids[2]},
+			expectStatus: http.StatusOK,
+			expectOrder:  []string{ids[3], ids[1], ids[2], ids[0]},
+		},
+		{
+			name:         "success, move between todos",
+			id:           ids[3],
+			auth:         "userid:password",
+			body:         handler.MoveTodoRequest{After: ids[2]},
+			expectStatus: http.StatusOK,
+			expectOrder:  []string{ids[1], ids[2], ids[3], ids[0]},
+		},
+		{
+			name:         "fail, both before and after",
+			id:           ids[0],
+			auth:         "userid:password",
+			body:         handler.MoveTodoRequest{Before: ids[1], After: ids[2]},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "fail, neither before nor after",
+			id:           ids[0],
+			auth:         "userid:password",
+			body:         handler.MoveTodoRequest{},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "fail, relative to itself",
+			id:           ids[0],
+			auth:         "userid:password",
+			body:         handler.MoveTodoRequest{Before: ids[0]},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "not found(others todo as anchor)",
+			id:           ids[0],
+			auth:         "userid:password",
+			body:         handler.MoveTodoRequest{Before: others.ID},
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			name:         "not found(others todo)",
+			id:           ids[0],
+			auth:         "userid2:password2",
+			body:         handler.MoveTodoRequest{Before: others.ID},
+			expectStatus: http.StatusNotFound,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			b, _ := json.Marshal(c.body)
+			body := ioutil.NopCloser(bytes.NewBuffer(b))
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/todos/%s/move", c.id), body)
+			req.Header.Set("Authorization", c.auth)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, c.expectStatus, w.Code, w.Body.String())
+			if c.expectStatus != http.StatusOK {
+				return
+			}
+
+			actuals := listTodos(t, router, c.auth, "sortby=manual")
+			actualOrder := make([]string, 0, len(actuals.Entries))
+			for _, td := range actuals.Entries {
+				actualOrder = append(actualOrder, td.ID)
+			}
+			assert.Equal(t, c.expectOrder, actualOrder)
+		})
+	}
 }
 
-func createTodo(
-	t *testing.T, router *gin.Engine, auth string, reqBody handler.CreateTodoRequest,
-) handler.TodoResponse {
+func TestTodoMoveConcurrentlyWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	ids := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		td := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: fmt.Sprintf("t%d", i)})
+		ids = append(ids, td.ID)
+	}
+
+	// every todo is moved right after the first one at the same time
+	var wg sync.WaitGroup
+	for i := 1; i < len(ids); i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			b, _ := json.Marshal(handler.MoveTodoRequest{After: ids[0]})
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/todos/%s/move", id), bytes.NewBuffer(b))
+			req.Header.Set("Authorization", "userid:password")
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		}(ids[i])
+	}
+	wg.Wait()
+
+	actuals := listTodos(t, router, "userid:password", "sortby=manual")
+	assert.Equal(t, len(ids), len(actuals.Entries))
+	assert.Equal(t, ids[0], actuals.Entries[0].ID)
+	positions := map[string]bool{}
+	for _, td := range actuals.Entries {
+		assert.False(t, positions[td.Position], td.Position)
+		positions[td.Position] = true
+	}
+}
+
+func TestTodoEstimateWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoEstimate(t, router, db, userRepo)
+}
+
+func TestTodoEstimateWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoEstimate(t, router, db, userRepo)
+}
+
+func testTodoEstimate(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
 	t.Helper()
 
-	w := httptest.NewRecorder()
-	b, _ := json.Marshal(reqBody)
-	body := ioutil.NopCloser(bytes.NewBuffer(b))
-	req, _ := http.NewRequest("POST", "/todos", body)
-	req.Header.Set("Authorization", auth)
-	router.ServeHTTP(w, req)
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
 
-	var td handler.TodoResponse
-	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
-	if err := json.Unmarshal(w.Body.Bytes(), &td); err != nil {
-		t.Fatal(err)
+	// prepare todo
+	userTodos := make([]handler.TodoResponse, 0)
+	tParams := []handler.CreateTodoRequest{
+		{Title: "t1", Status: handler.StatusValue(model.StatusReady), Priority: handler.PriorityValue(model.PriorityHigh), EstimatePoints: ptr(3), EstimateMinutes: ptr(60)},
+		{Title: "t2", Status: handler.StatusValue(model.StatusDoing), Priority: handler.PriorityValue(model.PriorityLow), EstimatePoints: ptr(5)},
+		{Title: "t3", Status: handler.StatusValue(model.StatusDone), Priority: handler.PriorityValue(model.PriorityHigh), EstimatePoints: ptr(8), EstimateMinutes: ptr(30)},
+		{Title: "t4", Status: handler.StatusValue(model.StatusReady), Priority: handler.PriorityValue(model.PriorityMiddle)},
 	}
-	return td
+	for _, tp := range tParams {
+		td := createTodo(t, router, "userid:password", tp)
+		userTodos = append(userTodos, td)
+	}
+	assert.Equal(t, ptr(3), userTodos[0].EstimatePoints)
+	assert.Nil(t, userTodos[1].EstimateMinutes)
+
+	t.Run("fail, negative estimate", func(t *testing.T) {
+		body := handler.CreateTodoRequest{Title: "t", EstimatePoints: ptr(-1)}
+		w := sendRequest(t, router, "POST", "/todos", "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("update estimate", func(t *testing.T) {
+		body := handler.UpdateTodoRequest{EstimateMinutes: ptr(90)}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", userTodos[1].ID), "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		decodeResponse(t, w, &userTodos[1])
+		assert.Equal(t, ptr(90), userTodos[1].EstimateMinutes)
+	})
+
+	cases := []struct {
+		name         string
+		query        string
+		expectStatus int
+		expects      []handler.TodoResponse
+	}{
+		{
+			name:         "sort by estimate points, todos without estimate come last",
+			query:        "sortby=estimatePoints&orderby=desc&includeDone=true",
+			expectStatus: http.StatusOK,
+			expects:      []handler.TodoResponse{userTodos[2], userTodos[1], userTodos[0], userTodos[3]},
+		},
+		{
+			name:         "sort by estimate minutes",
+			query:        "sortby=estimateMinutes&orderby=asc",
+			expectStatus: http.StatusOK,
+			expects:      []handler.TodoResponse{userTodos[0], userTodos[1], userTodos[3]},
+		},
+		{
+			name:         "filter by estimate points",
+			query:        "minEstimatePoints=4&maxEstimatePoints=8&includeDone=true",
+			expectStatus: http.StatusOK,
+			expects:      []handler.TodoResponse{userTodos[1], userTodos[2]},
+		},
+		{
+			name:         "fail, invalid range",
+			query:        "minEstimateMinutes=10&maxEstimateMinutes=5",
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := sendRequest(t, router, "GET", "/todos?"+c.query, "userid:password", nil)
+			assert.Equal(t, c.expectStatus, w.Code, w.Body.String())
+			if c.expectStatus != http.StatusOK {
+				return
+			}
+
+			var actuals handler.ListTodoResponse
+			decodeResponse(t, w, &actuals)
+			assert.Equal(t, c.expects, actuals.Entries)
+		})
+	}
+
+	t.Run("summary", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/todos/summary", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var actual handler.SummaryResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, handler.EffortResponse{Count: 3, EstimatePoints: 8, EstimateMinutes: 150}, actual.Remaining)
+		assert.Equal(t, handler.EffortResponse{Count: 1, EstimatePoints: 8, EstimateMinutes: 30}, actual.Completed)
+		assert.Equal(t, 4, len(actual.ByStatus))
+		assert.Equal(t, int(model.StatusReady), actual.ByStatus[1].Status)
+		assert.Equal(t, handler.EffortResponse{Count: 2, EstimatePoints: 3, EstimateMinutes: 60}, actual.ByStatus[1].EffortResponse)
+		assert.Equal(t, 3, len(actual.ByPriority))
+		assert.Equal(t, int(model.PriorityHigh), actual.ByPriority[0].Priority)
+		assert.Equal(t, handler.EffortResponse{Count: 2, EstimatePoints: 11, EstimateMinutes: 90}, actual.ByPriority[0].EffortResponse)
+	})
+}
+
+func TestTodoAssignWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoAssign(t, router, db, userRepo)
+}
+
+func TestTodoAssignWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoAssign(t, router, db, userRepo)
+}
+
+func testTodoAssign(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	assigned := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "t1", AssigneeID: ptr("userid2")})
+	unassigned := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "t2"})
+	assert.Equal(t, "userid", assigned.OwnerID)
+	assert.Equal(t, ptr("userid2"), assigned.AssigneeID)
+
+	cases := []struct {
+		name         string
+		method       string
+		url          string
+		auth         string
+		body         interface{}
+		expectStatus int
+	}{
+		{
+			name:         "fail, assignee not found",
+			method:       "PATCH",
+			url:          fmt.Sprintf("/todos/%s", unassigned.ID),
+			auth:         "userid:password",
+			body:         handler.UpdateTodoRequest{AssigneeID: ptr("unknown")},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "assignee can get todo",
+			method:       "GET",
+			url:          fmt.Sprintf("/todos/%s", assigned.ID),
+			auth:         "userid2:password2",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "assignee can update status",
+			method:       "PATCH",
+			url:          fmt.Sprintf("/todos/%s", assigned.ID),
+			auth:         "userid2:password2",
+			body:         handler.UpdateTodoRequest{Status: ptr(handler.StatusValue(model.StatusDoing))},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "assignee can't update title",
+			method:       "PATCH",
+			url:          fmt.Sprintf("/todos/%s", assigned.ID),
+			auth:         "userid2:password2",
+			body:         handler.UpdateTodoRequest{Title: ptr("updated"), Status: ptr(handler.StatusValue(model.StatusDone))},
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "assignee can't delete todo",
+			method:       "DELETE",
+			url:          fmt.Sprintf("/todos/%s", assigned.ID),
+			auth:         "userid2:password2",
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "not found(others todo not assigned)",
+			method:       "GET",
+			url:          fmt.Sprintf("/todos/%s", unassigned.ID),
+			auth:         "userid2:password2",
+			expectStatus: http.StatusNotFound,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := sendRequest(t, router, c.method, c.url, c.auth, c.body)
+			assert.Equal(t, c.expectStatus, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("list assigned todos", func(t *testing.T) {
+		actuals := listTodos(t, router, "userid2:password2", "assignee=me")
+		assert.Equal(t, 1, len(actuals.Entries))
+		assert.Equal(t, assigned.ID, actuals.Entries[0].ID)
+		assert.Equal(t, int(model.StatusDoing), actuals.Entries[0].Status)
+
+		actuals = listTodos(t, router, "userid:password", "assignee=none")
+		assert.Equal(t, 1, len(actuals.Entries))
+		assert.Equal(t, unassigned.ID, actuals.Entries[0].ID)
+
+		actuals = listTodos(t, router, "userid:password", "assignee=userid2")
+		assert.Equal(t, 1, len(actuals.Entries))
+		assert.Equal(t, assigned.ID, actuals.Entries[0].ID)
+	})
+
+	t.Run("unassign", func(t *testing.T) {
+		body := handler.UpdateTodoRequest{AssigneeID: ptr("")}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", assigned.ID), "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TodoResponse
+		decodeResponse(t, w, &actual)
+		assert.Nil(t, actual.AssigneeID)
+
+		w = sendRequest(t, router, "GET", fmt.Sprintf("/todos/%s", assigned.ID), "userid2:password2", nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+}
+
+func TestTodoSnoozeWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoSnooze(t, router, db, userRepo)
+}
+
+func TestTodoSnoozeWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoSnooze(t, router, db, userRepo)
+}
+
+func testTodoSnooze(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	future := time.Now().AddDate(0, 0, 3).Format(time.RFC3339)
+	past := time.Now().AddDate(0, 0, -1).Format(time.RFC3339)
+	active := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "active"})
+	started := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "started", StartAt: past})
+	later := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "later", StartAt: future})
+	assert.Nil(t, active.StartAt)
+	assert.False(t, started.Snoozed)
+	assert.True(t, later.Snoozed)
+
+	t.Run("snoozed todos are excluded by default", func(t *testing.T) {
+		actuals := listTodos(t, router, "userid:password", "")
+		assert.Equal(t, 2, len(actuals.Entries))
+		assert.Equal(t, active.ID, actuals.Entries[0].ID)
+		assert.Equal(t, started.ID, actuals.Entries[1].ID)
+
+		actuals = listTodos(t, router, "userid:password", "includeSnoozed=true")
+		assert.Equal(t, 3, len(actuals.Entries))
+
+		w := sendRequest(t, router, "GET", fmt.Sprintf("/todos/%s", later.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+
+	t.Run("snooze todo", func(t *testing.T) {
+		now := time.Now()
+		tests := []struct {
+			until    string
+			expected func(time.Time) bool
+		}{
+			{"2h", func(s time.Time) bool { return s.Sub(now) >= 2*time.Hour && s.Sub(now) < 2*time.Hour+time.Minute }},
+			{"1d", func(s time.Time) bool { return s.Sub(now) >= 23*time.Hour && s.Sub(now) < 25*time.Hour }},
+			{"tomorrow", func(s time.Time) bool { return s.Day() == now.AddDate(0, 0, 1).Day() && s.Hour() == 0 }},
+			{"next-monday", func(s time.Time) bool {
+				return s.Weekday() == time.Monday && s.After(now) && s.Sub(now) <= 7*24*time.Hour && s.Hour() == 0
+			}},
+			{"next-week", func(s time.Time) bool { return s.Weekday() == time.Monday && s.After(now) }},
+		}
+		for _, tt := range tests {
+			body := handler.SnoozeTodoRequest{Until: tt.until}
+			w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/snooze", active.ID), "userid:password", body)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var actual handler.TodoResponse
+			decodeResponse(t, w, &actual)
+			assert.True(t, actual.Snoozed, tt.until)
+			startAt, err := time.Parse(time.RFC3339Nano, *actual.StartAt)
+			assert.NoError(t, err)
+			assert.True(t, tt.expected(startAt.Local()), fmt.Sprintf("%s: %s", tt.until, *actual.StartAt))
+		}
+
+		actuals := listTodos(t, router, "userid:password", "")
+		assert.Equal(t, 1, len(actuals.Entries))
+		assert.Equal(t, started.ID, actuals.Entries[0].ID)
+	})
+
+	t.Run("snooze todo with invalid time", func(t *testing.T) {
+		for _, until := range []string{"", "1y", "-1d", "next-someday", past, "9999d"} {
+			body := handler.SnoozeTodoRequest{Until: until}
+			w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/snooze", started.ID), "userid:password", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, fmt.Sprintf("%s: %s", until, w.Body.String()))
+		}
+
+		w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/snooze", started.ID), "userid2:password2",
+			handler.SnoozeTodoRequest{Until: "1d"})
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	})
+
+	t.Run("wake todo up", func(t *testing.T) {
+		body := handler.UpdateTodoRequest{StartAt: ptr("")}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", later.ID), "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TodoResponse
+		decodeResponse(t, w, &actual)
+		assert.Nil(t, actual.StartAt)
+		assert.False(t, actual.Snoozed)
+
+		actuals := listTodos(t, router, "userid:password", "")
+		assert.Equal(t, 2, len(actuals.Entries))
+	})
+}
+
+func TestTodoMarkdownWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoMarkdown(t, router, db, userRepo)
+}
+
+func TestTodoMarkdownWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoMarkdown(t, router, db, userRepo)
+}
+
+func testTodoMarkdown(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+	_ = userRepo.Create(getContext(t, db), "userid3", "password3")
+
+	description := "## steps\n\n- [ ] build\n- [x] **test**\n\n```\n- [ ] not a task\n```\n\n" +
+		"<script>alert(1)</script>\n[link](javascript:alert(1))\n"
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "markdown", Description: description, AssigneeID: ptr("userid2"),
+	})
+	assert.Nil(t, todo.DescriptionHTML)
+
+	t.Run("render description as html", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", fmt.Sprintf("/todos/%s?render=html", todo.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TodoResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, description, actual.Description)
+		html := *actual.DescriptionHTML
+		assert.Contains(t, html, "<h2>steps</h2>")
+		assert.Contains(t, html, `<li><input disabled="" type="checkbox"> build</li>`)
+		assert.Contains(t, html, `<li><input checked="" disabled="" type="checkbox"> <strong>test</strong></li>`)
+		assert.Contains(t, html, "<code>- [ ] not a task")
+		assert.NotContains(t, html, "<script")
+		assert.NotContains(t, html, "javascript:")
+
+		actuals := listTodos(t, router, "userid:password", "render=html")
+		assert.Equal(t, html, *actuals.Entries[0].DescriptionHTML)
+		actuals = listTodos(t, router, "userid:password", "")
+		assert.Nil(t, actuals.Entries[0].DescriptionHTML)
+
+		w = sendRequest(t, router, "GET", fmt.Sprintf("/todos/%s?render=pdf", todo.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("toggle task", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/tasks/0/toggle", todo.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.TodoResponse
+		decodeResponse(t, w, &actual)
+		assert.Contains(t, actual.Description, "- [x] build\n- [x] **test**")
+
+		// assignee can toggle as well
+		w = sendRequest(t, router, "POST", fmt.Sprintf("/todos/%s/tasks/1/toggle", todo.ID), "userid2:password2", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		decodeResponse(t, w, &actual)
+		assert.Contains(t, actual.Description, "- [x] build\n- [ ] **test**")
+		assert.Contains(t, actual.Description, "- [ ] not a task")
+	})
+
+	t.Run("toggle invalid task", func(t *testing.T) {
+		tests := []struct {
+			url  string
+			auth string
+			code int
+		}{
+			{fmt.Sprintf("/todos/%s/tasks/2/toggle", todo.ID), "userid:password", http.StatusBadRequest},
+			{fmt.Sprintf("/todos/%s/tasks/-1/toggle", todo.ID), "userid:password", http.StatusBadRequest},
+			{fmt.Sprintf("/todos/%s/tasks/a/toggle", todo.ID), "userid:password", http.StatusBadRequest},
+			{fmt.Sprintf("/todos/%s/tasks/0/toggle", todo.ID), "userid3:password3", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			w := sendRequest(t, router, "POST", tt.url, tt.auth, nil)
+			assert.Equal(t, tt.code, w.Code, fmt.Sprintf("%s: %s", tt.url, w.Body.String()))
+		}
+	})
+}
+
+func TestTodoPaginationWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoPagination(t, router, db, userRepo)
+}
+
+func TestTodoPaginationWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoPagination(t, router, db, userRepo)
+}
+
+func TestTodoPaginationWithPostgresRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithPostgresRepository(t)
+	testTodoPagination(t, router, db, userRepo)
+}
+
+func testTodoPagination(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	priorities := []int{2, 1, 3, 2, 1, 2, 3}
+	points := []*int{ptr(3), nil, ptr(1), ptr(3), nil, ptr(2), ptr(5)}
+	for i := range priorities {
+		createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+			Title: fmt.Sprintf("todo%d", i+1), Priority: handler.PriorityValue(priorities[i]), EstimatePoints: points[i],
+		})
+	}
+
+	// listAll follows cursors until the last page, and returns titles of todos in the order.
+	listAll := func(t *testing.T, query string, limit int) []string {
+		titles := []string{}
+		cursor := ""
+		for i := 0; i < 10; i++ {
+			page := listTodos(t, router, "userid:password", fmt.Sprintf("%s&limit=%d&cursor=%s", query, limit, cursor))
+			assert.LessOrEqual(t, len(page.Entries), limit)
+			for _, e := range page.Entries {
+				titles = append(titles, e.Title)
+			}
+			if page.NextCursor == "" {
+				return titles
+			}
+			cursor = page.NextCursor
+		}
+		t.Fatal("too many pages")
+		return nil
+	}
+
+	t.Run("pages are in the same order as the whole list", func(t *testing.T) {
+		queries := []string{
+			"sortby=id&orderby=asc",
+			"sortby=id&orderby=desc",
+			"sortby=priority&orderby=asc",
+			"sortby=priority&orderby=desc",
+			"sortby=manual&orderby=desc",
+			"sortby=estimatePoints&orderby=asc",
+			"sortby=estimatePoints&orderby=desc",
+		}
+		for _, q := range queries {
+			expected := []string{}
+			for _, e := range listTodos(t, router, "userid:password", q).Entries {
+				expected = append(expected, e.Title)
+			}
+			for _, limit := range []int{1, 2, 3, 7} {
+				assert.Equal(t, expected, listAll(t, q, limit), "%s limit=%d", q, limit)
+			}
+		}
+	})
+
+	t.Run("last page has no cursor", func(t *testing.T) {
+		actual := listTodos(t, router, "userid:password", "limit=7")
+		assert.Equal(t, 7, len(actual.Entries))
+		assert.Empty(t, actual.NextCursor)
+
+		actual = listTodos(t, router, "userid:password", "")
+		assert.Equal(t, 7, len(actual.Entries))
+		assert.Empty(t, actual.NextCursor)
+	})
+
+	t.Run("todos created while paging don't shift pages", func(t *testing.T) {
+		first := listTodos(t, router, "userid:password", "sortby=priority&orderby=asc&limit=3")
+		createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "high", Priority: 1})
+		second := listTodos(
+			t, router, "userid:password", "sortby=priority&orderby=asc&limit=3&cursor="+first.NextCursor,
+		)
+		seen := map[string]bool{}
+		for _, e := range append(first.Entries, second.Entries...) {
+			assert.False(t, seen[e.ID], "todo %s is listed twice", e.ID)
+			seen[e.ID] = true
+		}
+		assert.Equal(t, "todo1", first.Entries[2].Title)
+		// the new todo comes before the cursor, so it doesn't push todos of the first page into the second
+		assert.Equal(t, []string{"todo4", "todo6", "todo3"}, []string{
+			second.Entries[0].Title, second.Entries[1].Title, second.Entries[2].Title,
+		})
+	})
+
+	t.Run("fail, invalid paging", func(t *testing.T) {
+		cursor := listTodos(t, router, "userid:password", "sortby=id&limit=1").NextCursor
+		tests := []struct {
+			query string
+			code  string
+		}{
+			{"limit=0", "out_of_range"},
+			{"limit=101", "out_of_range"},
+			{"cursor=broken", "invalid_format"},
+			{"sortby=priority&cursor=" + cursor, "invalid"},
+		}
+		for _, c := range tests {
+			w := sendRequest(t, router, "GET", "/todos?"+c.query, "userid:password", nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			var actual servermodel.ErrorResponse
+			decodeResponse(t, w, &actual)
+			if assert.Equal(t, 1, len(actual.Errors), c.query) {
+				assert.Equal(t, c.code, actual.Errors[0].Code, c.query)
+			}
+		}
+	})
+}
+
+func TestTodoSortWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoSort(t, router, db, userRepo)
+}
+
+func TestTodoSortWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoSort(t, router, db, userRepo)
+}
+
+func TestTodoSortWithPostgresRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithPostgresRepository(t)
+	testTodoSort(t, router, db, userRepo)
+}
+
+func testTodoSort(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	banana := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "banana", Priority: 2, Status: 1})
+	apple := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "apple", Priority: 1, Status: 2})
+	cherry := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "cherry", Priority: 2, Status: 3})
+	appleU := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "Apple", Priority: 2, Status: 2})
+	date := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "date", Priority: 1, Status: 1})
+	for _, todo := range []handler.TodoResponse{banana, cherry} {
+		time.Sleep(10 * time.Millisecond)
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo.ID), "userid:password",
+			handler.UpdateTodoRequest{Description: ptr("updated")})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	ids := func(res handler.ListTodoResponse) []string {
+		ret := []string{}
+		for _, e := range res.Entries {
+			ret = append(ret, e.ID)
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"ties are broken by id", "sort=priority", []string{apple.ID, date.ID, banana.ID, cherry.ID, appleU.ID}},
+		{"two keys", "sort=priority,title", []string{apple.ID, date.ID, appleU.ID, banana.ID, cherry.ID}},
+		{"descending key", "sort=priority,-title", []string{date.ID, apple.ID, cherry.ID, banana.ID, appleU.ID}},
+		{"status and priority", "sort=status,-priority", []string{banana.ID, date.ID, appleU.ID, apple.ID, cherry.ID}},
+		{"created time", "sort=-createdAt", []string{date.ID, appleU.ID, cherry.ID, apple.ID, banana.ID}},
+		{"updated time", "sort=-updatedAt,id&limit=2", []string{cherry.ID, banana.ID}},
+		{"ascending key with +", "sort=" + url.QueryEscape("+title"), []string{appleU.ID, apple.ID, banana.ID, cherry.ID, date.ID}},
+		{"overrides sortby", "sort=title&sortby=priority&orderby=desc", []string{appleU.ID, apple.ID, banana.ID, cherry.ID, date.ID}},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, ids(listTodos(t, router, "userid:password", c.query)))
+		})
+	}
+
+	t.Run("pages are in the same order as the whole list", func(t *testing.T) {
+		for _, sort := range []string{"priority,-title", "status,-priority,-updatedAt", "-status,title"} {
+			expected := ids(listTodos(t, router, "userid:password", "sort="+sort))
+			actual := []string{}
+			cursor := ""
+			for i := 0; i < len(expected); i++ {
+				page := listTodos(t, router, "userid:password", fmt.Sprintf("sort=%s&limit=2&cursor=%s", sort, cursor))
+				actual = append(actual, ids(page)...)
+				if page.NextCursor == "" {
+					break
+				}
+				cursor = page.NextCursor
+			}
+			assert.Equal(t, expected, actual, sort)
+		}
+	})
+
+	t.Run("fail, invalid sort", func(t *testing.T) {
+		tests := []struct {
+			query string
+			code  string
+		}{
+			{"sort=priority,-priority", "duplicated"},
+			{"sort=priority,color", "invalid_choice"},
+			{"sort=cf.unknown", "not_found"},
+		}
+		for _, c := range tests {
+			w := sendRequest(t, router, "GET", "/todos?"+c.query, "userid:password", nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			var actual servermodel.ErrorResponse
+			decodeResponse(t, w, &actual)
+			if assert.Equal(t, 1, len(actual.Errors), c.query) {
+				assert.Equal(t, "sort", actual.Errors[0].Field, c.query)
+				assert.Equal(t, c.code, actual.Errors[0].Code, c.query)
+			}
+		}
+	})
+}
+
+func TestTodoFilterWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoFilter(t, router, db, userRepo)
+}
+
+func TestTodoFilterWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoFilter(t, router, db, userRepo)
+}
+
+func testTodoFilter(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	milk := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Buy milk", Description: "from the store", Status: 1, Priority: 1,
+	})
+	report := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Write report", Description: "50% done_now", Status: 3, Priority: 2,
+	})
+	bread := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "buy bread", Status: 4, Priority: 3,
+	})
+
+	time.Sleep(10 * time.Millisecond)
+	updatedFrom := time.Now().UTC().Format(time.RFC3339Nano)
+	w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", milk.ID), "userid:password",
+		handler.UpdateTodoRequest{Description: ptr("from the market")})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	today := time.Now().Format("2006-01-02")
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"status in set", "status=1,3", []string{milk.ID, report.ID}},
+		{"status includes done todos", "status=4", []string{bread.ID}},
+		{"priority in set", "priority=2,3", []string{report.ID}},
+		{"priority in set including done", "priority=2,3&includeDone=true", []string{report.ID, bread.ID}},
+		{"title ignoring case", "title=BUY&includeDone=true", []string{milk.ID, bread.ID}},
+		{"description", "description=MARKET", []string{milk.ID}},
+		{"description with wildcard characters", "description=" + url.QueryEscape("0%"), []string{report.ID}},
+		{"description with underscore", "description=_", []string{report.ID}},
+		{"created until today", "createdTo=" + today + "&includeDone=true", []string{milk.ID, report.ID, bread.ID}},
+		{"created from tomorrow", "createdFrom=" + tomorrow, []string{}},
+		{"updated from", "updatedFrom=" + url.QueryEscape(updatedFrom), []string{milk.ID}},
+		{"combined", "status=1,3&title=write&priority=2", []string{report.ID}},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			actuals := listTodos(t, router, "userid:password", c.query)
+			ids := []string{}
+			for _, e := range actuals.Entries {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, c.expected, ids)
+		})
+	}
+
+	t.Run("fail, invalid filters", func(t *testing.T) {
+		w := sendRequest(
+			t, router, "GET", "/todos?status=1,x&priority=9&createdFrom=2024-01-02&createdTo=2024-01-01&updatedTo=bad",
+			"userid:password", nil,
+		)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var actual servermodel.ErrorResponse
+		decodeResponse(t, w, &actual)
+		codes := map[string]string{}
+		for _, e := range actual.Errors {
+			codes[e.Field] = e.Code
+		}
+		assert.Equal(t, map[string]string{
+			"status":      "invalid_choice",
+			"priority":    "invalid_choice",
+			"createdFrom": "out_of_range",
+			"updatedTo":   "invalid_format",
+		}, codes)
+	})
+}
+
+func TestTodoQueryWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoQuery(t, router, db, userRepo)
+}
+
+func TestTodoQueryWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoQuery(t, router, db, userRepo)
+}
+
+func testTodoQuery(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	notes := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Write release notes", Status: 2, Priority: 1,
+	})
+	review := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Review", Description: "check the release notes", Status: 3, Priority: 2, AssigneeID: ptr("userid2"),
+	})
+	deploy := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Deploy", Description: "10:30 (JST)", Status: 4, Priority: 3,
+	})
+	chore := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Clean up", Status: 1, Priority: 3,
+	})
+
+	today := time.Now().Format("2006-01-02")
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{"status by names", "status:ready,doing", []string{notes.ID, review.ID}},
+		{"status by number includes done", "status:4", []string{deploy.ID}},
+		{"negated status", "-status:done", []string{notes.ID, review.ID, chore.ID}},
+		{"priority", "priority:low", []string{chore.ID}},
+		{"phrase in title or description", `"release notes"`, []string{notes.ID, review.ID}},
+		{"words are ANDed", "release check", []string{review.ID}},
+		{"field with phrase", `title:"release notes"`, []string{notes.ID}},
+		{"quoted colon and parentheses", `"10:30 (jst)" status:done`, []string{deploy.ID}},
+		{"OR", "priority:high OR status:not-ready", []string{notes.ID, chore.ID}},
+		{"explicit AND", "priority:middle AND assignee:userid2", []string{review.ID}},
+		{"parentheses", "(priority:high OR priority:middle) -assignee:none", []string{review.ID}},
+		{"negated group", "-(priority:high OR priority:middle)", []string{chore.ID}},
+		{"assignee none", "assignee:none", []string{notes.ID, chore.ID}},
+		{"updated today", "updated:" + today, []string{notes.ID, review.ID, chore.ID}},
+		{"created after today", "created:>" + today, []string{}},
+		{"created until today", "created:<=" + today + " status:1,2,3,4", []string{
+			notes.ID, review.ID, deploy.ID, chore.ID,
+		}},
+		{
+			"example of the request",
+			`status:ready,doing priority:high updated:>2026-01-01 -status:done "release notes"`,
+			[]string{notes.ID},
+		},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			actuals := listTodos(t, router, "userid:password", "q="+url.QueryEscape(c.query))
+			ids := []string{}
+			for _, e := range actuals.Entries {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, c.expected, ids)
+		})
+	}
+
+	t.Run("fail, syntax errors", func(t *testing.T) {
+		tests := []struct {
+			query    string
+			position float64
+		}{
+			{`title:"release`, 7},
+			{"status:ready OR", 14},
+			{"(status:ready", 1},
+			{"status:ready)", 13},
+			{"()", 1},
+			{"priority:urgent", 1},
+			{"10:30", 1},
+			{"a updated:>yesterday", 3},
+		}
+		for _, c := range tests {
+			w := sendRequest(t, router, "GET", "/todos?q="+url.QueryEscape(c.query), "userid:password", nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			var actual servermodel.ErrorResponse
+			decodeResponse(t, w, &actual)
+			if assert.Equal(t, 1, len(actual.Errors), c.query) {
+				assert.Equal(t, "q", actual.Errors[0].Field, c.query)
+				assert.Equal(t, "invalid_format", actual.Errors[0].Code, c.query)
+				assert.Equal(t, c.position, actual.Errors[0].Params["position"], c.query)
+			}
+		}
+	})
+}
+
+func TestTodoSearchWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoSearch(t, router, db, userRepo)
+}
+
+func TestTodoSearchWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoSearch(t, router, db, userRepo)
+}
+
+func TestTodoSearchWithPostgresRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithPostgresRepository(t)
+	testTodoSearch(t, router, db, userRepo)
+}
+
+func testTodoSearch(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	notesJa := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "リリースノートを書く", Description: "v2.0のリリースノートを作成する。変更点をまとめる。",
+	})
+	notesEn := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Review release notes", Description: "Check the release notes for typos",
+	})
+	deploy := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Deploy", Description: "Release after review <b>",
+	})
+	meeting := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "会議", Description: "リリース計画について話す",
+	})
+	long := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "Long", Description: strings.Repeat("a", 100) + "needle" + strings.Repeat("b", 100),
+	})
+	createTodo(t, router, "userid2:password2", handler.CreateTodoRequest{Title: "release of other user"})
+
+	searchTodos := func(t *testing.T, q string) handler.SearchTodoResponse {
+		w := sendRequest(t, router, "GET", "/todos/search?q="+url.QueryEscape(q), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var ret handler.SearchTodoResponse
+		decodeResponse(t, w, &ret)
+		return ret
+	}
+	ids := func(res handler.SearchTodoResponse) []string {
+		ret := []string{}
+		for _, e := range res.Entries {
+			ret = append(ret, e.ID)
+		}
+		return ret
+	}
+
+	tests := []struct {
+		name     string
+		q        string
+		expected []string
+	}{
+		{"japanese word", "リリースノート", []string{notesJa.ID}},
+		{"japanese word ranked by title", "リリース", []string{notesJa.ID, meeting.ID}},
+		{"two characters", "会議", []string{meeting.ID}},
+		{"a character", "議", []string{meeting.ID}},
+		{"phrase", `"release notes"`, []string{notesEn.ID}},
+		{"terms ranked by occurrences", "RELEASE review", []string{notesEn.ID, deploy.ID}},
+		{"no match", "release 会議", []string{}},
+	}
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, ids(searchTodos(t, c.q)))
+		})
+	}
+
+	t.Run("matches are highlighted", func(t *testing.T) {
+		actual := searchTodos(t, "release")
+		if assert.Equal(t, []string{notesEn.ID, deploy.ID}, ids(actual)) {
+			assert.Equal(t, "Review <mark>release</mark> notes", actual.Entries[0].Highlights.Title)
+			assert.Equal(t, "Deploy", actual.Entries[1].Highlights.Title)
+			assert.Equal(t, "<mark>Release</mark> after review &lt;b&gt;", actual.Entries[1].Highlights.Description)
+			assert.Greater(t, actual.Entries[0].Score, actual.Entries[1].Score)
+		}
+
+		actual = searchTodos(t, "needle")
+		if assert.Equal(t, []string{long.ID}, ids(actual)) {
+			expected := "…" + strings.Repeat("a", 40) + "<mark>needle</mark>" + strings.Repeat("b", 34) + "…"
+			assert.Equal(t, expected, actual.Entries[0].Highlights.Description)
+		}
+	})
+
+	t.Run("limit keeps the most relevant todos", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/todos/search?q=release&limit=1", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual map[string][]handler.SearchHitResponse
+		decodeResponse(t, w, &actual)
+		if assert.Equal(t, 1, len(actual["entries"])) {
+			assert.Equal(t, notesEn.ID, actual["entries"][0].ID)
+		}
+	})
+
+	t.Run("updated todo is searched by new text", func(t *testing.T) {
+		body := handler.UpdateTodoRequest{Description: ptr("予算について話す")}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", meeting.ID), "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		assert.Equal(t, []string{notesJa.ID}, ids(searchTodos(t, "リリース")))
+		assert.Equal(t, []string{meeting.ID}, ids(searchTodos(t, "予算")))
+	})
+
+	t.Run("fail, invalid search", func(t *testing.T) {
+		tests := []struct {
+			query string
+			field string
+			code  string
+		}{
+			{"q=", "q", "required"},
+			{"q=%22%20%22", "q", "required"},
+			{"q=release&limit=0", "limit", "out_of_range"},
+		}
+		for _, c := range tests {
+			w := sendRequest(t, router, "GET", "/todos/search?"+c.query, "userid:password", nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			var actual servermodel.ErrorResponse
+			decodeResponse(t, w, &actual)
+			if assert.Equal(t, 1, len(actual.Errors), c.query) {
+				assert.Equal(t, c.field, actual.Errors[0].Field, c.query)
+				assert.Equal(t, c.code, actual.Errors[0].Code, c.query)
+			}
+		}
+	})
+}
+
+func TestTodoBatchWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoBatch(t, router, db, userRepo)
+}
+
+func TestTodoBatchWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoBatch(t, router, db, userRepo)
+}
+
+func testTodoBatch(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	w := sendRequest(t, router, "POST", "/custom-fields", "userid:password",
+		handler.CreateCustomFieldRequest{Name: "tag", Type: "text"})
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	todo1 := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "todo1", Priority: 2, Status: 1})
+	todo2 := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "todo2", Priority: 2, Status: 2, CustomFields: map[string]interface{}{"tag": "release"},
+	})
+	todo3 := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+		Title: "todo3", Priority: 3, Status: 3, CustomFields: map[string]interface{}{"tag": "release"},
+	})
+	other := createTodo(t, router, "userid2:password2", handler.CreateTodoRequest{Title: "other", Priority: 2, Status: 1})
+
+	titles := func() []string {
+		ret := []string{}
+		for _, e := range listTodos(t, router, "userid:password", "includeDone=true").Entries {
+			ret = append(ret, e.Title)
+		}
+		return ret
+	}
+	results := func(res handler.BatchResponse) []string {
+		ret := []string{}
+		for _, r := range res.Results {
+			ret = append(ret, r.Result)
+		}
+		return ret
+	}
+
+	t.Run("atomic batch is rolled back by a failure", func(t *testing.T) {
+		body := handler.BatchRequest{Operations: []handler.BatchOperationRequest{
+			{Op: "create", Todo: &handler.CreateTodoRequest{Title: "new"}},
+			{Op: "update", ID: todo1.ID, Fields: &handler.UpdateTodoRequest{Title: ptr("renamed")}},
+			{Op: "delete", ID: other.ID},
+			{Op: "delete", ID: todo2.ID},
+		}}
+		w := sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		var actual handler.BatchResponse
+		decodeResponse(t, w, &actual)
+		assert.False(t, actual.Committed)
+		assert.Equal(t, []string{"rolledBack", "rolledBack", "failed", "skipped"}, results(actual))
+		assert.Equal(t, http.StatusNotFound, actual.Results[2].Error.ErrCode)
+		assert.Equal(t, []string{"todo1", "todo2", "todo3"}, titles())
+	})
+
+	t.Run("atomic batch", func(t *testing.T) {
+		body := handler.BatchRequest{Mode: "atomic", Operations: []handler.BatchOperationRequest{
+			{Op: "create", Todo: &handler.CreateTodoRequest{Title: "new", Priority: 1}},
+			{Op: "update", ID: todo1.ID, Fields: &handler.UpdateTodoRequest{Title: ptr("renamed")}},
+			{Op: "delete", ID: todo3.ID},
+		}}
+		w := sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.BatchResponse
+		decodeResponse(t, w, &actual)
+		assert.True(t, actual.Committed)
+		assert.Equal(t, []string{"succeeded", "succeeded", "succeeded"}, results(actual))
+		assert.Equal(t, []int{http.StatusCreated, http.StatusOK, http.StatusOK},
+			[]int{actual.Results[0].Status, actual.Results[1].Status, actual.Results[2].Status})
+		assert.Equal(t, 1, actual.Results[0].Entries[0].Status)
+		assert.Equal(t, []string{"renamed", "todo2", "new"}, titles())
+	})
+
+	t.Run("best-effort batch", func(t *testing.T) {
+		body := handler.BatchRequest{Mode: "bestEffort", Operations: []handler.BatchOperationRequest{
+			{Op: "create", Todo: &handler.CreateTodoRequest{Title: ""}},
+			{Op: "update", ID: todo1.ID, Fields: &handler.UpdateTodoRequest{Priority: ptr[handler.PriorityValue](1)}},
+			{Op: "archive", ID: todo2.ID},
+			{Op: "create", Todo: &handler.CreateTodoRequest{Title: "another", Priority: 3}},
+		}}
+		w := sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.BatchResponse
+		decodeResponse(t, w, &actual)
+		assert.True(t, actual.Committed)
+		assert.Equal(t, []string{"failed", "succeeded", "failed", "succeeded"}, results(actual))
+		assert.Equal(t, "title", actual.Results[0].Error.Errors[0].Field)
+		assert.Equal(t, "op", actual.Results[2].Error.Errors[0].Field)
+		assert.Equal(t, []string{"renamed", "todo2", "new", "another"}, titles())
+	})
+
+	t.Run("update todos matching filter", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", "/todos/batch", "userid:password", handler.BatchRequest{
+			Operations: []handler.BatchOperationRequest{{Op: "create", Todo: &handler.CreateTodoRequest{
+				Title: "todo4", CustomFields: map[string]interface{}{"tag": "release"},
+			}}},
+		})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		body := handler.BatchRequest{Operations: []handler.BatchOperationRequest{{
+			Op:     "updateMatching",
+			Filter: &handler.BatchFilterRequest{CustomFields: map[string]string{"tag": "release"}},
+			Fields: &handler.UpdateTodoRequest{Status: ptr[handler.StatusValue](4)},
+		}}}
+		w = sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.BatchResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 2, len(actual.Results[0].Entries))
+		for _, e := range actual.Results[0].Entries {
+			assert.Equal(t, 4, e.Status)
+		}
+		assert.Equal(t, []string{"renamed", "new", "another"}, func() []string {
+			ret := []string{}
+			for _, e := range listTodos(t, router, "userid:password", "").Entries {
+				ret = append(ret, e.Title)
+			}
+			return ret
+		}())
+
+		body.Operations[0].Filter = &handler.BatchFilterRequest{Q: "status:"}
+		w = sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, "filter.q", actual.Results[0].Error.Errors[0].Field)
+	})
+
+	t.Run("explicit zero status and priority are invalid as POST /todos", func(t *testing.T) {
+		for _, field := range []string{"status", "priority"} {
+			todo := map[string]interface{}{"title": "zero", field: 0}
+			w := sendRequest(t, router, "POST", "/todos", "userid:password", todo)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+			body := map[string]interface{}{"operations": []interface{}{map[string]interface{}{"op": "create", "todo": todo}}}
+			w = sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			var actual handler.BatchResponse
+			decodeResponse(t, w, &actual)
+			if assert.Equal(t, []string{"failed"}, results(actual)) {
+				assert.Equal(t, field, actual.Results[0].Error.Errors[0].Field)
+			}
+		}
+	})
+
+	t.Run("errors are problems since v2", func(t *testing.T) {
+		body := handler.BatchRequest{Mode: "bestEffort", Operations: []handler.BatchOperationRequest{
+			{Op: "delete", ID: other.ID},
+			{Op: "create", Todo: &handler.CreateTodoRequest{Title: ""}},
+		}}
+		w := sendRequest(t, router, "POST", "/v2/todos/batch", "userid:password", body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var actual handler.BatchResponseV2
+		decodeResponse(t, w, &actual)
+		if assert.Len(t, actual.Results, 2) && assert.NotNil(t, actual.Results[0].Error) &&
+			assert.NotNil(t, actual.Results[1].Error) {
+			assert.Equal(t, "todo_not_found", actual.Results[0].Error.Code)
+			assert.Equal(t, "/problems/todo_not_found", actual.Results[0].Error.Type)
+			assert.Equal(t, http.StatusNotFound, actual.Results[0].Error.Status)
+			assert.Equal(t, "/v2/todos/batch", actual.Results[0].Error.Instance)
+			assert.Equal(t, "validation_failed", actual.Results[1].Error.Code)
+			assert.Equal(t, "title", actual.Results[1].Error.Errors[0].Field)
+		}
+	})
+
+	t.Run("invalid batch", func(t *testing.T) {
+		for _, body := range []handler.BatchRequest{
+			{Operations: []handler.BatchOperationRequest{}},
+			{Mode: "eventually", Operations: []handler.BatchOperationRequest{{Op: "delete", ID: todo1.ID}}},
+		} {
+			w := sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	})
+}
+
+// failingTodoRepository fails after updating todos, as usecases failing after a part of their changes.
+type failingTodoRepository struct {
+	repository.TodoRepository
+}
+
+func (r failingTodoRepository) Update(ctx context.Context, todo *model.Todo) error {
+	if err := r.TodoRepository.Update(ctx, todo); err != nil {
+		return err
+	}
+	return utility.InternalServerError("", errors.New("failed after update"))
+}
+
+func TestTodoRollbackWithDatabaseRepository(t *testing.T) {
+	db := db.GetTestDBConn(t)
+	userRepo := database.NewDatabaseUserRepository()
+	todoUsecase := usecase.NewTodoUsecase(
+		failingTodoRepository{database.NewDatabaseTodoRepository()}, database.NewDatabaseTimeEntryRepository(),
+		userRepo, database.NewDatabaseCustomFieldRepository(), usecase.TodoOptions{},
+	)
+	todoHandler := handler.NewTodoHandler(todoUsecase)
+	router := gin.New()
+	// users are read from the database in the transaction
+	router.Use(middleware.NewDBMiddleware(db).NewTransaction(), middleware.NewAuthMiddleware(userRepo).NewAuthentication())
+	router.POST("/todos", todoHandler.Create)
+	router.GET("/todos/:id", todoHandler.Get)
+	router.PATCH("/todos/:id", todoHandler.Update)
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "todo"})
+
+	w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo.ID), "userid:password",
+		handler.UpdateTodoRequest{Title: ptr("renamed")})
+	assert.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+
+	// the update is rolled back with the failed request
+	w = sendRequest(t, router, "GET", fmt.Sprintf("/todos/%s", todo.ID), "userid:password", nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var actual handler.TodoResponse
+	decodeResponse(t, w, &actual)
+	assert.Equal(t, "todo", actual.Title)
+	assert.Equal(t, todo.Version, actual.Version)
+}
+
+// blockingTodoRepository blocks creating the todo titled "blocked" until release is closed.
+type blockingTodoRepository struct {
+	repository.TodoRepository
+	blocked chan struct{} // closed when creating the todo is blocked
+	release chan struct{}
+}
+
+func (r *blockingTodoRepository) Create(ctx context.Context, todo model.Todo) (int, error) {
+	if todo.Title == "blocked" {
+		close(r.blocked)
+		<-r.release
+	}
+	return r.TodoRepository.Create(ctx, todo)
+}
+
+func TestTodoBatchKeepsConcurrentChangesWithOnmemoryRepository(t *testing.T) {
+	blocking := &blockingTodoRepository{blocked: make(chan struct{}), release: make(chan struct{})}
+	router, db, userRepo := createRouterWithWrappedOnmemoryRepository(
+		t, usecase.TodoOptions{}, false, func(repo repository.TodoRepository) repository.TodoRepository {
+			blocking.TodoRepository = repo
+			return blocking
+		},
+	)
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "todo"})
+
+	body := handler.BatchRequest{Operations: []handler.BatchOperationRequest{
+		{Op: "create", Todo: &handler.CreateTodoRequest{Title: "rolled back"}},
+		{Op: "create", Todo: &handler.CreateTodoRequest{Title: "blocked"}},
+		{Op: "delete", ID: "0"},
+	}}
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- sendRequest(t, router, "POST", "/todos/batch", "userid:password", body)
+	}()
+
+	// other requests change todos while the batch is running
+	<-blocking.blocked
+	createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "concurrent"})
+	w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo.ID), "userid:password",
+		handler.UpdateTodoRequest{Title: ptr("renamed")})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	close(blocking.release)
+
+	w = <-done
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	titles := []string{}
+	for _, e := range listTodos(t, router, "userid:password", "").Entries {
+		titles = append(titles, e.Title)
+	}
+	assert.Equal(t, []string{"renamed", "concurrent"}, titles)
+}
+
+func TestTodoVersionWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoVersion(t, router, db, userRepo)
+}
+
+func TestTodoVersionWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoVersion(t, router, db, userRepo)
+}
+
+// TestTodoVersionWithPostgresRepository calls the repository directly, since requests sent concurrently by
+// testTodoVersion share the connection of the test database.
+func TestTodoVersionWithPostgresRepository(t *testing.T) {
+	db := db.GetTestDBConn(t)
+	ctx := getContext(t, db)
+	_ = database.NewDatabaseUserRepository().Create(ctx, "userid", "password")
+	repo := database.NewDatabaseTodoRepository()
+	assertCode := func(t *testing.T, expected utility.ErrorCode, err error) {
+		t.Helper()
+		var httpErr *utility.HTTPError
+		if assert.ErrorAs(t, err, &httpErr) {
+			assert.Equal(t, expected, httpErr.Code(), err.Error())
+		}
+	}
+
+	_, err := repo.Create(ctx, model.Todo{UserID: "unknown", Title: "todo", Status: model.StatusReady, Priority: model.PriorityHigh})
+	assertCode(t, utility.ErrorCodeUserNotFound, err)
+
+	id, err := repo.Create(ctx, model.Todo{UserID: "userid", Title: "todo", Status: model.StatusReady, Priority: model.PriorityHigh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(t *testing.T) *model.Todo {
+		t.Helper()
+		todo, err := repo.Get(ctx, "userid", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return todo
+	}
+
+	t.Run("todo read before an update isn't saved", func(t *testing.T) {
+		updated, stale := get(t), get(t)
+		updated.Title = "updated"
+		assert.NoError(t, repo.Update(ctx, updated))
+		assert.Equal(t, 2, updated.Version)
+
+		stale.Title = "stale"
+		assertCode(t, utility.ErrorCodeConcurrentModification, repo.Update(ctx, stale))
+		assert.Equal(t, 1, stale.Version)
+		actual := get(t)
+		assert.Equal(t, "updated", actual.Title)
+		assert.Equal(t, 2, actual.Version)
+	})
+
+	t.Run("todo in another version isn't deleted", func(t *testing.T) {
+		assertCode(t, utility.ErrorCodeConcurrentModification, repo.Delete(ctx, id, 1))
+		assert.Equal(t, 2, get(t).Version)
+
+		assert.NoError(t, repo.Delete(ctx, id, 2))
+		assertCode(t, utility.ErrorCodeTodoNotFound, repo.Delete(ctx, id, 2))
+		assertCode(t, utility.ErrorCodeTodoNotFound, repo.Update(ctx, &model.Todo{ID: id, UserID: "userid", Version: 2}))
+	})
+}
+
+func testTodoVersion(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "todo", Priority: 2, Status: 1})
+	assert.Equal(t, 1, todo.Version)
+	url := fmt.Sprintf("/todos/%s", todo.ID)
+
+	t.Run("get with etag", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", url, "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, `"1-0"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("update with if-match", func(t *testing.T) {
+		body := handler.UpdateTodoRequest{Title: ptr("renamed")}
+		w := sendRequestWithHeader(t, router, "PATCH", url, "userid:password", map[string]string{"If-Match": `"1-0"`}, body)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, `"2-0"`, w.Header().Get("ETag"))
+		var actual handler.TodoResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 2, actual.Version)
+
+		for _, ifMatch := range []string{`"1-0"`, `W/"2-0"`, `"1-0", "3-0"`, `2`} {
+			w = sendRequestWithHeader(t, router, "PATCH", url, "userid:password", map[string]string{"If-Match": ifMatch}, body)
+			assert.Equal(t, http.StatusPreconditionFailed, w.Code, ifMatch)
+		}
+		for _, ifMatch := range []string{`"1-0", "2-0"`, `*`, ``} {
+			w = sendRequestWithHeader(t, router, "PATCH", url, "userid:password", map[string]string{"If-Match": ifMatch}, body)
+			assert.Equal(t, http.StatusOK, w.Code, ifMatch)
+		}
+		w = sendRequest(t, router, "GET", url, "userid:password", nil)
+		assert.Equal(t, `"5-0"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("move changes version", func(t *testing.T) {
+		other := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "other", Priority: 2, Status: 1})
+		w := sendRequest(t, router, "POST", url+"/move", "userid:password", handler.MoveTodoRequest{After: other.ID})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = sendRequest(t, router, "GET", url, "userid:password", nil)
+		assert.Equal(t, `"6-0"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("concurrent updates with the same if-match", func(t *testing.T) {
+		codes := make(chan int, 10)
+		var wg sync.WaitGroup
+		for i := 0; i < cap(codes); i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				body := handler.UpdateTodoRequest{Description: ptr(fmt.Sprintf("update %d", i))}
+				w := sendRequestWithHeader(t, router, "PATCH", url, "userid:password", map[string]string{"If-Match": `"6-0"`}, body)
+				codes <- w.Code
+			}(i)
+		}
+		wg.Wait()
+		close(codes)
+		succeeded := 0
+		for code := range codes {
+			if code == http.StatusOK {
+				succeeded++
+			} else {
+				assert.Equal(t, http.StatusPreconditionFailed, code)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	t.Run("delete with if-match", func(t *testing.T) {
+		w := sendRequestWithHeader(t, router, "DELETE", url, "userid:password", map[string]string{"If-Match": `"6-0"`}, nil)
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, w.Body.String())
+		w = sendRequestWithHeader(t, router, "DELETE", url, "userid:password", map[string]string{"If-Match": `"7-0"`}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	})
+}
+
+func TestTodoConditionalGetWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoConditionalGet(t, router, db, userRepo)
+}
+
+func TestTodoConditionalGetWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoConditionalGet(t, router, db, userRepo)
+}
+
+func testTodoConditionalGet(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "todo", Priority: 2, Status: 1})
+	url := fmt.Sprintf("/todos/%s", todo.ID)
+
+	t.Run("get todo", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", url, "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		tag := w.Header().Get("ETag")
+		modified := w.Header().Get("Last-Modified")
+		assert.Equal(t, `"1-0"`, tag)
+		assert.NotEmpty(t, modified)
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+		for _, header := range []map[string]string{
+			{"If-None-Match": tag},
+			{"If-None-Match": `"0-0", W/` + tag},
+			{"If-None-Match": `*`},
+			{"If-Modified-Since": modified},
+		} {
+			w = sendRequestWithHeader(t, router, "GET", url, "userid:password", header, nil)
+			assert.Equal(t, http.StatusNotModified, w.Code, header)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, tag, w.Header().Get("ETag"))
+		}
+
+		for _, header := range []map[string]string{
+			{"If-None-Match": `"0-0"`},
+			{"If-None-Match": `"0-0"`, "If-Modified-Since": modified},
+			{"If-Modified-Since": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+			{"If-Modified-Since": "yesterday"},
+		} {
+			w = sendRequestWithHeader(t, router, "GET", url, "userid:password", header, nil)
+			assert.Equal(t, http.StatusOK, w.Code, header)
+		}
+
+		w = sendRequest(t, router, "PATCH", url, "userid:password", handler.UpdateTodoRequest{Title: ptr("renamed")})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = sendRequestWithHeader(t, router, "GET", url, "userid:password", map[string]string{"If-None-Match": tag}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, `"2-0"`, w.Header().Get("ETag"))
+	})
+
+	t.Run("list todos", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/todos", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		tag := w.Header().Get("ETag")
+		assert.NotEmpty(t, tag)
+		modified := w.Header().Get("Last-Modified")
+		assert.NotEmpty(t, modified)
+
+		for _, header := range []map[string]string{{"If-None-Match": tag}, {"If-Modified-Since": modified}} {
+			w = sendRequestWithHeader(t, router, "GET", "/todos", "userid:password", header, nil)
+			assert.Equal(t, http.StatusNotModified, w.Code, header)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, tag, w.Header().Get("ETag"))
+		}
+		since := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+		w = sendRequestWithHeader(t, router, "GET", "/todos", "userid:password", map[string]string{"If-Modified-Since": since}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		other := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "other", Priority: 2, Status: 1})
+		w = sendRequestWithHeader(t, router, "GET", "/todos", "userid:password", map[string]string{"If-None-Match": tag}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		created := w.Header().Get("ETag")
+		assert.NotEqual(t, tag, created)
+
+		w = sendRequest(t, router, "DELETE", fmt.Sprintf("/todos/%s", other.ID), "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		w = sendRequestWithHeader(t, router, "GET", "/todos", "userid:password", map[string]string{"If-None-Match": created}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, tag, w.Header().Get("ETag"))
+	})
+}
+
+func TestTodoIdempotencyWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoIdempotency(t, router, db, userRepo)
+}
+
+func TestTodoIdempotencyWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoIdempotency(t, router, db, userRepo)
+}
+
+func testTodoIdempotency(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	_ = userRepo.Create(getContext(t, db), "userid2", "password2")
+
+	post := func(auth, key string, body interface{}) *httptest.ResponseRecorder {
+		return sendRequestWithHeader(t, router, "POST", "/todos", auth, map[string]string{"Idempotency-Key": key}, body)
+	}
+
+	t.Run("retry is replayed", func(t *testing.T) {
+		body := handler.CreateTodoRequest{Title: "once", Priority: 1}
+		w := post("userid:password", "key1", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+		first := w.Body.String()
+		tag := w.Header().Get("ETag")
+		assert.NotEmpty(t, tag)
+
+		// the same content in another order of keys is the same request
+		w = post("userid:password", "key1", map[string]interface{}{"priority": 1, "description": "", "title": "once"})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, first, w.Body.String())
+		assert.Equal(t, tag, w.Header().Get("ETag"))
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, 1, len(listTodos(t, router, "userid:password", "").Entries))
+
+		w = post("userid:password", "key1", handler.CreateTodoRequest{Title: "another", Priority: 1})
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+
+		// keys are unique for each user
+		w = post("userid2:password2", "key1", handler.CreateTodoRequest{Title: "another", Priority: 1})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("failure isn't replayed", func(t *testing.T) {
+		w := post("userid:password", "key2", handler.CreateTodoRequest{Title: "", Priority: 1})
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		w = post("userid:password", "key2", handler.CreateTodoRequest{Title: "fixed", Priority: 1})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+
+		w = post("userid:password", strings.Repeat("k", 256), handler.CreateTodoRequest{Title: "long key", Priority: 1})
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("concurrent retries", func(t *testing.T) {
+		before := len(listTodos(t, router, "userid:password", "").Entries)
+		codes := make(chan int, 10)
+		var wg sync.WaitGroup
+		for i := 0; i < cap(codes); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes <- post("userid:password", "key3", handler.CreateTodoRequest{Title: "concurrent", Priority: 1}).Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+		for code := range codes {
+			// requests while the first one is processed are rejected unless they can wait for it
+			assert.Contains(t, []int{http.StatusCreated, http.StatusConflict}, code)
+		}
+		assert.Equal(t, before+1, len(listTodos(t, router, "userid:password", "").Entries))
+	})
+}
+
+func TestIdempotencyRespondsAfterCommitWithDatabaseRepository(t *testing.T) {
+	db := db.GetTestDBConn(t)
+	userRepo := database.NewDatabaseUserRepository()
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(database.NewDatabaseIdempotencyKeyRepository(), time.Hour)
+	router := gin.New()
+	router.Use(middleware.NewDBMiddleware(db).NewTransaction(), middleware.NewAuthMiddleware(userRepo).NewAuthentication())
+	// the handler succeeds, but the transaction is rolled back by the error recorded after it
+	router.POST("/todos", idempotencyMiddleware.NewIdempotency(), func(c *gin.Context) {
+		c.Header("ETag", `"1-0"`)
+		c.JSON(http.StatusCreated, map[string]string{"title": "rolled back"})
+		_ = c.Error(errors.New("failed after response"))
+	})
+
+	header := map[string]string{"Idempotency-Key": "key"}
+	w := sendRequestWithHeader(t, router, "POST", "/todos", "userid:password", header, handler.CreateTodoRequest{Title: "t"})
+	assert.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.NotContains(t, w.Body.String(), "rolled back")
+
+	// the key is rolled back as well, so the retry isn't replayed
+	w = sendRequestWithHeader(t, router, "POST", "/todos", "userid:password", header, handler.CreateTodoRequest{Title: "t"})
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyKeyPurgeWithDatabaseRepository(t *testing.T) {
+	db := db.GetTestDBConn(t)
+	ctx := getContext(t, db)
+	_ = database.NewDatabaseUserRepository().Create(ctx, "userid", "password")
+	_ = database.NewDatabaseUserRepository().Create(ctx, "userid2", "password2")
+	repo := database.NewDatabaseIdempotencyKeyRepository()
+
+	now := time.Now()
+	reserve := func(userID, key string, createdAt time.Time) {
+		_, reserved, err := repo.Reserve(ctx, model.IdempotencyKey{
+			UserID: userID, Key: key, RequestHash: "hash", CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour),
+		})
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+	}
+	reserve("userid", "expired", now.Add(-2*time.Hour))
+	reserve("userid", "alive", now.Add(-time.Minute))
+
+	// keys of other users which are never retried are purged as well
+	reserve("userid2", "key", now)
+	var keys []string
+	assert.NoError(t, db.Model(&model.IdempotencyKey{}).Order("key").Pluck("key", &keys).Error)
+	assert.Equal(t, []string{"alive", "key"}, keys)
+}
+
+func TestTodoPatchWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoPatch(t, router, db, userRepo)
+}
+
+func TestTodoPatchWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoPatch(t, router, db, userRepo)
+}
+
+func testTodoPatch(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	body := handler.CreateCustomFieldRequest{Name: "sprint", Type: "enum", Options: []string{"s1", "s2"}}
+	w := sendRequest(t, router, "POST", "/custom-fields", "userid:password", body)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	create := func() handler.TodoResponse {
+		return createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+			Title: "patched", Description: "desc", Priority: 2, EstimatePoints: ptr(3), EstimateMinutes: ptr(60),
+			CustomFields: map[string]interface{}{"sprint": "s1"},
+		})
+	}
+	patch := func(id, mime string, body interface{}, header map[string]string) *httptest.ResponseRecorder {
+		h := map[string]string{"Content-Type": mime}
+		for k, v := range header {
+			h[k] = v
+		}
+		return sendRequestWithHeader(t, router, "PATCH", "/todos/"+id, "userid:password", h, body)
+	}
+	get := func(id string) handler.TodoResponse {
+		w := sendRequest(t, router, "GET", "/todos/"+id, "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res handler.TodoResponse
+		decodeResponse(t, w, &res)
+		return res
+	}
+	fields := func(w *httptest.ResponseRecorder) []string {
+		var res servermodel.ErrorResponse
+		decodeResponse(t, w, &res)
+		ret := []string{}
+		for _, fe := range res.Errors {
+			ret = append(ret, fe.Field+":"+fe.Code)
+		}
+		return ret
+	}
+
+	t.Run("get advertises patch formats", func(t *testing.T) {
+		todo := create()
+		w := sendRequest(t, router, "GET", "/todos/"+todo.ID, "userid:password", nil)
+		assert.Equal(t, "application/merge-patch+json, application/json-patch+json", w.Header().Get("Accept-Patch"))
+	})
+
+	t.Run("merge patch clears fields by null", func(t *testing.T) {
+		todo := create()
+		w := patch(todo.ID, "application/merge-patch+json", map[string]interface{}{
+			"title": "merged", "description": nil, "estimatePoints": nil, "customFields": map[string]interface{}{"sprint": nil},
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res handler.TodoResponse
+		decodeResponse(t, w, &res)
+		assert.Equal(t, "merged", res.Title)
+		assert.Equal(t, "", res.Description)
+		assert.Nil(t, res.EstimatePoints)
+		assert.Equal(t, ptr(60), res.EstimateMinutes)
+		assert.Empty(t, res.CustomFields)
+		assert.Equal(t, 2, res.Priority)
+		assert.Equal(t, todo.Version+1, res.Version)
+		assert.Equal(t, fmt.Sprintf(`"%d-0"`, res.Version), w.Header().Get("ETag"))
+	})
+
+	t.Run("null of JSON body leaves fields", func(t *testing.T) {
+		todo := create()
+		w := sendRequest(t, router, "PATCH", "/todos/"+todo.ID, "userid:password",
+			map[string]interface{}{"title": "plain", "estimatePoints": nil})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, ptr(3), get(todo.ID).EstimatePoints)
+	})
+
+	t.Run("merge patch is validated", func(t *testing.T) {
+		todo := create()
+		w := patch(todo.ID, "application/merge-patch+json", map[string]interface{}{
+			"title": nil, "status": true, "priority": "urgent", "id": "100", "unknown": 1,
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.ElementsMatch(t, []string{
+			"unknown:not_allowed", "id:not_allowed", "title:required", "status:invalid_type", "priority:invalid_choice",
+		}, fields(w))
+
+		// the patched todo is validated as PATCH with JSON body
+		w = patch(todo.ID, "application/merge-patch+json", map[string]interface{}{
+			"title": "", "priority": 9, "estimatePoints": 1001, "startAt": "tomorrow",
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.ElementsMatch(t, []string{
+			"title:required", "priority:invalid_choice", "estimatePoints:out_of_range", "startAt:invalid_format",
+		}, fields(w))
+		assert.Equal(t, todo, get(todo.ID))
+
+		w = patch(todo.ID, "application/merge-patch+json", []interface{}{"title"}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	})
+
+	t.Run("JSON patch with test", func(t *testing.T) {
+		todo := create()
+		w := patch(todo.ID, "application/json-patch+json", []map[string]interface{}{
+			{"op": "test", "path": "/version", "value": todo.Version},
+			{"op": "test", "path": "/customFields/sprint", "value": "s1"},
+			{"op": "replace", "path": "/priority", "value": "high"},
+			{"op": "replace", "path": "/customFields/sprint", "value": "s2"},
+			{"op": "remove", "path": "/estimateMinutes"},
+			{"op": "copy", "from": "/title", "path": "/description"},
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res handler.TodoResponse
+		decodeResponse(t, w, &res)
+		assert.Equal(t, 1, res.Priority)
+		assert.Equal(t, map[string]interface{}{"sprint": "s2"}, res.CustomFields)
+		assert.Nil(t, res.EstimateMinutes)
+		assert.Equal(t, "patched", res.Description)
+		assert.Equal(t, todo.Version+1, res.Version)
+
+		// a patch of only tests doesn't update the todo
+		w = patch(todo.ID, "application/json-patch+json", []map[string]interface{}{
+			{"op": "test", "path": "/priority", "value": 1},
+		}, nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, res.Version, get(todo.ID).Version)
+	})
+
+	t.Run("JSON patch which doesn't fit the todo conflicts", func(t *testing.T) {
+		todo := create()
+		for _, ops := range [][]map[string]interface{}{
+			{{"op": "replace", "path": "/title", "value": "x"}, {"op": "test", "path": "/priority", "value": 1}},
+			{{"op": "remove", "path": "/customFields/unknown"}},
+			{{"op": "replace", "path": "/title/0", "value": "x"}},
+		} {
+			w := patch(todo.ID, "application/json-patch+json", ops, nil)
+			assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		}
+		assert.Equal(t, todo, get(todo.ID))
+	})
+
+	t.Run("malformed JSON patch", func(t *testing.T) {
+		todo := create()
+		for _, body := range []interface{}{
+			map[string]interface{}{"op": "replace", "path": "/title", "value": "x"},
+			[]map[string]interface{}{{"op": "rename", "path": "/title"}},
+			[]map[string]interface{}{{"op": "replace", "path": "title", "value": "x"}},
+			[]map[string]interface{}{{"op": "add", "path": "/title"}},
+			[]map[string]interface{}{{"op": "move", "path": "/customFields/a"}},
+		} {
+			w := patch(todo.ID, "application/json-patch+json", body, nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		w := patch(todo.ID, "application/json-patch+json", []map[string]interface{}{
+			{"op": "add", "path": "/foo", "value": 1},
+			{"op": "replace", "path": "/version", "value": 100},
+		}, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.ElementsMatch(t, []string{"foo:not_allowed", "version:not_allowed"}, fields(w))
+	})
+
+	t.Run("patch with If-Match", func(t *testing.T) {
+		todo := create()
+		ops := []map[string]interface{}{{"op": "replace", "path": "/title", "value": "matched"}}
+		w := patch(todo.ID, "application/json-patch+json", ops, map[string]string{"If-Match": `"100-0"`})
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, w.Body.String())
+		w = patch(todo.ID, "application/merge-patch+json", map[string]interface{}{"title": "matched"},
+			map[string]string{"If-Match": fmt.Sprintf(`"%d-0"`, todo.Version)})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "matched", get(todo.ID).Title)
+	})
+}
+
+func TestTodoEnumNamesWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoEnumNames(t, router, db, userRepo)
+}
+
+func TestTodoEnumNamesWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoEnumNames(t, router, db, userRepo)
+}
+
+func testTodoEnumNames(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	var todo handler.TodoResponse
+	t.Run("names are accepted ignoring case", func(t *testing.T) {
+		body := map[string]interface{}{"title": "named", "status": "Not Ready", "priority": "HIGH"}
+		w := sendRequest(t, router, "POST", "/todos", "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		decodeResponse(t, w, &todo)
+		assert.Equal(t, int(model.StatusNotReady), todo.Status)
+		assert.Equal(t, "not-ready", todo.StatusName)
+		assert.Equal(t, int(model.PriorityHigh), todo.Priority)
+		assert.Equal(t, "high", todo.PriorityName)
+
+		w = sendRequest(t, router, "PATCH", "/todos/"+todo.ID, "userid:password",
+			map[string]interface{}{"status": "doing", "priority": 3})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		decodeResponse(t, w, &todo)
+		assert.Equal(t, int(model.StatusDoing), todo.Status)
+		assert.Equal(t, "doing", todo.StatusName)
+		assert.Equal(t, "low", todo.PriorityName)
+
+		w = sendRequest(t, router, "PATCH", "/todos/"+todo.ID, "userid:password",
+			map[string]interface{}{"status": "not_ready"})
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		decodeResponse(t, w, &todo)
+		assert.Equal(t, "not-ready", todo.StatusName)
+	})
+
+	t.Run("unknown names are rejected", func(t *testing.T) {
+		w := sendRequest(t, router, "PATCH", "/todos/"+todo.ID, "userid:password",
+			map[string]interface{}{"status": "finished"})
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var actual servermodel.ErrorResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 1, len(actual.Errors))
+		assert.Equal(t, "status", actual.Errors[0].Field)
+		assert.Equal(t, "invalid_choice", actual.Errors[0].Code)
+	})
+
+	t.Run("names in list filters", func(t *testing.T) {
+		ready := createTodo(t, router, "userid:password", handler.CreateTodoRequest{
+			Title: "ready", Status: handler.StatusValue(model.StatusReady), Priority: handler.PriorityValue(model.PriorityMiddle),
+		})
+		res := listTodos(t, router, "userid:password", "status=ready,1")
+		ids := []string{}
+		for _, e := range res.Entries {
+			ids = append(ids, e.ID)
+		}
+		assert.Equal(t, []string{todo.ID, ready.ID}, ids)
+
+		res = listTodos(t, router, "userid:password", "priority=Middle")
+		assert.Equal(t, 1, len(res.Entries))
+		assert.Equal(t, ready.ID, res.Entries[0].ID)
+	})
+
+	t.Run("summary has names", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/todos/summary", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var res handler.SummaryResponse
+		decodeResponse(t, w, &res)
+		assert.Equal(t, "not-ready", res.ByStatus[0].StatusName)
+		assert.Equal(t, "high", res.ByPriority[0].PriorityName)
+	})
+}
+
+func TestAPIVersionWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testAPIVersion(t, router, db, userRepo)
+}
+
+func TestAPIVersionWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testAPIVersion(t, router, db, userRepo)
+}
+
+func testAPIVersion(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	keys := func(m map[string]interface{}) []string {
+		ret := []string{}
+		for k := range m {
+			ret = append(ret, k)
+		}
+		return ret
+	}
+
+	var first handler.TodoResponse
+	t.Run("unprefixed paths are deprecated aliases of v1", func(t *testing.T) {
+		body := handler.CreateTodoRequest{Title: "first"}
+		w := sendRequest(t, router, "POST", "/todos", "userid:password", body)
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		decodeResponse(t, w, &first)
+		assert.NotEmpty(t, first.CreatedAt)
+		assert.Equal(t, fmt.Sprintf("@%d", unversionedAPIDeprecatedAt.Unix()), w.Header().Get("Deprecation"))
+		assert.Equal(t, "Thu, 01 Apr 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+		assert.Equal(t, `</v1/todos>; rel="successor-version"`, w.Header().Get("Link"))
+
+		w = sendRequest(t, router, "GET", "/v1/todos/"+first.ID, "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get("Deprecation"))
+		var res map[string]interface{}
+		decodeResponse(t, w, &res)
+		assert.Contains(t, keys(res), "createAt")
+		assert.NotContains(t, keys(res), "createdAt")
+
+		w = sendRequest(t, router, "GET", "/v1/todos", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		res = map[string]interface{}{}
+		decodeResponse(t, w, &res)
+		assert.Equal(t, []string{"Entries"}, keys(res))
+	})
+
+	t.Run("v2 fixes names", func(t *testing.T) {
+		w := sendRequest(t, router, "POST", "/v2/todos", "userid:password", handler.CreateTodoRequest{Title: "second"})
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Empty(t, w.Header().Get("Deprecation"))
+		var res map[string]interface{}
+		decodeResponse(t, w, &res)
+		assert.Contains(t, keys(res), "createdAt")
+		assert.NotContains(t, keys(res), "createAt")
+
+		w = sendRequest(t, router, "GET", "/v2/todos/search?q=first", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var search handler.SearchTodoResponse
+		decodeResponse(t, w, &search)
+		assert.Equal(t, 1, len(search.Entries))
+		assert.Equal(t, first.ID, search.Entries[0].ID)
+		assert.Equal(t, first.CreatedAt, search.Entries[0].CreatedAtV2)
+	})
+
+	t.Run("v2 lists have pagination", func(t *testing.T) {
+		w := sendRequest(t, router, "GET", "/v2/todos?limit=1", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page handler.ListTodoResponseV2
+		decodeResponse(t, w, &page)
+		assert.Equal(t, 1, len(page.Entries))
+		assert.Equal(t, first.ID, page.Entries[0].ID)
+		assert.Equal(t, ptr(1), page.Pagination.Limit)
+		assert.True(t, page.Pagination.HasMore)
+		assert.NotNil(t, page.Pagination.NextCursor)
+
+		w = sendRequest(t, router, "GET", "/v2/todos?limit=1&cursor="+*page.Pagination.NextCursor, "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var raw map[string]interface{}
+		decodeResponse(t, w, &raw)
+		assert.Equal(t, map[string]interface{}{"limit": float64(1), "hasMore": false, "nextCursor": nil}, raw["pagination"])
+
+		w = sendRequest(t, router, "GET", "/v2/todos", "userid:password", nil)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		page = handler.ListTodoResponseV2{}
+		decodeResponse(t, w, &page)
+		assert.Equal(t, 2, len(page.Entries))
+		assert.Nil(t, page.Pagination.Limit)
+		assert.False(t, page.Pagination.HasMore)
+	})
+}
+
+func TestTodoValidationWithOnmemoryRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithOnmemoryRepository(t)
+	testTodoValidation(t, router, db, userRepo)
+}
+
+func TestTodoValidationWithDatabaseRepository(t *testing.T) {
+	router, db, userRepo := createRouterWithDatabaseRepository(t)
+	testTodoValidation(t, router, db, userRepo)
+}
+
+func testTodoValidation(t *testing.T, router *gin.Engine, db *gorm.DB, userRepo repository.UserRepository) {
+	t.Helper()
+
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	t.Run("all violations are reported", func(t *testing.T) {
+		body := map[string]interface{}{
+			"title":          "",
+			"description":    strings.Repeat("a", 501),
+			"status":         9,
+			"priority":       0,
+			"estimatePoints": -1,
+			"assigneeId":     "nobody",
+			"customFields":   map[string]interface{}{"unknown": 1},
+		}
+		w := sendRequest(t, router, "POST", "/todos", "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var actual servermodel.ErrorResponse
+		decodeResponse(t, w, &actual)
+		codes := map[string]string{}
+		for _, e := range actual.Errors {
+			codes[e.Field] = e.Code
+			assert.NotEmpty(t, e.Message)
+		}
+		assert.Equal(t, map[string]string{
+			"title":                "required",
+			"description":          "too_long",
+			"status":               "invalid_choice",
+			"priority":             "invalid_choice",
+			"estimatePoints":       "out_of_range",
+			"assigneeId":           "not_found",
+			"customFields.unknown": "not_found",
+		}, codes)
+		for _, e := range actual.Errors {
+			if e.Field == "description" {
+				assert.Equal(t, map[string]interface{}{"max": float64(500), "actual": float64(501)}, e.Params)
+			}
+		}
+	})
+
+	t.Run("lengths are counted in characters", func(t *testing.T) {
+		todo := createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: strings.Repeat("あ", 50)})
+		assert.Equal(t, strings.Repeat("あ", 50), todo.Title)
+
+		body := handler.UpdateTodoRequest{Title: ptr(strings.Repeat("あ", 51))}
+		w := sendRequest(t, router, "PATCH", fmt.Sprintf("/todos/%s", todo.ID), "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var actual servermodel.ErrorResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 1, len(actual.Errors))
+		assert.Equal(t, "title", actual.Errors[0].Field)
+		assert.Equal(t, "too_long", actual.Errors[0].Code)
+		assert.Equal(t, map[string]interface{}{"max": float64(50), "actual": float64(51)}, actual.Errors[0].Params)
+	})
+
+	t.Run("binding errors are reported by field", func(t *testing.T) {
+		body := map[string]interface{}{"title": "t", "status": true}
+		w := sendRequest(t, router, "POST", "/todos", "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var actual servermodel.ErrorResponse
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 1, len(actual.Errors))
+		assert.Equal(t, "status", actual.Errors[0].Field)
+		assert.Equal(t, "invalid_type", actual.Errors[0].Code)
+
+		body = map[string]interface{}{"title": "t", "priority": "urgent"}
+		w = sendRequest(t, router, "POST", "/todos", "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 1, len(actual.Errors))
+		assert.Equal(t, "priority", actual.Errors[0].Field)
+		assert.Equal(t, "invalid_choice", actual.Errors[0].Code)
+		assert.Equal(t, []interface{}{"1", "high", "2", "middle", "3", "low"}, actual.Errors[0].Params["choices"])
+
+		w = sendRequest(t, router, "GET", "/todos?render=pdf", "userid:password", nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		decodeResponse(t, w, &actual)
+		assert.Equal(t, 1, len(actual.Errors))
+		assert.Equal(t, "render", actual.Errors[0].Field)
+		assert.Equal(t, "invalid_choice", actual.Errors[0].Code)
+	})
+
+	t.Run("violations of template items are prefixed", func(t *testing.T) {
+		body := handler.CreateTemplateRequest{
+			Name:  "t",
+			Items: []handler.TemplateItemRequest{{Title: "ok"}, {Title: "", Status: ptr[handler.StatusValue](9)}},
+		}
+		w := sendRequest(t, router, "POST", "/templates", "userid:password", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var actual servermodel.ErrorResponse
+		decodeResponse(t, w, &actual)
+		fields := []string{}
+		for _, e := range actual.Errors {
+			fields = append(fields, e.Field)
+		}
+		assert.Equal(t, []string{"items[1].title", "items[1].status"}, fields)
+	})
+}
+
+func TestTodoLimitsWithOnmemoryRepository(t *testing.T) {
+	limits := usecase.Limits{TitleMaxLength: 5, DescriptionMaxLength: 10}
+	router, db, userRepo := createRouterWithOnmemoryRepositoryAndOption(t, usecase.TodoOptions{Limits: limits})
+	_ = userRepo.Create(getContext(t, db), "userid", "password")
+
+	createTodo(t, router, "userid:password", handler.CreateTodoRequest{Title: "12345", Description: "1234567890"})
+
+	body := handler.CreateTodoRequest{Title: "123456", Description: "12345678901"}
+	w := sendRequest(t, router, "POST", "/todos", "userid:password", body)
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var actual servermodel.ErrorResponse
+	decodeResponse(t, w, &actual)
+	assert.Equal(t, 2, len(actual.Errors))
+}
+
+// unversionedAPIDeprecatedAt and unversionedAPISunset are the dates in the headers of the unprefixed paths.
+var (
+	unversionedAPIDeprecatedAt = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	unversionedAPISunset       = time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func createRouterWithDatabaseRepository(t *testing.T) (*gin.Engine, *gorm.DB, repository.UserRepository) {
+	db := db.GetTestDBConn(t)
+	todoRepo := onmemory.NewOnmemoryTodoRepository()
+	userRepo := onmemory.NewOnmemoryUserRepository()
+	timeEntryRepo, err := onmemory.NewOnmemoryTimeEntryRepository(todoRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customFieldRepo := onmemory.NewOnmemoryCustomFieldRepository()
+	templateRepo := onmemory.NewOnmemoryTemplateRepository()
+	txRepo := onmemory.NewOnmemoryTransactionRepository()
+	idempotencyKeyRepo := onmemory.NewOnmemoryIdempotencyKeyRepository()
+	todoUsecase := usecase.NewTodoUsecase(todoRepo, timeEntryRepo, userRepo, customFieldRepo, usecase.TodoOptions{})
+	timeEntryUsecase := usecase.NewTimeEntryUsecase(timeEntryRepo, todoRepo)
+	customFieldUsecase := usecase.NewCustomFieldUsecase(customFieldRepo, todoRepo)
+	templateUsecase := usecase.NewTemplateUsecase(templateRepo, todoUsecase, usecase.Limits{})
+	batchUsecase := usecase.NewBatchUsecase(txRepo, todoUsecase)
+	todoHandler := handler.NewTodoHandler(todoUsecase)
+	timeEntryHandler := handler.NewTimeEntryHandler(timeEntryUsecase)
+	customFieldHandler := handler.NewCustomFieldHandler(customFieldUsecase)
+	templateHandler := handler.NewTemplateHandler(templateUsecase)
+	batchHandler := handler.NewBatchHandler(batchUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(userRepo)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyKeyRepo, time.Hour)
+	deprecationMiddleware := middleware.NewDeprecationMiddleware(unversionedAPIDeprecatedAt, unversionedAPISunset)
+	dbMiddleware := middleware.NewDBMiddleware(db)
+	doc := openapi.NewDocument()
+	validationMiddleware := middleware.NewValidationMiddleware(doc, false)
+	return api.Route(authMiddleware, dbMiddleware, idempotencyMiddleware, deprecationMiddleware, validationMiddleware, todoHandler, timeEntryHandler, customFieldHandler, templateHandler, batchHandler, openapi.NewHandler(doc), problem.NewHandler(), graphql.NewHandler(todoUsecase, txRepo)), db, userRepo
+}
+
+// createRouterWithPostgresRepository returns the router whose usecases use the database repositories, so that
+// their queries run on PostgreSQL in the transaction of the test.
+func createRouterWithPostgresRepository(t *testing.T) (*gin.Engine, *gorm.DB, repository.UserRepository) {
+	db := db.GetTestDBConn(t)
+	todoRepo := database.NewDatabaseTodoRepository()
+	userRepo := database.NewDatabaseUserRepository()
+	timeEntryRepo := database.NewDatabaseTimeEntryRepository()
+	customFieldRepo := database.NewDatabaseCustomFieldRepository()
+	templateRepo := database.NewDatabaseTemplateRepository()
+	txRepo := database.NewDatabaseTransactionRepository()
+	idempotencyKeyRepo := database.NewDatabaseIdempotencyKeyRepository()
+	todoUsecase := usecase.NewTodoUsecase(todoRepo, timeEntryRepo, userRepo, customFieldRepo, usecase.TodoOptions{})
+	timeEntryUsecase := usecase.NewTimeEntryUsecase(timeEntryRepo, todoRepo)
+	customFieldUsecase := usecase.NewCustomFieldUsecase(customFieldRepo, todoRepo)
+	templateUsecase := usecase.NewTemplateUsecase(templateRepo, todoUsecase, usecase.Limits{})
+	batchUsecase := usecase.NewBatchUsecase(txRepo, todoUsecase)
+	todoHandler := handler.NewTodoHandler(todoUsecase)
+	timeEntryHandler := handler.NewTimeEntryHandler(timeEntryUsecase)
+	customFieldHandler := handler.NewCustomFieldHandler(customFieldUsecase)
+	templateHandler := handler.NewTemplateHandler(templateUsecase)
+	batchHandler := handler.NewBatchHandler(batchUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(userRepo)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyKeyRepo, time.Hour)
+	deprecationMiddleware := middleware.NewDeprecationMiddleware(unversionedAPIDeprecatedAt, unversionedAPISunset)
+	dbMiddleware := middleware.NewDBMiddleware(db)
+	doc := openapi.NewDocument()
+	validationMiddleware := middleware.NewValidationMiddleware(doc, false)
+	return api.Route(authMiddleware, dbMiddleware, idempotencyMiddleware, deprecationMiddleware, validationMiddleware, todoHandler, timeEntryHandler, customFieldHandler, templateHandler, batchHandler, openapi.NewHandler(doc), problem.NewHandler(), graphql.NewHandler(todoUsecase, txRepo)), db, userRepo
+}
+
+func createRouterWithOnmemoryRepository(t *testing.T) (*gin.Engine, *gorm.DB, repository.UserRepository) {
+	return createRouterWithOnmemoryRepositoryAndOption(t, usecase.TodoOptions{})
+}
+
+func createRouterWithOnmemoryRepositoryAndOption(
+	t *testing.T, opts usecase.TodoOptions,
+) (*gin.Engine, *gorm.DB, repository.UserRepository) {
+	return createRouterWithOnmemoryRepositoryAndValidation(t, opts, false)
+}
+
+// createRouterWithOnmemoryRepositoryAndValidation returns the router which validates requests by
+// the OpenAPI document if validation is true.
+func createRouterWithOnmemoryRepositoryAndValidation(
+	t *testing.T, opts usecase.TodoOptions, validation bool,
+) (*gin.Engine, *gorm.DB, repository.UserRepository) {
+	return createRouterWithWrappedOnmemoryRepository(t, opts, validation, nil)
+}
+
+// createRouterWithWrappedOnmemoryRepository returns the router whose usecases use the onmemory todo repository
+// wrapped by wrap unless it's nil, so that tests can intervene in calls of the repository.
+func createRouterWithWrappedOnmemoryRepository(
+	t *testing.T, opts usecase.TodoOptions, validation bool,
+	wrap func(repository.TodoRepository) repository.TodoRepository,
+) (*gin.Engine, *gorm.DB, repository.UserRepository) {
+	todoRepo := onmemory.NewOnmemoryTodoRepository()
+	userRepo := onmemory.NewOnmemoryUserRepository()
+	timeEntryRepo, err := onmemory.NewOnmemoryTimeEntryRepository(todoRepo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	customFieldRepo := onmemory.NewOnmemoryCustomFieldRepository()
+	templateRepo := onmemory.NewOnmemoryTemplateRepository()
+	txRepo := onmemory.NewOnmemoryTransactionRepository()
+	idempotencyKeyRepo := onmemory.NewOnmemoryIdempotencyKeyRepository()
+	if wrap != nil {
+		todoRepo = wrap(todoRepo)
+	}
+	todoUsecase := usecase.NewTodoUsecase(todoRepo, timeEntryRepo, userRepo, customFieldRepo, opts)
+	timeEntryUsecase := usecase.NewTimeEntryUsecase(timeEntryRepo, todoRepo)
+	customFieldUsecase := usecase.NewCustomFieldUsecase(customFieldRepo, todoRepo)
+	templateUsecase := usecase.NewTemplateUsecase(templateRepo, todoUsecase, opts.Limits)
+	batchUsecase := usecase.NewBatchUsecase(txRepo, todoUsecase)
+	todoHandler := handler.NewTodoHandler(todoUsecase)
+	timeEntryHandler := handler.NewTimeEntryHandler(timeEntryUsecase)
+	customFieldHandler := handler.NewCustomFieldHandler(customFieldUsecase)
+	templateHandler := handler.NewTemplateHandler(templateUsecase)
+	batchHandler := handler.NewBatchHandler(batchUsecase)
+	authMiddleware := middleware.NewAuthMiddleware(userRepo)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyKeyRepo, time.Hour)
+	deprecationMiddleware := middleware.NewDeprecationMiddleware(unversionedAPIDeprecatedAt, unversionedAPISunset)
+	doc := openapi.NewDocument()
+	validationMiddleware := middleware.NewValidationMiddleware(doc, validation)
+	return api.Route(authMiddleware, nil, idempotencyMiddleware, deprecationMiddleware, validationMiddleware, todoHandler, timeEntryHandler, customFieldHandler, templateHandler, batchHandler, openapi.NewHandler(doc), problem.NewHandler(), graphql.NewHandler(todoUsecase, txRepo)), nil, userRepo
+}
+
+func getContext(t *testing.T, db *gorm.DB) context.Context {
+	t.Helper()
+
+	return context.WithValue(context.TODO(), config.DBKey, db) //nolint:staticcheck
+}
+
+func createTodo(
+	t *testing.T, router *gin.Engine, auth string, reqBody handler.CreateTodoRequest,
+) handler.TodoResponse {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	b, _ := json.Marshal(reqBody)
+	body := ioutil.NopCloser(bytes.NewBuffer(b))
+	req, _ := http.NewRequest("POST", "/todos", body)
+	req.Header.Set("Authorization", auth)
+	router.ServeHTTP(w, req)
+
+	var td handler.TodoResponse
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	if err := json.Unmarshal(w.Body.Bytes(), &td); err != nil {
+		t.Fatal(err)
+	}
+	return td
+}
+
+func listTodos(t *testing.T, router *gin.Engine, auth string, query string) handler.ListTodoResponse {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/todos?"+query, nil)
+	req.Header.Set("Authorization", auth)
+	router.ServeHTTP(w, req)
+
+	var ret handler.ListTodoResponse
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+		t.Fatal(err)
+	}
+	return ret
 }
 
 // -----
 // utilities
 
 type pointable interface {
-	int | string // NOTE: float, bool, and a few other things, but I'll ignore them for now.
+	~int | ~string // NOTE: float, bool, and a few other things, but I'll ignore them for now.
 }
 
 func ptr[T pointable](v T) *T {
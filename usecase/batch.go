@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+// BatchMode decides what happens to the other operations of a batch when one of them fails.
+type BatchMode string
+
+const (
+	// BatchModeAtomic applies all operations or none of them.
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort applies the operations which succeed, and goes on after failures.
+	BatchModeBestEffort BatchMode = "bestEffort"
+)
+
+// BatchOp is the kind of an operation of a batch.
+type BatchOp string
+
+const (
+	BatchOpCreate BatchOp = "create"
+	BatchOpUpdate BatchOp = "update"
+	BatchOpDelete BatchOp = "delete"
+	// BatchOpUpdateMatching updates all todos matching the filter with the same fields.
+	BatchOpUpdateMatching BatchOp = "updateMatching"
+)
+
+// errBatchAborted is returned to roll back a batch in atomic mode.
+var errBatchAborted = errors.New("batch is aborted")
+
+type BatchUsecase interface {
+	// Run executes the operations in order, and returns the result of each operation.
+	// An error is returned only if the batch itself is invalid, while failures of operations are in the results.
+	Run(ctx context.Context, userID, mode string, operations []BatchOperationParams) ([]*BatchResult, error)
+}
+
+// BatchOperationParams is an operation of a batch.
+type BatchOperationParams struct {
+	Op      string
+	ID      string           // the todo to be updated or deleted
+	IfMatch []int            // the versions which the todo to be updated or deleted must be in, nil for any version
+	Create  CreateTodoParams // the todo to be created
+	Update  UpdateTodoParams // the fields to be updated by update and updateMatching, whose IfMatch is ignored
+	Filter  ListTodoParams   // the todos to be updated by updateMatching, whose sort and page are ignored
+}
+
+// BatchResult is the result of an operation of a batch.
+type BatchResult struct {
+	Op         BatchOp
+	Todos      []*model.Todo // todos created or updated, which are not saved if RolledBack
+	Err        error         // nil if the operation succeeded
+	RolledBack bool          // whether the operation is undone, since another one failed in atomic mode
+	Skipped    bool          // whether the operation isn't executed, since a former one failed in atomic mode
+}
+
+type batchUsecase struct {
+	txRepo      repository.TransactionRepository
+	todoUsecase TodoUsecase
+}
+
+func NewBatchUsecase(txRepo repository.TransactionRepository, todoUsecase TodoUsecase) BatchUsecase {
+	return &batchUsecase{txRepo: txRepo, todoUsecase: todoUsecase}
+}
+
+func (u *batchUsecase) Run(
+	ctx context.Context, userID, mode string, operations []BatchOperationParams,
+) ([]*BatchResult, error) {
+	verr := &utility.ValidationError{}
+	batchMode := BatchMode(mode)
+	if batchMode == "" {
+		batchMode = BatchModeAtomic
+	}
+	if batchMode != BatchModeAtomic && batchMode != BatchModeBestEffort {
+		verr.Add(utility.NewFieldError(
+			"mode", utility.CodeInvalidChoice,
+			map[string]interface{}{"choices": []BatchMode{BatchModeAtomic, BatchModeBestEffort}},
+			"mode must be %s or %s, but %s", BatchModeAtomic, BatchModeBestEffort, mode,
+		))
+	}
+	if len(operations) < 1 || len(operations) > batchOperationsMax {
+		verr.Add(utility.NewFieldError(
+			"operations", utility.CodeOutOfRange,
+			map[string]interface{}{"min": 1, "max": batchOperationsMax, "actual": len(operations)},
+			"number of operations must be 1 to %d, but %d", batchOperationsMax, len(operations),
+		))
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]*BatchResult, 0, len(operations))
+	err := u.txRepo.Savepoint(ctx, func(ctx context.Context) error {
+		for _, op := range operations {
+			res := &BatchResult{Op: BatchOp(op.Op)}
+			// each operation has its own savepoint, so that a failed one leaves nothing behind
+			res.Err = u.txRepo.Savepoint(ctx, func(ctx context.Context) error {
+				todos, err := u.run(ctx, userID, op)
+				res.Todos = todos
+				return err
+			})
+			results = append(results, res)
+			if res.Err != nil && batchMode == BatchModeAtomic {
+				return errBatchAborted
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchAborted) {
+		return nil, err
+	}
+	if err != nil {
+		for _, res := range results {
+			if res.Err == nil {
+				res.RolledBack = true
+			}
+		}
+		for _, op := range operations[len(results):] {
+			results = append(results, &BatchResult{Op: BatchOp(op.Op), Skipped: true})
+		}
+	}
+	return results, nil
+}
+
+// run executes the operation, and returns the todos created or updated.
+func (u *batchUsecase) run(ctx context.Context, userID string, op BatchOperationParams) ([]*model.Todo, error) {
+	switch BatchOp(op.Op) {
+	case BatchOpCreate:
+		todo, err := u.todoUsecase.Create(ctx, userID, op.Create)
+		if err != nil {
+			return nil, err
+		}
+		return []*model.Todo{todo}, nil
+	case BatchOpUpdate:
+		op.Update.IfMatch = op.IfMatch
+		todo, err := u.todoUsecase.Update(ctx, userID, op.ID, op.Update)
+		if err != nil {
+			return nil, err
+		}
+		return []*model.Todo{todo}, nil
+	case BatchOpDelete:
+		return nil, u.todoUsecase.Delete(ctx, userID, op.ID, op.IfMatch)
+	case BatchOpUpdateMatching:
+		return u.updateMatching(ctx, userID, op.Filter, op.Update)
+	}
+	choices := []BatchOp{BatchOpCreate, BatchOpUpdate, BatchOpDelete, BatchOpUpdateMatching}
+	return nil, utility.BadRequest("", utility.NewFieldError(
+		"op", utility.CodeInvalidChoice, map[string]interface{}{"choices": choices},
+		"op must be one of %v, but %s", choices, op.Op,
+	))
+}
+
+// updateMatching updates all todos matching the filter, and returns them in the order of id.
+func (u *batchUsecase) updateMatching(
+	ctx context.Context, userID string, filter ListTodoParams, params UpdateTodoParams,
+) ([]*model.Todo, error) {
+	filter.Sort = string(model.SortByID)
+	filter.Limit = nil
+	filter.Cursor = ""
+	todos, _, err := u.todoUsecase.List(ctx, userID, filter)
+	if err != nil {
+		verr := &utility.ValidationError{}
+		if err := verr.Collect(utility.WithPrefix("filter.", err)); err != nil {
+			return nil, err
+		}
+		return nil, verr.Err()
+	}
+
+	ret := make([]*model.Todo, 0, len(todos))
+	for _, todo := range todos {
+		updated, err := u.todoUsecase.Update(ctx, userID, strconv.Itoa(todo.ID), params)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, updated)
+	}
+	return ret, nil
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+// todoCursor is the content of the cursor of todo list, which points the last todo of a page.
+// The cursor is opaque to clients, so that its format can be changed.
+type todoCursor struct {
+	Sort string            `json:"s"` // sort keys which the cursor is issued for
+	ID   int               `json:"i"`
+	Keys []json.RawMessage `json:"k"` // the values sorted by
+}
+
+// encodeTodoCursor returns the cursor pointing the todo in the order.
+func encodeTodoCursor(sort []model.SortKey, todo *model.Todo) (string, error) {
+	c := todoCursor{Sort: formatSort(sort), ID: todo.ID}
+	for _, key := range sort {
+		value, err := json.Marshal(key.Sorter.Key(todo))
+		if err != nil {
+			return "", utility.InternalServerError("can't encode cursor", err)
+		}
+		c.Keys = append(c.Keys, value)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", utility.InternalServerError("can't encode cursor", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeTodoCursor returns the todo pointed by the cursor, which has only its id and the values sorted by.
+func decodeTodoCursor(s string, sort []model.SortKey) (*model.Todo, error) {
+	malformed := utility.NewFieldError("cursor", utility.CodeInvalidFormat, nil, "cursor is malformed")
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, malformed
+	}
+	var c todoCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, malformed
+	}
+	if c.Sort != formatSort(sort) {
+		return nil, utility.NewFieldError(
+			"cursor", utility.CodeInvalid, nil, "cursor was issued for another order, %s", c.Sort,
+		)
+	}
+	if len(c.Keys) != len(sort) {
+		return nil, malformed
+	}
+
+	todo := &model.Todo{ID: c.ID}
+	for i, key := range sort {
+		if err := setSortKey(todo, key.Sorter, c.Keys[i]); err != nil {
+			return nil, malformed
+		}
+	}
+	return todo, nil
+}
+
+// setSortKey sets the value sorted by the sorter to the todo.
+func setSortKey(todo *model.Todo, sorter model.Sorter, value json.RawMessage) error {
+	switch sorter {
+	case model.SortByID:
+		return nil
+	case model.SortByPriority:
+		return json.Unmarshal(value, &todo.Priority)
+	case model.SortByStatus:
+		return json.Unmarshal(value, &todo.Status)
+	case model.SortByManual:
+		return json.Unmarshal(value, &todo.Position)
+	case model.SortByTitle:
+		return json.Unmarshal(value, &todo.Title)
+	case model.SortByCreatedAt:
+		return json.Unmarshal(value, &todo.CreatedAt)
+	case model.SortByUpdatedAt:
+		return json.Unmarshal(value, &todo.UpdatedAt)
+	case model.SortByEstimatePoints:
+		return json.Unmarshal(value, &todo.EstimatePoints)
+	case model.SortByEstimateMinutes:
+		return json.Unmarshal(value, &todo.EstimateMinutes)
+	}
+	name, _ := sorter.CustomField()
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return err
+	}
+	if v != nil {
+		if todo.CustomFields == nil {
+			todo.CustomFields = model.CustomFieldValues{}
+		}
+		todo.CustomFields[name] = v
+	}
+	return nil
+}
+
+// formatSort returns the sort keys formatted as the query, such as `priority,-updated_at`.
+func formatSort(sort []model.SortKey) string {
+	keys := make([]string, 0, len(sort))
+	for _, key := range sort {
+		if key.Order == model.OrderByDESC {
+			keys = append(keys, "-"+string(key.Sorter))
+		} else {
+			keys = append(keys, string(key.Sorter))
+		}
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type CustomFieldUsecase interface {
+	Create(ctx context.Context, userID, name, typeStr string, options []string) (*model.CustomField, error)
+	List(ctx context.Context, userID string) ([]*model.CustomField, error)
+	Delete(ctx context.Context, userID, idStr string) error
+}
+
+type customFieldUsecase struct {
+	repo     repository.CustomFieldRepository
+	todoRepo repository.TodoRepository
+}
+
+func NewCustomFieldUsecase(repo repository.CustomFieldRepository, todoRepo repository.TodoRepository) CustomFieldUsecase {
+	return &customFieldUsecase{repo: repo, todoRepo: todoRepo}
+}
+
+func (u *customFieldUsecase) Create(
+	ctx context.Context, userID, name, typeStr string, options []string,
+) (*model.CustomField, error) {
+	verr := &utility.ValidationError{}
+	verr.Add(validateCustomFieldName(name))
+	fieldType, err := model.ToCustomFieldType(typeStr)
+	if err != nil {
+		verr.Add(utility.NewFieldError(
+			"type", utility.CodeInvalidChoice,
+			map[string]interface{}{"choices": []string{"text", "number", "date", "enum", "bool"}}, "%s", err.Error(),
+		))
+	} else {
+		verr.Add(validateCustomFieldOptions(fieldType, options))
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	if options == nil {
+		options = []string{}
+	}
+
+	newField := model.CustomField{
+		UserID:  userID,
+		Name:    name,
+		Type:    fieldType,
+		Options: options,
+	}
+	newID, err := u.repo.Create(ctx, newField)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.repo.Get(ctx, userID, newID)
+}
+
+func (u *customFieldUsecase) List(ctx context.Context, userID string) ([]*model.CustomField, error) {
+	return u.repo.List(ctx, userID)
+}
+
+func (u *customFieldUsecase) Delete(ctx context.Context, userID, idStr string) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+
+	field, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := u.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	// values are removed as well, so that a new field with the same name doesn't take them over
+	return u.todoRepo.RemoveCustomField(ctx, userID, field.Name)
+}
+
+// findCustomField returns the definition with the name, or nil if there is none.
+func findCustomField(fields []*model.CustomField, name string) *model.CustomField {
+	for _, f := range fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
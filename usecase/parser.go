@@ -1,17 +1,25 @@
 package usecase
 
 import (
-	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
 )
 
 func parseStatus(s int) (model.Status, error) {
 	status := model.ToStatus(s)
 	if status == model.StatusUnknown {
-		err := fmt.Errorf(
-			"status must be %d to %d, but %d",
-			int(model.StatusNotReady), int(model.StatusDone), s,
+		err := utility.NewFieldError(
+			"status", utility.CodeInvalidChoice,
+			map[string]interface{}{"choices": []int{
+				int(model.StatusNotReady), int(model.StatusReady), int(model.StatusDoing), int(model.StatusDone),
+			}},
+			"status must be %d to %d, but %d", int(model.StatusNotReady), int(model.StatusDone), s,
 		)
 		return status, err
 	}
@@ -21,11 +29,334 @@ func parseStatus(s int) (model.Status, error) {
 func parsePriority(p int) (model.Priority, error) {
 	priority := model.ToPriority(p)
 	if priority == model.PriorityUnknown {
-		err := fmt.Errorf(
-			"priority must be %d to %d, but %d",
-			int(model.PriorityHigh), int(model.PriorityLow), p,
+		err := utility.NewFieldError(
+			"priority", utility.CodeInvalidChoice,
+			map[string]interface{}{"choices": []int{
+				int(model.PriorityHigh), int(model.PriorityMiddle), int(model.PriorityLow),
+			}},
+			"priority must be %d to %d, but %d", int(model.PriorityHigh), int(model.PriorityLow), p,
 		)
 		return priority, err
 	}
 	return priority, nil
 }
+
+// parseSort parses comma separated sort keys such as `priority,-updatedAt`, where `-` means descending order.
+func parseSort(s string) ([]model.SortKey, error) {
+	keys := []model.SortKey{}
+	seen := map[model.Sorter]bool{}
+	for _, v := range strings.Split(s, ",") {
+		// `+` for ascending order may be decoded into a space from the query string
+		v = strings.TrimPrefix(strings.TrimSpace(v), "+")
+		order := model.OrderByASC
+		if strings.HasPrefix(v, "-") {
+			order = model.OrderByDESC
+			v = strings.TrimPrefix(v, "-")
+		}
+		sorter, err := model.ToSorter(v)
+		if err != nil {
+			return nil, utility.NewFieldError("sort", utility.CodeInvalidChoice, nil, "%s", err.Error())
+		}
+		if seen[sorter] {
+			return nil, utility.NewFieldError("sort", utility.CodeDuplicated, nil, "sort key %s is duplicated", v)
+		}
+		seen[sorter] = true
+		keys = append(keys, model.SortKey{Sorter: sorter, Order: order})
+	}
+	return keys, nil
+}
+
+// parseStatuses parses comma separated statuses given as numbers or names, such as `ready,3`.
+// Empty string means no statuses.
+func parseStatuses(s string) ([]model.Status, error) {
+	if s == "" {
+		return nil, nil
+	}
+	statuses := []model.Status{}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if n, err := strconv.Atoi(v); err == nil {
+			status, err := parseStatus(n)
+			if err != nil {
+				return nil, err
+			}
+			statuses = append(statuses, status)
+			continue
+		}
+		status := model.ParseStatus(v)
+		if status == model.StatusUnknown {
+			return nil, utility.NewFieldError(
+				"status", utility.CodeInvalidChoice, map[string]interface{}{"choices": statusNames()},
+				"status must be %s, but %s", strings.Join(statusNames(), ", "), v,
+			)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// parsePriorities parses comma separated priorities given as numbers or names, such as `high,2`.
+// Empty string means no priorities.
+func parsePriorities(s string) ([]model.Priority, error) {
+	if s == "" {
+		return nil, nil
+	}
+	priorities := []model.Priority{}
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if n, err := strconv.Atoi(v); err == nil {
+			priority, err := parsePriority(n)
+			if err != nil {
+				return nil, err
+			}
+			priorities = append(priorities, priority)
+			continue
+		}
+		priority := model.ParsePriority(v)
+		if priority == model.PriorityUnknown {
+			return nil, utility.NewFieldError(
+				"priority", utility.CodeInvalidChoice, map[string]interface{}{"choices": priorityNames()},
+				"priority must be %s, but %s", strings.Join(priorityNames(), ", "), v,
+			)
+		}
+		priorities = append(priorities, priority)
+	}
+	return priorities, nil
+}
+
+func statusNames() []string {
+	names := []string{}
+	for status := model.StatusNotReady; status <= model.StatusDone; status++ {
+		names = append(names, status.Name())
+	}
+	return names
+}
+
+func priorityNames() []string {
+	names := []string{}
+	for priority := model.PriorityHigh; priority <= model.PriorityLow; priority++ {
+		names = append(names, priority.Name())
+	}
+	return names
+}
+
+// parseTimeRange parses the range given as `<name>From` and `<name>To`. Empty string means unbounded.
+func parseTimeRange(name, fromStr, toStr string) (model.TimeRange, error) {
+	verr := &utility.ValidationError{}
+	r := model.TimeRange{}
+	if fromStr != "" {
+		t, err := parseTimeOrDate(name+"From", fromStr, false)
+		verr.Add(err)
+		r.From = &t
+	}
+	if toStr != "" {
+		t, err := parseTimeOrDate(name+"To", toStr, true)
+		verr.Add(err)
+		r.To = &t
+	}
+	if len(verr.Errors) > 0 {
+		return r, verr
+	}
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		return r, utility.NewFieldError(
+			name+"From", utility.CodeOutOfRange, nil, "%sFrom must be before %sTo", name, name,
+		)
+	}
+	return r, nil
+}
+
+// parseSearchTerms splits the full-text search query into terms by spaces. Text enclosed in double quotes
+// is a term even if it includes spaces.
+func parseSearchTerms(s string) ([]string, error) {
+	terms := []string{}
+	seen := map[string]bool{}
+	for i, part := range strings.Split(s, `"`) {
+		words := strings.Fields(part)
+		if i%2 == 1 {
+			// inside quotes
+			words = []string{strings.TrimSpace(part)}
+		}
+		for _, w := range words {
+			key := strings.ToLower(w)
+			if w == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if err := validateLength("q", w, false, searchTermMaxLength); err != nil {
+				return nil, err
+			}
+			terms = append(terms, w)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, utility.NewFieldError("q", utility.CodeRequired, nil, "q must have a term to search")
+	}
+	if len(terms) > searchTermsMax {
+		return nil, utility.NewFieldError(
+			"q", utility.CodeOutOfRange, map[string]interface{}{"max": searchTermsMax, "actual": len(terms)},
+			"q must have at most %d terms, but %d", searchTermsMax, len(terms),
+		)
+	}
+	return terms, nil
+}
+
+// parseTimeOrDate parses RFC 3339 time or date formatted as 2006-01-02 given as the field.
+// If endOfDate is true, date is parsed as the beginning of the next day,
+// so that the date itself is included in a range ending with it.
+func parseTimeOrDate(field, s string, endOfDate bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return t, utility.NewFieldError(
+			field, utility.CodeInvalidFormat, map[string]interface{}{"format": "RFC 3339 or 2006-01-02"},
+			"%s must be RFC 3339 or 2006-01-02 format, but %s", field, s,
+		)
+	}
+	if endOfDate {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// snoozeDurationPattern matches durations such as `30m`, `2h`, `1d` or `1w`.
+var snoozeDurationPattern = regexp.MustCompile(`^(\d{1,6})([mhdw])$`)
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseSnoozeUntil parses the time when a snoozed todo shows up again. It's one of
+//   - duration from now such as `30m`, `2h`, `1d` or `1w`
+//   - `tomorrow`, `next-week` (next Monday) or `next-<weekday>` such as `next-friday`, which mean the beginning of the day
+//   - RFC 3339 time or date formatted as 2006-01-02
+func parseSnoozeUntil(s string, now time.Time) (time.Time, error) {
+	ls := strings.ToLower(s)
+	if m := snoozeDurationPattern.FindStringSubmatch(ls); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "m":
+			return now.Add(time.Duration(n) * time.Minute), nil
+		case "h":
+			return now.Add(time.Duration(n) * time.Hour), nil
+		case "d":
+			return now.AddDate(0, 0, n), nil
+		default:
+			return now.AddDate(0, 0, 7*n), nil
+		}
+	}
+
+	beginningOfDay := func(days int) time.Time {
+		y, m, d := now.Date()
+		return time.Date(y, m, d+days, 0, 0, 0, 0, now.Location())
+	}
+	if ls == "tomorrow" {
+		return beginningOfDay(1), nil
+	}
+	if ls == "next-week" {
+		ls = "next-monday"
+	}
+	if weekday, ok := weekdays[strings.TrimPrefix(ls, "next-")]; ok && strings.HasPrefix(ls, "next-") {
+		days := (int(weekday) - int(now.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return beginningOfDay(days), nil
+	}
+
+	t, err := parseTimeOrDate("until", s, false)
+	if err != nil {
+		return t, utility.NewFieldError(
+			"until", utility.CodeInvalidFormat,
+			map[string]interface{}{"format": "duration, tomorrow, next-week, next-<weekday>, RFC 3339 or 2006-01-02"},
+			"snooze must be duration such as 1d, tomorrow, next-week, next-<weekday>, RFC 3339 or 2006-01-02, but %s", s,
+		)
+	}
+	return t, nil
+}
+
+// parseCustomFieldValue converts a value given in JSON to the value of the custom field.
+func parseCustomFieldValue(field *model.CustomField, v interface{}) (interface{}, error) {
+	name := "customFields." + field.Name
+	invalidType := func(expected string) error {
+		return utility.NewFieldError(
+			name, utility.CodeInvalidType, map[string]interface{}{"expected": expected},
+			"custom field %s must be %s, but %v", field.Name, expected, v,
+		)
+	}
+	switch field.Type {
+	case model.CustomFieldNumber:
+		n, ok := v.(float64)
+		if !ok || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, invalidType("number")
+		}
+		return n, nil
+	case model.CustomFieldBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, invalidType("boolean")
+		}
+		return b, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, invalidType("string")
+	}
+	return parseCustomFieldString(name, field, s)
+}
+
+// parseCustomFieldString converts a value given as string, such as a query parameter, to the value of the custom field.
+func parseCustomFieldString(name string, field *model.CustomField, s string) (interface{}, error) {
+	switch field.Type {
+	case model.CustomFieldNumber:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, utility.NewFieldError(
+				name, utility.CodeInvalidFormat, map[string]interface{}{"format": "number"},
+				"custom field %s must be number, but %s", field.Name, s,
+			)
+		}
+		return n, nil
+	case model.CustomFieldBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, utility.NewFieldError(
+				name, utility.CodeInvalidFormat, map[string]interface{}{"format": "boolean"},
+				"custom field %s must be boolean, but %s", field.Name, s,
+			)
+		}
+		return b, nil
+	case model.CustomFieldDate:
+		t, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return nil, utility.NewFieldError(
+				name, utility.CodeInvalidFormat, map[string]interface{}{"format": "2006-01-02"},
+				"custom field %s must be date formatted as 2006-01-02, but %s", field.Name, s,
+			)
+		}
+		return t.Format("2006-01-02"), nil
+	case model.CustomFieldEnum:
+		for _, o := range field.Options {
+			if o == s {
+				return s, nil
+			}
+		}
+		return nil, utility.NewFieldError(
+			name, utility.CodeInvalidChoice, map[string]interface{}{"choices": field.Options},
+			"custom field %s must be one of %v, but %s", field.Name, field.Options, s,
+		)
+	default:
+		if err := validateLength(name, s, false, customFieldTextMaxLength); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+}
@@ -0,0 +1,285 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/jsonpatch"
+)
+
+// PatchFormat is the format of a patch document of a todo.
+type PatchFormat string
+
+const (
+	PatchFormatMerge PatchFormat = "merge" // JSON Merge Patch (RFC 7396)
+	PatchFormatJSON  PatchFormat = "json"  // JSON Patch (RFC 6902)
+)
+
+// todoDocumentMembers is the members of the JSON document of a todo which patches are applied to.
+// They are named as the response, and read-only ones can be used only by test operations.
+var (
+	todoDocumentMembers = []string{
+		"id", "version", "ownerId", "title", "description", "status", "priority",
+		"estimatePoints", "estimateMinutes", "assigneeId", "customFields", "startAt",
+	}
+	todoReadOnlyMembers = map[string]bool{"id": true, "version": true, "ownerId": true}
+)
+
+// Patch updates the todo with the patch document applied to its JSON document. The changed members are
+// validated and saved as Update, and the todo is saved only if it's still in the version the patch was applied to.
+func (u *todoUsecase) Patch(
+	ctx context.Context, userID, idStr string, format PatchFormat, patch []byte, ifMatch []int,
+) (*model.Todo, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	p, err := jsonpatch.Decode(patch)
+	if err != nil {
+		return nil, utility.BadRequest("patch is not valid JSON", err).WithCode(utility.ErrorCodeInvalidPatch)
+	}
+	todo, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(todo, ifMatch); err != nil {
+		return nil, err
+	}
+
+	doc, err := todoDocument(todo)
+	if err != nil {
+		return nil, err
+	}
+	var patched interface{}
+	switch format {
+	case PatchFormatMerge:
+		if _, ok := p.(map[string]interface{}); !ok {
+			return nil, utility.BadRequest("", errors.New("merge patch of a todo must be an object")).
+				WithCode(utility.ErrorCodeInvalidPatch)
+		}
+		patched = jsonpatch.MergePatch(doc, p)
+	case PatchFormatJSON:
+		patched, err = jsonpatch.Apply(doc, p)
+		var perr *jsonpatch.Error
+		if errors.As(err, &perr) && perr.Conflict {
+			return nil, utility.Conflict("", err).WithCode(utility.ErrorCodePatchConflict)
+		} else if err != nil {
+			return nil, utility.BadRequest("", err).WithCode(utility.ErrorCodeInvalidPatch)
+		}
+	default:
+		return nil, utility.InternalServerError("", fmt.Errorf("unknown patch format %s", format))
+	}
+
+	after, ok := patched.(map[string]interface{})
+	if !ok {
+		return nil, utility.BadRequest("", errors.New("patched todo must be an object")).
+			WithCode(utility.ErrorCodeInvalidPatch)
+	}
+	params, err := patchParams(doc, after)
+	if err != nil {
+		return nil, err
+	}
+	if params.isEmpty() {
+		// a patch which changes nothing, such as only tests, succeeds without updating the todo
+		return u.get(ctx, userID, id)
+	}
+	params.IfMatch = ifMatch
+	return u.update(ctx, userID, todo, params)
+}
+
+// todoDocument returns the JSON document of the todo which patches are applied to.
+func todoDocument(todo *model.Todo) (map[string]interface{}, error) {
+	var startAt *string
+	if todo.StartAt != nil {
+		s := todo.StartAt.Format(time.RFC3339Nano)
+		startAt = &s
+	}
+	customFields := todo.CustomFields
+	if customFields == nil {
+		customFields = model.CustomFieldValues{}
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"id":              strconv.Itoa(todo.ID),
+		"version":         todo.Version,
+		"ownerId":         todo.UserID,
+		"title":           todo.Title,
+		"description":     todo.Description,
+		"status":          int(todo.Status),
+		"priority":        int(todo.Priority),
+		"estimatePoints":  todo.EstimatePoints,
+		"estimateMinutes": todo.EstimateMinutes,
+		"assigneeId":      todo.AssigneeID,
+		"customFields":    customFields,
+		"startAt":         startAt,
+	})
+	if err != nil {
+		return nil, utility.InternalServerError("can't encode todo", err)
+	}
+	doc, err := jsonpatch.Decode(b)
+	if err != nil {
+		return nil, utility.InternalServerError("can't encode todo", err)
+	}
+	return doc.(map[string]interface{}), nil
+}
+
+// patchParams returns the params updating the members changed from before to after. Removed members are null,
+// which clears optional fields and is a violation for required ones.
+func patchParams(before, after map[string]interface{}) (UpdateTodoParams, error) {
+	verr := &utility.ValidationError{}
+	unknown := []string{}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		verr.Add(utility.NewFieldError(name, utility.CodeNotAllowed, nil, "%s is not a field of todo", name))
+	}
+
+	params := UpdateTodoParams{}
+	for _, name := range todoDocumentMembers {
+		value := after[name]
+		if jsonpatch.Equal(before[name], value) {
+			continue
+		}
+		if todoReadOnlyMembers[name] {
+			verr.Add(utility.NewFieldError(name, utility.CodeNotAllowed, nil, "%s is read-only", name))
+			continue
+		}
+		var err error
+		switch name {
+		case "title":
+			params.Title, err = patchString(name, value, true)
+		case "description":
+			params.Description, err = patchString(name, value, false)
+		case "status":
+			params.Status, err = patchEnum(name, value, statusNames(), func(s string) int {
+				return int(model.ParseStatus(s))
+			})
+		case "priority":
+			params.Priority, err = patchEnum(name, value, priorityNames(), func(s string) int {
+				return int(model.ParsePriority(s))
+			})
+		case "estimatePoints":
+			params.EstimatePoints, err = patchNullableInt(name, value)
+		case "estimateMinutes":
+			params.EstimateMinutes, err = patchNullableInt(name, value)
+		case "assigneeId":
+			params.AssigneeID, err = patchString(name, value, false)
+		case "startAt":
+			params.StartAt, err = patchString(name, value, false)
+		case "customFields":
+			params.CustomFields, err = patchCustomFields(before[name], value)
+		}
+		verr.Add(err)
+	}
+	if err := verr.Err(); err != nil {
+		return UpdateTodoParams{}, err
+	}
+	return params, nil
+}
+
+// patchString returns the string value, where null is empty if the field isn't required.
+func patchString(field string, value interface{}, required bool) (*string, error) {
+	if value == nil {
+		if required {
+			return nil, utility.NewFieldError(field, utility.CodeRequired, nil, "%s must not be null", field)
+		}
+		s := ""
+		return &s, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, invalidType(field, "string")
+	}
+	return &s, nil
+}
+
+func patchInt(field string, value interface{}) (*int, error) {
+	if value == nil {
+		return nil, utility.NewFieldError(field, utility.CodeRequired, nil, "%s must not be null", field)
+	}
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, invalidType(field, "integer")
+	}
+	i, err := strconv.Atoi(n.String())
+	if err != nil {
+		return nil, invalidType(field, "integer")
+	}
+	return &i, nil
+}
+
+// patchEnum returns the number of the enum given as the number or the name parsed by parse,
+// which returns 0 for unknown names.
+func patchEnum(field string, value interface{}, names []string, parse func(string) int) (*int, error) {
+	name, ok := value.(string)
+	if !ok {
+		return patchInt(field, value)
+	}
+	n := parse(name)
+	if n == 0 {
+		return nil, utility.NewFieldError(
+			field, utility.CodeInvalidChoice, map[string]interface{}{"choices": names},
+			"%s must be %s, but %s", field, strings.Join(names, ", "), name,
+		)
+	}
+	return &n, nil
+}
+
+func patchNullableInt(field string, value interface{}) (utility.Nullable[int], error) {
+	if value == nil {
+		return utility.Null[int](), nil
+	}
+	i, err := patchInt(field, value)
+	return utility.NullableFrom(i), err
+}
+
+// patchCustomFields returns the changed values of custom fields, where nil removes the value.
+func patchCustomFields(before, after interface{}) (map[string]interface{}, error) {
+	if after == nil {
+		after = map[string]interface{}{}
+	}
+	values, ok := after.(map[string]interface{})
+	if !ok {
+		return nil, invalidType("customFields", "object")
+	}
+	old, _ := before.(map[string]interface{})
+	changed := map[string]interface{}{}
+	for name := range old {
+		if _, ok := values[name]; !ok {
+			changed[name] = nil
+		}
+	}
+	for name, value := range values {
+		if !jsonpatch.Equal(old[name], value) {
+			if n, ok := value.(json.Number); ok {
+				// custom fields are validated as values decoded by encoding/json
+				f, err := n.Float64()
+				if err != nil {
+					return nil, invalidType("customFields."+name, "number")
+				}
+				value = f
+			}
+			changed[name] = value
+		}
+	}
+	return changed, nil
+}
+
+func invalidType(field, expected string) error {
+	return utility.NewFieldError(
+		field, utility.CodeInvalidType, map[string]interface{}{"expected": expected},
+		"%s must be %s", field, expected,
+	)
+}
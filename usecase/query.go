@@ -0,0 +1,402 @@
+package usecase
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+const (
+	queryMaxLength = 1000
+	queryMaxDepth  = 20
+)
+
+type queryTokenKind int
+
+const (
+	queryTokenEOF queryTokenKind = iota
+	queryTokenWord
+	queryTokenNot
+	queryTokenLParen
+	queryTokenRParen
+)
+
+type queryToken struct {
+	kind   queryTokenKind
+	field  string // name before ':', empty if the word has no field
+	text   string // unquoted text after ':'
+	quoted bool
+	pos    int // 1-based position in characters
+}
+
+// isKeyword reports whether the token is the operator such as OR, which is case sensitive.
+func (t queryToken) isKeyword(keyword string) bool {
+	return t.kind == queryTokenWord && !t.quoted && t.field == "" && t.text == keyword
+}
+
+// queryError returns the syntax error of the query at the position.
+func queryError(pos int, format string, args ...interface{}) error {
+	args = append(args, pos)
+	return utility.NewFieldError(
+		"q", utility.CodeInvalidFormat, map[string]interface{}{"position": pos}, format+" at position %d", args...,
+	)
+}
+
+// tokenizeQuery splits the query into tokens.
+func tokenizeQuery(s string) ([]queryToken, error) {
+	runes := []rune(s)
+	tokens := []queryToken{}
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: queryTokenLParen, pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: queryTokenRParen, pos: i + 1})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, queryToken{kind: queryTokenNot, pos: i + 1})
+			i++
+		default:
+			token := queryToken{kind: queryTokenWord, pos: i + 1}
+			var text strings.Builder
+			hasField := false
+			for i < len(runes) && !isQueryDelimiter(runes[i]) {
+				switch {
+				case runes[i] == '"':
+					start := i
+					token.quoted = true
+					i++
+					for ; i < len(runes) && runes[i] != '"'; i++ {
+						if runes[i] == '\\' && i+1 < len(runes) {
+							i++
+						}
+						text.WriteRune(runes[i])
+					}
+					if i == len(runes) {
+						return nil, queryError(start+1, "quote is not closed")
+					}
+					i++
+				case runes[i] == ':' && !token.quoted && !hasField:
+					token.field = strings.ToLower(text.String())
+					hasField = true
+					text.Reset()
+					i++
+				default:
+					text.WriteRune(runes[i])
+					i++
+				}
+			}
+			token.text = text.String()
+			tokens = append(tokens, token)
+		}
+	}
+	return append(tokens, queryToken{kind: queryTokenEOF, pos: len(runes) + 1}), nil
+}
+
+func isQueryDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')'
+}
+
+type queryParser struct {
+	tokens []queryToken
+	next   int
+	depth  int
+	userID string // user resolved from assignee:me
+}
+
+// parseQuery parses the search query of todos into its syntax tree. Empty query is parsed into nil.
+//
+// The query is terms separated by spaces, all of which must match unless joined by OR.
+//
+//	query = or
+//	or    = and { "OR" and }
+//	and   = unary { [ "AND" ] unary }
+//	unary = "-" unary | "(" or ")" | term
+//	term  = field ":" value | text
+//
+// Fields are
+//   - status:ready,doing    status is any of them, by name (not-ready, ready, doing, done) or number
+//   - priority:high,middle  priority is any of them, by name (high, middle, low) or number
+//   - assignee:me           assignee is the user, `me` or `none`
+//   - title:word            title contains the word
+//   - description:word      description contains the word
+//   - created:>2026-01-01   created time compared by =, <, <=, > or >= with RFC 3339 time or date,
+//     where a date means the whole day
+//   - updated:<=2026-01-01  updated time, same as created
+//
+// Text without field matches todos whose title or description contains it. Text containing spaces, parentheses
+// or colons is quoted with double quotes, such as "release notes".
+func parseQuery(s, userID string) (model.Query, error) {
+	if len([]rune(s)) > queryMaxLength {
+		return nil, utility.NewFieldError(
+			"q", utility.CodeTooLong, map[string]interface{}{"max": queryMaxLength},
+			"q must be at most %d characters", queryMaxLength,
+		)
+	}
+	tokens, err := tokenizeQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens, userID: userID}
+	if p.peek().kind == queryTokenEOF {
+		return nil, nil
+	}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != queryTokenEOF {
+		return nil, queryError(t.pos, "unexpected %s", describeQueryToken(t))
+	}
+	return q, nil
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.next]
+}
+
+func (p *queryParser) consume() queryToken {
+	t := p.tokens[p.next]
+	if t.kind != queryTokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *queryParser) parseOr() (model.Query, error) {
+	operands := []model.Query{}
+	for {
+		q, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, q)
+		if !p.peek().isKeyword("OR") {
+			break
+		}
+		or := p.consume()
+		if next := p.peek(); next.kind == queryTokenEOF || next.kind == queryTokenRParen || next.isKeyword("OR") {
+			return nil, queryError(or.pos, "OR needs terms on both sides")
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return model.OrQuery{Operands: operands}, nil
+}
+
+func (p *queryParser) parseAnd() (model.Query, error) {
+	operands := []model.Query{}
+	for {
+		t := p.peek()
+		if t.kind == queryTokenEOF || t.kind == queryTokenRParen || t.isKeyword("OR") {
+			break
+		}
+		if t.isKeyword("AND") {
+			p.consume()
+			next := p.peek()
+			if len(operands) == 0 ||
+				next.kind == queryTokenEOF || next.kind == queryTokenRParen || next.isKeyword("OR") {
+				return nil, queryError(t.pos, "AND needs terms on both sides")
+			}
+			continue
+		}
+		q, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, q)
+	}
+	switch len(operands) {
+	case 0:
+		t := p.peek()
+		if t.isKeyword("OR") {
+			return nil, queryError(t.pos, "OR needs terms on both sides")
+		}
+		return nil, queryError(t.pos, "term is expected before %s", describeQueryToken(t))
+	case 1:
+		return operands[0], nil
+	default:
+		return model.AndQuery{Operands: operands}, nil
+	}
+}
+
+func (p *queryParser) parseUnary() (model.Query, error) {
+	t := p.consume()
+	switch t.kind {
+	case queryTokenNot:
+		if next := p.peek(); next.kind == queryTokenNot {
+			return nil, queryError(next.pos, "- can't be repeated")
+		}
+		q, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return model.NotQuery{Operand: q}, nil
+	case queryTokenLParen:
+		p.depth++
+		if p.depth > queryMaxDepth {
+			return nil, queryError(t.pos, "parentheses are nested more than %d levels", queryMaxDepth)
+		}
+		if p.peek().kind == queryTokenRParen {
+			return nil, queryError(t.pos, "parentheses are empty")
+		}
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.consume().kind != queryTokenRParen {
+			return nil, queryError(t.pos, "parenthesis is not closed")
+		}
+		p.depth--
+		return q, nil
+	case queryTokenWord:
+		return p.parseTerm(t)
+	default:
+		return nil, queryError(t.pos, "unexpected %s", describeQueryToken(t))
+	}
+}
+
+func (p *queryParser) parseTerm(t queryToken) (model.Query, error) {
+	if t.field == "" {
+		if t.text == "" {
+			return nil, queryError(t.pos, "quoted text is empty")
+		}
+		return model.TextQuery{
+			Fields: []model.QueryField{model.QueryFieldTitle, model.QueryFieldDescription}, Text: t.text,
+		}, nil
+	}
+	if t.text == "" {
+		return nil, queryError(t.pos, "value of %s is empty", t.field)
+	}
+
+	switch t.field {
+	case "status":
+		statuses := []model.Status{}
+		for _, v := range strings.Split(t.text, ",") {
+			status := model.ParseStatus(v)
+			if status == model.StatusUnknown {
+				return nil, queryError(t.pos, "status must be not-ready, ready, doing or done, but %s", v)
+			}
+			statuses = append(statuses, status)
+		}
+		return model.StatusQuery{Statuses: statuses}, nil
+	case "priority":
+		priorities := []model.Priority{}
+		for _, v := range strings.Split(t.text, ",") {
+			priority := model.ParsePriority(v)
+			if priority == model.PriorityUnknown {
+				return nil, queryError(t.pos, "priority must be high, middle or low, but %s", v)
+			}
+			priorities = append(priorities, priority)
+		}
+		return model.PriorityQuery{Priorities: priorities}, nil
+	case "assignee":
+		switch t.text {
+		case "me":
+			return model.AssigneeQuery{AssigneeID: &p.userID}, nil
+		case "none":
+			return model.AssigneeQuery{}, nil
+		default:
+			assigneeID := t.text
+			return model.AssigneeQuery{AssigneeID: &assigneeID}, nil
+		}
+	case "title":
+		return model.TextQuery{Fields: []model.QueryField{model.QueryFieldTitle}, Text: t.text}, nil
+	case "description":
+		return model.TextQuery{Fields: []model.QueryField{model.QueryFieldDescription}, Text: t.text}, nil
+	case "created":
+		return parseTimeTerm(t, model.QueryFieldCreatedAt)
+	case "updated":
+		return parseTimeTerm(t, model.QueryFieldUpdatedAt)
+	default:
+		return nil, queryError(
+			t.pos, "unknown field %s, text containing ':' must be quoted such as \"%s:%s\"", t.field, t.field, t.text,
+		)
+	}
+}
+
+// parseTimeTerm parses the comparison of the time field, such as `>=2026-01-01`.
+// A date is converted to the comparison with the whole day.
+func parseTimeTerm(t queryToken, field model.QueryField) (model.Query, error) {
+	comparison := model.ComparisonEQ
+	value := t.text
+	for _, c := range []model.Comparison{
+		model.ComparisonLE, model.ComparisonGE, model.ComparisonLT, model.ComparisonGT, model.ComparisonEQ,
+	} {
+		if strings.HasPrefix(value, string(c)) {
+			comparison = c
+			value = strings.TrimPrefix(value, string(c))
+			break
+		}
+	}
+
+	if tm, err := time.Parse(time.RFC3339, value); err == nil {
+		return model.TimeQuery{Field: field, Comparison: comparison, Time: tm}, nil
+	}
+	start, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, queryError(t.pos, "time must be RFC 3339 or 2006-01-02 format, but %s", value)
+	}
+	end := start.AddDate(0, 0, 1)
+	switch comparison {
+	case model.ComparisonLT:
+		return model.TimeQuery{Field: field, Comparison: model.ComparisonLT, Time: start}, nil
+	case model.ComparisonLE:
+		return model.TimeQuery{Field: field, Comparison: model.ComparisonLT, Time: end}, nil
+	case model.ComparisonGT:
+		return model.TimeQuery{Field: field, Comparison: model.ComparisonGE, Time: end}, nil
+	case model.ComparisonGE:
+		return model.TimeQuery{Field: field, Comparison: model.ComparisonGE, Time: start}, nil
+	default:
+		return model.AndQuery{Operands: []model.Query{
+			model.TimeQuery{Field: field, Comparison: model.ComparisonGE, Time: start},
+			model.TimeQuery{Field: field, Comparison: model.ComparisonLT, Time: end},
+		}}, nil
+	}
+}
+
+func describeQueryToken(t queryToken) string {
+	switch t.kind {
+	case queryTokenEOF:
+		return "end of query"
+	case queryTokenLParen:
+		return "("
+	case queryTokenRParen:
+		return ")"
+	case queryTokenNot:
+		return "-"
+	default:
+		return t.text
+	}
+}
+
+// queryHasStatus reports whether the query has a condition on status.
+func queryHasStatus(q model.Query) bool {
+	switch q := q.(type) {
+	case model.StatusQuery:
+		return true
+	case model.NotQuery:
+		return queryHasStatus(q.Operand)
+	case model.AndQuery:
+		for _, operand := range q.Operands {
+			if queryHasStatus(operand) {
+				return true
+			}
+		}
+	case model.OrQuery:
+		for _, operand := range q.Operands {
+			if queryHasStatus(operand) {
+				return true
+			}
+		}
+	}
+	return false
+}
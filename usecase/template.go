@@ -0,0 +1,296 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+// placeholderPattern matches placeholders such as `{{name}}` or `{{ date }}`.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// dateVariable is the variable filled with today's date unless it's specified.
+const dateVariable = "date"
+
+type TemplateUsecase interface {
+	Create(ctx context.Context, userID, name string, items []TemplateItemParams) (*model.Template, error)
+	Get(ctx context.Context, userID, idStr string) (*model.Template, error)
+	List(ctx context.Context, userID string) ([]*model.Template, error)
+	Update(ctx context.Context, userID, idStr string, name *string, items []TemplateItemParams) (*model.Template, error)
+	Delete(ctx context.Context, userID, idStr string) error
+	Instantiate(ctx context.Context, userID, idStr string, variables map[string]string) ([]*model.Todo, error)
+}
+
+// TemplateItemParams is the fields of a todo to be created from a template.
+type TemplateItemParams struct {
+	Title           string
+	Description     string
+	Status          int
+	Priority        int
+	EstimatePoints  *int
+	EstimateMinutes *int
+}
+
+type templateUsecase struct {
+	repo        repository.TemplateRepository
+	todoUsecase TodoUsecase
+	limits      Limits
+}
+
+// NewTemplateUsecase returns TemplateUsecase. Limits should be the same as the one of todoUsecase.
+func NewTemplateUsecase(repo repository.TemplateRepository, todoUsecase TodoUsecase, limits Limits) TemplateUsecase {
+	if limits == (Limits{}) {
+		limits = DefaultLimits
+	}
+	return &templateUsecase{repo: repo, todoUsecase: todoUsecase, limits: limits}
+}
+
+func (u *templateUsecase) Create(
+	ctx context.Context, userID, name string, items []TemplateItemParams,
+) (*model.Template, error) {
+	verr := &utility.ValidationError{}
+	verr.Add(validateTemplateName(name))
+	templateItems, err := u.parseTemplateItems(items)
+	verr.Add(err)
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	newTemplate := model.Template{
+		UserID: userID,
+		Name:   name,
+		Items:  templateItems,
+	}
+	newID, err := u.repo.Create(ctx, newTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.repo.Get(ctx, userID, newID)
+}
+
+func (u *templateUsecase) Get(ctx context.Context, userID, idStr string) (*model.Template, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+
+	return u.repo.Get(ctx, userID, id)
+}
+
+func (u *templateUsecase) List(ctx context.Context, userID string) ([]*model.Template, error) {
+	return u.repo.List(ctx, userID)
+}
+
+func (u *templateUsecase) Update(
+	ctx context.Context, userID, idStr string, name *string, items []TemplateItemParams,
+) (*model.Template, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	template, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == nil && items == nil {
+		err := errors.New("no fields to be updated")
+		return nil, utility.BadRequest("", err)
+	}
+
+	verr := &utility.ValidationError{}
+	if name != nil {
+		verr.Add(validateTemplateName(*name))
+		template.Name = *name
+	}
+	// items are replaced as a whole
+	if items != nil {
+		templateItems, err := u.parseTemplateItems(items)
+		verr.Add(err)
+		template.Items = templateItems
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.Update(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return u.repo.Get(ctx, userID, id)
+}
+
+func (u *templateUsecase) Delete(ctx context.Context, userID, idStr string) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+
+	if _, err := u.repo.Get(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return u.repo.Delete(ctx, id)
+}
+
+func (u *templateUsecase) Instantiate(
+	ctx context.Context, userID, idStr string, variables map[string]string,
+) ([]*model.Todo, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	template, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := resolveTemplateVariables(template, variables, time.Now())
+	if err != nil {
+		return nil, utility.BadRequest("", err)
+	}
+
+	// all items are expanded and validated first, so that no todo is created from an invalid template
+	verr := &utility.ValidationError{}
+	params := make([]CreateTodoParams, 0, len(template.Items))
+	for i, item := range template.Items {
+		prefix := fmt.Sprintf("items[%d].", i)
+		title := expandPlaceholders(item.Title, values)
+		verr.Add(utility.WithPrefix(prefix, validateTitle(title, u.limits)))
+		description := expandPlaceholders(item.Description, values)
+		verr.Add(utility.WithPrefix(prefix, validateDescription(description, u.limits)))
+		params = append(params, CreateTodoParams{
+			Title:           title,
+			Description:     description,
+			Status:          int(item.Status),
+			Priority:        int(item.Priority),
+			EstimatePoints:  item.EstimatePoints,
+			EstimateMinutes: item.EstimateMinutes,
+		})
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	todos := make([]*model.Todo, 0, len(params))
+	for _, p := range params {
+		todo, err := u.todoUsecase.Create(ctx, userID, p)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
+
+func (u *templateUsecase) parseTemplateItems(items []TemplateItemParams) ([]model.TemplateItem, error) {
+	if len(items) < 1 || len(items) > templateItemsMax {
+		return nil, utility.NewFieldError(
+			"items", utility.CodeOutOfRange,
+			map[string]interface{}{"min": 1, "max": templateItemsMax, "actual": len(items)},
+			"number of items must be 1 to %d, but %d", templateItemsMax, len(items),
+		)
+	}
+
+	verr := &utility.ValidationError{}
+	ret := make([]model.TemplateItem, 0, len(items))
+	for i, item := range items {
+		itemErr := &utility.ValidationError{}
+		itemErr.Add(validateTitle(item.Title, u.limits))
+		itemErr.Add(validateDescription(item.Description, u.limits))
+		status, err := parseStatus(item.Status)
+		itemErr.Add(err)
+		priority, err := parsePriority(item.Priority)
+		itemErr.Add(err)
+		itemErr.Add(validateEstimatePoints(item.EstimatePoints))
+		itemErr.Add(validateEstimateMinutes(item.EstimateMinutes))
+		if len(itemErr.Errors) > 0 {
+			verr.Add(utility.WithPrefix(fmt.Sprintf("items[%d].", i), itemErr))
+			continue
+		}
+
+		ret = append(ret, model.TemplateItem{
+			Title:           item.Title,
+			Description:     item.Description,
+			Status:          status,
+			Priority:        priority,
+			EstimatePoints:  item.EstimatePoints,
+			EstimateMinutes: item.EstimateMinutes,
+		})
+	}
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return ret, nil
+}
+
+// resolveTemplateVariables checks that the variables are exactly the placeholders of the template,
+// and returns them with the default date.
+func resolveTemplateVariables(
+	template *model.Template, variables map[string]string, now time.Time,
+) (map[string]string, error) {
+	used := map[string]bool{}
+	for _, item := range template.Items {
+		for _, s := range []string{item.Title, item.Description} {
+			for _, m := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+				used[m[1]] = true
+			}
+		}
+	}
+
+	verr := &utility.ValidationError{}
+	values := make(map[string]string, len(variables)+1)
+	for name, v := range variables {
+		if !used[name] {
+			verr.Add(utility.NewFieldError(
+				"variables."+name, utility.CodeNotAllowed, nil,
+				"variable %s isn't used in template %s", name, template.Name,
+			))
+			continue
+		}
+		verr.Add(validateTemplateVariable(name, v))
+		values[name] = v
+	}
+	if _, ok := values[dateVariable]; !ok {
+		values[dateVariable] = now.Format("2006-01-02")
+	}
+
+	var missing []string
+	for name := range used {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		verr.Add(utility.NewFieldError(
+			"variables."+name, utility.CodeRequired, nil, "variable %s must be specified", name,
+		))
+	}
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return values, nil
+}
+
+// expandPlaceholders replaces the placeholders in s with the values.
+func expandPlaceholders(s string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		return values[name]
+	})
+}
@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
+
+type TimeEntryUsecase interface {
+	Start(ctx context.Context, userID, todoIDStr string) (*model.TimeEntry, error)
+	Stop(ctx context.Context, userID, todoIDStr string) (*model.TimeEntry, error)
+	List(ctx context.Context, userID, fromStr, toStr string) ([]*model.TimeEntry, error)
+	Update(ctx context.Context, userID, idStr string, startedAtStr, stoppedAtStr *string) (*model.TimeEntry, error)
+	Delete(ctx context.Context, userID, idStr string) error
+}
+
+type timeEntryUsecase struct {
+	repo     repository.TimeEntryRepository
+	todoRepo repository.TodoRepository
+}
+
+func NewTimeEntryUsecase(repo repository.TimeEntryRepository, todoRepo repository.TodoRepository) TimeEntryUsecase {
+	return &timeEntryUsecase{repo: repo, todoRepo: todoRepo}
+}
+
+func (u *timeEntryUsecase) Start(ctx context.Context, userID, todoIDStr string) (*model.TimeEntry, error) {
+	todoID, err := strconv.Atoi(todoIDStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", todoIDStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	if _, err := u.todoRepo.Get(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+
+	return startTimer(ctx, u.repo, userID, todoID, time.Now())
+}
+
+func (u *timeEntryUsecase) Stop(ctx context.Context, userID, todoIDStr string) (*model.TimeEntry, error) {
+	todoID, err := strconv.Atoi(todoIDStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", todoIDStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	if _, err := u.todoRepo.Get(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+
+	entry, err := stopTimer(ctx, u.repo, userID, todoID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, utility.Conflict("", fmt.Errorf("timer of todo with id %d is not running", todoID)).
+			WithCode(utility.ErrorCodeTimerNotRunning)
+	}
+	return entry, nil
+}
+
+func (u *timeEntryUsecase) List(ctx context.Context, userID, fromStr, toStr string) ([]*model.TimeEntry, error) {
+	verr := &utility.ValidationError{}
+	var from, to *time.Time
+	if fromStr != "" {
+		t, err := parseTimeOrDate("from", fromStr, false)
+		verr.Add(err)
+		from = &t
+	}
+	if toStr != "" {
+		t, err := parseTimeOrDate("to", toStr, true)
+		verr.Add(err)
+		to = &t
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		err := utility.NewFieldError("from", utility.CodeOutOfRange, nil, "from must be before to")
+		return nil, utility.BadRequest("", err)
+	}
+
+	return u.repo.List(ctx, userID, from, to)
+}
+
+func (u *timeEntryUsecase) Update(
+	ctx context.Context, userID, idStr string, startedAtStr, stoppedAtStr *string,
+) (*model.TimeEntry, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	entry, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if startedAtStr == nil && stoppedAtStr == nil {
+		err := errors.New("no fields to be updated")
+		return nil, utility.BadRequest("", err)
+	}
+
+	verr := &utility.ValidationError{}
+	if startedAtStr != nil {
+		startedAt, err := time.Parse(time.RFC3339, *startedAtStr)
+		if err != nil {
+			verr.Add(utility.NewFieldError(
+				"startedAt", utility.CodeInvalidFormat, map[string]interface{}{"format": "RFC 3339"},
+				"startedAt must be RFC 3339 format, but %s", *startedAtStr,
+			))
+		}
+		entry.StartedAt = startedAt
+	}
+	if stoppedAtStr != nil {
+		stoppedAt, err := time.Parse(time.RFC3339, *stoppedAtStr)
+		if err != nil {
+			verr.Add(utility.NewFieldError(
+				"stoppedAt", utility.CodeInvalidFormat, map[string]interface{}{"format": "RFC 3339"},
+				"stoppedAt must be RFC 3339 format, but %s", *stoppedAtStr,
+			))
+		}
+		entry.StoppedAt = &stoppedAt
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	if err := validateTimeEntry(entry, time.Now()); err != nil {
+		return nil, utility.BadRequest("", err)
+	}
+
+	if err := u.repo.Update(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	return u.repo.Get(ctx, userID, id)
+}
+
+func (u *timeEntryUsecase) Delete(ctx context.Context, userID, idStr string) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+
+	if _, err = u.repo.Get(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return u.repo.Delete(ctx, id)
+}
+
+// startTimer starts a timer of the todo. The running timer of the user is stopped
+// beforehand, since a user can run only one timer at a time.
+func startTimer(
+	ctx context.Context, repo repository.TimeEntryRepository, userID string, todoID int, now time.Time,
+) (*model.TimeEntry, error) {
+	running, err := repo.GetRunning(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if running != nil {
+		if running.TodoID == todoID {
+			return running, nil
+		}
+		running.StoppedAt = &now
+		if err := repo.Update(ctx, running); err != nil {
+			return nil, err
+		}
+	}
+
+	newID, err := repo.Create(ctx, model.TimeEntry{UserID: userID, TodoID: todoID, StartedAt: now})
+	if err != nil {
+		return nil, err
+	}
+	return repo.Get(ctx, userID, newID)
+}
+
+// stopTimer stops the timer of the todo. It returns nil if the timer of the todo isn't running.
+func stopTimer(
+	ctx context.Context, repo repository.TimeEntryRepository, userID string, todoID int, now time.Time,
+) (*model.TimeEntry, error) {
+	running, err := repo.GetRunning(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if running == nil || running.TodoID != todoID {
+		return nil, nil
+	}
+
+	running.StoppedAt = &now
+	if err := repo.Update(ctx, running); err != nil {
+		return nil, err
+	}
+	return repo.Get(ctx, userID, running.ID)
+}
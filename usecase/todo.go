@@ -4,146 +4,741 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/repository"
 	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/markdown"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility/search"
 )
 
 type TodoUsecase interface {
-	Create(ctx context.Context, userID string, title, description string, status, priority int) (*model.Todo, error)
+	Create(ctx context.Context, userID string, params CreateTodoParams) (*model.Todo, error)
 	Get(ctx context.Context, userID, id string) (*model.Todo, error)
-	List(ctx context.Context, userID, sortByStr, orderByStr string, includeDone bool) ([]*model.Todo, error)
-	Update(ctx context.Context, userID, idStr string, title, description *string, status, priority *int) (*model.Todo, error)
-	Delete(ctx context.Context, userID, idStr string) error
+	// List returns todos and the cursor of the next page, which is empty if there are no more todos.
+	List(ctx context.Context, userID string, params ListTodoParams) ([]*model.Todo, string, error)
+	Update(ctx context.Context, userID, idStr string, params UpdateTodoParams) (*model.Todo, error)
+	// Patch updates the todo with a patch document in the format, if it's in one of the versions of ifMatch.
+	Patch(ctx context.Context, userID, idStr string, format PatchFormat, patch []byte, ifMatch []int) (*model.Todo, error)
+	// Delete deletes the todo if it's in one of the versions of ifMatch. Nil ifMatch deletes it in any version.
+	Delete(ctx context.Context, userID, idStr string, ifMatch []int) error
+	Move(ctx context.Context, userID, idStr, beforeStr, afterStr string) (*model.Todo, error)
+	Summary(ctx context.Context, userID string) (*model.EffortReport, error)
+	Snooze(ctx context.Context, userID, idStr, until string) (*model.Todo, error)
+	ToggleTask(ctx context.Context, userID, idStr, indexStr string) (*model.Todo, error)
+	// Search returns todos matching the full-text search query, in the order of relevance.
+	Search(ctx context.Context, userID string, params SearchTodoParams) ([]*model.SearchHit, error)
 }
 
-type todoUsecase struct {
-	repo repository.TodoRepository
+// CreateTodoParams is the fields of a todo to be created.
+type CreateTodoParams struct {
+	Title           string
+	Description     string
+	Status          int
+	Priority        int
+	EstimatePoints  *int
+	EstimateMinutes *int
+	AssigneeID      *string
+	CustomFields    map[string]interface{}
+	StartAt         string // RFC 3339 or 2006-01-02, empty if the todo isn't snoozed
 }
 
-func NewTodoUsecase(repo repository.TodoRepository) TodoUsecase {
-	return &todoUsecase{repo: repo}
+// ListTodoParams is the conditions of todos to be listed.
+type ListTodoParams struct {
+	SortBy             string
+	OrderBy            string
+	Sort               string // comma separated keys such as "priority,-updatedAt", overrides SortBy and OrderBy
+	IncludeDone        bool
+	IncludeSnoozed     bool
+	Assignee           string // "me", "none" or id of the user assigned
+	Statuses           string // comma separated statuses such as "1,2" or "ready,doing", empty for any status
+	Priorities         string // comma separated priorities such as "1,2" or "high", empty for any priority
+	CreatedFrom        string // RFC 3339 or 2006-01-02, inclusive
+	CreatedTo          string // RFC 3339 (exclusive) or 2006-01-02 (inclusive)
+	UpdatedFrom        string // RFC 3339 or 2006-01-02, inclusive
+	UpdatedTo          string // RFC 3339 (exclusive) or 2006-01-02 (inclusive)
+	Title              string // substring of title, ignoring case
+	Description        string // substring of description, ignoring case
+	Query              string // search query such as `status:ready,doing -priority:low "release notes"`
+	MinEstimatePoints  *int
+	MaxEstimatePoints  *int
+	MinEstimateMinutes *int
+	MaxEstimateMinutes *int
+	CustomFields       map[string]string // values of custom fields to be matched, keyed by name
+	Limit              *int              // nil lists all todos
+	Cursor             string            // nextCursor of the previous page, empty for the first page
 }
 
-func (u *todoUsecase) Create(
-	ctx context.Context, userID string, title, description string, statusInt, priorityInt int,
-) (*model.Todo, error) {
-	if err := validateTitle(title); err != nil {
-		return nil, utility.BadRequest("", err)
+// SearchTodoParams is the conditions of full-text search of todos.
+type SearchTodoParams struct {
+	Query string // terms separated by spaces, or quoted phrases such as "release notes"
+	Limit *int   // nil lists searchLimitDefault todos
+}
+
+// UpdateTodoParams is the fields of a todo to be updated. Nil fields are left as they are.
+type UpdateTodoParams struct {
+	Title           *string
+	Description     *string
+	Status          *int
+	Priority        *int
+	EstimatePoints  utility.Nullable[int]  // null clears the estimate
+	EstimateMinutes utility.Nullable[int]  // null clears the estimate
+	AssigneeID      *string                // empty string unassigns the todo
+	CustomFields    map[string]interface{} // nil value removes the field from the todo
+	StartAt         *string                // RFC 3339 or 2006-01-02, empty string wakes the todo up
+	IfMatch         []int                  // the todo is updated only in one of the versions, nil for any version
+}
+
+// isEmpty reports whether no fields are to be updated.
+func (p UpdateTodoParams) isEmpty() bool {
+	return p.Title == nil && p.Description == nil && p.Status == nil && p.Priority == nil &&
+		!p.EstimatePoints.Set && !p.EstimateMinutes.Set && p.AssigneeID == nil && len(p.CustomFields) == 0 &&
+		p.StartAt == nil
+}
+
+// onlyStatus reports whether fields other than status are not to be updated.
+func (p UpdateTodoParams) onlyStatus() bool {
+	p.Status = nil
+	return p.isEmpty()
+}
+
+// TodoOptions is the settings of TodoUsecase.
+type TodoOptions struct {
+	// AutoTimeTracking starts a timer when a todo becomes Doing, and stops it when the todo leaves Doing.
+	AutoTimeTracking bool
+	// Limits is DefaultLimits if it's zero.
+	Limits Limits
+}
+
+type todoUsecase struct {
+	repo             repository.TodoRepository
+	timeRepo         repository.TimeEntryRepository
+	userRepo         repository.UserRepository
+	fieldRepo        repository.CustomFieldRepository
+	autoTimeTracking bool
+	limits           Limits
+}
+
+func NewTodoUsecase(
+	repo repository.TodoRepository,
+	timeRepo repository.TimeEntryRepository,
+	userRepo repository.UserRepository,
+	fieldRepo repository.CustomFieldRepository,
+	opts TodoOptions,
+) TodoUsecase {
+	limits := opts.Limits
+	if limits == (Limits{}) {
+		limits = DefaultLimits
 	}
-	if err := validateDescription(description); err != nil {
-		return nil, utility.BadRequest("", err)
+	return &todoUsecase{
+		repo:             repo,
+		timeRepo:         timeRepo,
+		userRepo:         userRepo,
+		fieldRepo:        fieldRepo,
+		autoTimeTracking: opts.AutoTimeTracking,
+		limits:           limits,
 	}
+}
 
-	status, err := parseStatus(statusInt)
-	if err != nil {
-		return nil, utility.BadRequest("", err)
+func (u *todoUsecase) Create(ctx context.Context, userID string, params CreateTodoParams) (*model.Todo, error) {
+	// all fields are validated to report every violation at once
+	verr := &utility.ValidationError{}
+	verr.Add(validateTitle(params.Title, u.limits))
+	verr.Add(validateDescription(params.Description, u.limits))
+
+	status, err := parseStatus(params.Status)
+	verr.Add(err)
+	priority, err := parsePriority(params.Priority)
+	verr.Add(err)
+
+	verr.Add(validateEstimatePoints(params.EstimatePoints))
+	verr.Add(validateEstimateMinutes(params.EstimateMinutes))
+
+	startAt, err := parseStartAt(params.StartAt)
+	verr.Add(err)
+
+	assigneeID, err := u.parseAssignee(ctx, params.AssigneeID)
+	if err := verr.Collect(err); err != nil {
+		return nil, err
 	}
-	priority, err := parsePriority(priorityInt)
-	if err != nil {
-		return nil, utility.BadRequest("", err)
+	customFields, err := u.mergeCustomFields(ctx, userID, nil, params.CustomFields)
+	if err := verr.Collect(err); err != nil {
+		return nil, err
+	}
+
+	if err := verr.Err(); err != nil {
+		return nil, err
 	}
 
 	newTodo := model.Todo{
-		Title:       title,
-		Description: description,
-		UserID:      userID,
-		Status:      status,
-		Priority:    priority,
+		Title:           params.Title,
+		Description:     params.Description,
+		UserID:          userID,
+		Status:          status,
+		Priority:        priority,
+		EstimatePoints:  params.EstimatePoints,
+		EstimateMinutes: params.EstimateMinutes,
+		AssigneeID:      assigneeID,
+		CustomFields:    customFields,
+		StartAt:         startAt,
 	}
 	newID, err := u.repo.Create(ctx, newTodo)
 	if err != nil {
 		return nil, err
 	}
 
-	return u.repo.Get(ctx, userID, newID)
+	if u.autoTimeTracking && status == model.StatusDoing {
+		if _, err := startTimer(ctx, u.timeRepo, userID, newID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return u.get(ctx, userID, newID)
 }
 
 func (u *todoUsecase) Get(ctx context.Context, userID, idStr string) (*model.Todo, error) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err)
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
 	}
 
-	return u.repo.Get(ctx, userID, id)
+	return u.get(ctx, userID, id)
 }
 
-func (u *todoUsecase) List(ctx context.Context, userID, sortByStr, orderByStr string, includeDone bool) ([]*model.Todo, error) {
-	sortBy, err := model.ToSorter(sortByStr)
-	if err != nil {
-		return nil, utility.BadRequest("", err)
+func (u *todoUsecase) List(ctx context.Context, userID string, params ListTodoParams) ([]*model.Todo, string, error) {
+	verr := &utility.ValidationError{}
+	var sort []model.SortKey
+	var err error
+	sortField := "sort"
+	if params.Sort != "" {
+		sort, err = parseSort(params.Sort)
+		verr.Add(err)
+	} else {
+		sortField = "sortby"
+		sortBy, err := model.ToSorter(params.SortBy)
+		if err != nil {
+			verr.Add(utility.NewFieldError("sortby", utility.CodeInvalidChoice, nil, "%s", err.Error()))
+		}
+		orderBy, err := model.ToOrder(params.OrderBy)
+		if err != nil {
+			verr.Add(utility.NewFieldError("orderby", utility.CodeInvalidChoice, nil, "%s", err.Error()))
+		}
+		if sortBy != "" && orderBy != "" {
+			sort = []model.SortKey{{Sorter: sortBy, Order: orderBy}}
+		}
+	}
+
+	var fields []*model.CustomField
+	sortByCustomField := false
+	for _, key := range sort {
+		if _, ok := key.Sorter.CustomField(); ok {
+			sortByCustomField = true
+		}
+	}
+	if sortByCustomField || len(params.CustomFields) > 0 {
+		fields, err = u.fieldRepo.List(ctx, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, key := range sort {
+			if name, ok := key.Sorter.CustomField(); ok && findCustomField(fields, name) == nil {
+				verr.Add(utility.NewFieldError(
+					sortField, utility.CodeNotFound, nil, "custom field %s is not found", name,
+				))
+			}
+		}
+	}
+
+	verr.Add(validateRange("estimatePoints", params.MinEstimatePoints, params.MaxEstimatePoints))
+	verr.Add(validateRange("estimateMinutes", params.MinEstimateMinutes, params.MaxEstimateMinutes))
+	filter := model.TodoFilter{
+		IncludeDone:         params.IncludeDone,
+		IncludeSnoozed:      params.IncludeSnoozed,
+		AssignedToMe:        params.Assignee == "me",
+		TitleContains:       params.Title,
+		DescriptionContains: params.Description,
+		MinEstimatePoints:   params.MinEstimatePoints,
+		MaxEstimatePoints:   params.MaxEstimatePoints,
+		MinEstimateMinutes:  params.MinEstimateMinutes,
+		MaxEstimateMinutes:  params.MaxEstimateMinutes,
+	}
+	filter.Statuses, err = parseStatuses(params.Statuses)
+	verr.Add(err)
+	if len(filter.Statuses) > 0 {
+		// statuses specified explicitly decide whether done todos are listed
+		filter.IncludeDone = true
+	}
+	filter.Query, err = parseQuery(params.Query, userID)
+	verr.Add(err)
+	if queryHasStatus(filter.Query) {
+		filter.IncludeDone = true
+	}
+	filter.Priorities, err = parsePriorities(params.Priorities)
+	verr.Add(err)
+	filter.CreatedAt, err = parseTimeRange("created", params.CreatedFrom, params.CreatedTo)
+	verr.Add(err)
+	filter.UpdatedAt, err = parseTimeRange("updated", params.UpdatedFrom, params.UpdatedTo)
+	verr.Add(err)
+	for name, s := range params.CustomFields {
+		query := fmt.Sprintf("cf[%s]", name)
+		field := findCustomField(fields, name)
+		if field == nil {
+			verr.Add(utility.NewFieldError(query, utility.CodeNotFound, nil, "custom field %s is not found", name))
+			continue
+		}
+		v, err := parseCustomFieldString(query, field, s)
+		if err != nil {
+			verr.Add(err)
+			continue
+		}
+		if filter.CustomFields == nil {
+			filter.CustomFields = model.CustomFieldValues{}
+		}
+		filter.CustomFields[name] = v
+	}
+	verr.Add(validateIntRange("limit", params.Limit, 1, listLimitMax))
+	page := model.Page{}
+	if params.Cursor != "" && len(sort) > 0 {
+		page.After, err = decodeTodoCursor(params.Cursor, sort)
+		verr.Add(err)
 	}
-	orderBy, err := model.ToOrder(orderByStr)
+	if err := verr.Err(); err != nil {
+		return nil, "", err
+	}
+	switch params.Assignee {
+	case "", "me":
+	case "none":
+		filter.AssigneeID = new(string)
+	default:
+		filter.AssigneeID = &params.Assignee
+	}
+
+	if params.Limit != nil {
+		// one more todo is fetched to know whether there is the next page
+		page.Limit = *params.Limit + 1
+	}
+	todos, err := u.repo.List(ctx, userID, sort, filter, page)
 	if err != nil {
-		return nil, utility.BadRequest("", err)
+		return nil, "", err
+	}
+	next := ""
+	if params.Limit != nil && len(todos) > *params.Limit {
+		todos = todos[:*params.Limit]
+		if next, err = encodeTodoCursor(sort, todos[len(todos)-1]); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := u.fillTrackedTime(ctx, todos...); err != nil {
+		return nil, "", err
 	}
-	return u.repo.List(ctx, userID, sortBy, orderBy, includeDone)
+	return todos, next, nil
 }
 
 func (u *todoUsecase) Update(
-	ctx context.Context, userID, idStr string, title, description *string, statusIntP, priorityIntP *int,
+	ctx context.Context, userID, idStr string, params UpdateTodoParams,
 ) (*model.Todo, error) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err)
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
 	}
 	todo, err := u.repo.Get(ctx, userID, id)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkVersion(todo, params.IfMatch); err != nil {
+		return nil, err
+	}
+	return u.update(ctx, userID, todo, params)
+}
 
-	if title == nil && description == nil && statusIntP == nil && priorityIntP == nil {
+// update updates the todo read by the user with the params. The todo is saved only if it hasn't been
+// modified since it was read.
+func (u *todoUsecase) update(
+	ctx context.Context, userID string, todo *model.Todo, params UpdateTodoParams,
+) (*model.Todo, error) {
+	id := todo.ID
+	oldStatus := todo.Status
+
+	if params.isEmpty() {
 		err := errors.New("no fields to be updated")
 		return nil, utility.BadRequest("", err)
 	}
 
-	if title != nil {
-		if err := validateTitle(*title); err != nil {
-			return nil, utility.BadRequest("", err)
+	// assignee who isn't the owner can update only status
+	if todo.UserID != userID && !params.onlyStatus() {
+		err := fmt.Errorf("todo with id %d can be updated only its status by assignee", id)
+		return nil, utility.Forbidden("", err)
+	}
+
+	verr := &utility.ValidationError{}
+	if params.Title != nil {
+		verr.Add(validateTitle(*params.Title, u.limits))
+		todo.Title = *params.Title
+	}
+
+	if params.Description != nil {
+		verr.Add(validateDescription(*params.Description, u.limits))
+		todo.Description = *params.Description
+	}
+
+	if params.Status != nil {
+		status, err := parseStatus(*params.Status)
+		verr.Add(err)
+		todo.Status = status
+	}
+	if params.Priority != nil {
+		priority, err := parsePriority(*params.Priority)
+		verr.Add(err)
+		todo.Priority = priority
+	}
+
+	if params.EstimatePoints.Set {
+		verr.Add(validateEstimatePoints(params.EstimatePoints.Ptr()))
+		todo.EstimatePoints = params.EstimatePoints.Ptr()
+	}
+	if params.EstimateMinutes.Set {
+		verr.Add(validateEstimateMinutes(params.EstimateMinutes.Ptr()))
+		todo.EstimateMinutes = params.EstimateMinutes.Ptr()
+	}
+
+	if params.StartAt != nil {
+		startAt, err := parseStartAt(*params.StartAt)
+		verr.Add(err)
+		todo.StartAt = startAt
+	}
+
+	if params.AssigneeID != nil {
+		assigneeID, err := u.parseAssignee(ctx, params.AssigneeID)
+		if err := verr.Collect(err); err != nil {
+			return nil, err
 		}
-		todo.Title = *title
+		todo.AssigneeID = assigneeID
 	}
 
-	if description != nil {
-		if err := validateDescription(*description); err != nil {
-			return nil, utility.BadRequest("", err)
+	if len(params.CustomFields) > 0 {
+		customFields, err := u.mergeCustomFields(ctx, todo.UserID, todo.CustomFields, params.CustomFields)
+		if err := verr.Collect(err); err != nil {
+			return nil, err
 		}
-		todo.Description = *description
+		todo.CustomFields = customFields
 	}
 
-	if statusIntP != nil {
-		status, err := parseStatus(*statusIntP)
-		if err != nil {
-			return nil, utility.BadRequest("", err)
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.Update(ctx, todo); err != nil {
+		return nil, staleVersion(err, params.IfMatch)
+	}
+
+	if u.autoTimeTracking && oldStatus != todo.Status {
+		if todo.Status == model.StatusDoing {
+			if _, err := startTimer(ctx, u.timeRepo, userID, id, time.Now()); err != nil {
+				return nil, err
+			}
+		} else if oldStatus == model.StatusDoing {
+			if _, err := stopTimer(ctx, u.timeRepo, userID, id, time.Now()); err != nil {
+				return nil, err
+			}
 		}
-		todo.Status = status
 	}
-	if priorityIntP != nil {
-		priority, err := parsePriority(*priorityIntP)
-		if err != nil {
-			return nil, utility.BadRequest("", err)
+
+	return u.get(ctx, userID, id)
+}
+
+func (u *todoUsecase) Delete(ctx context.Context, userID, idStr string, ifMatch []int) error {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+
+	todo, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if todo.UserID != userID {
+		err := fmt.Errorf("todo with id %d can be deleted only by its owner", id)
+		return utility.Forbidden("", err)
+	}
+	if err := checkVersion(todo, ifMatch); err != nil {
+		return err
+	}
+
+	return staleVersion(u.repo.Delete(ctx, id, todo.Version), ifMatch)
+}
+
+// checkVersion returns PreconditionFailed unless the todo is in one of the versions of ifMatch.
+// Nil ifMatch matches any version.
+func checkVersion(todo *model.Todo, ifMatch []int) error {
+	if ifMatch == nil {
+		return nil
+	}
+	for _, version := range ifMatch {
+		if todo.Version == version {
+			return nil
 		}
-		todo.Priority = priority
+	}
+	return utility.PreconditionFailed("", fmt.Errorf("todo with id %d is in version %d", todo.ID, todo.Version)).
+		WithCode(utility.ErrorCodeVersionMismatch)
+}
+
+// staleVersion converts Conflict returned by the repository, which means the todo has been modified
+// since it was read, to PreconditionFailed if the client gave ifMatch, since the todo doesn't match it anymore.
+func staleVersion(err error, ifMatch []int) error {
+	var httpErr *utility.HTTPError
+	if ifMatch != nil && errors.As(err, &httpErr) && httpErr.ErrCode() == http.StatusConflict {
+		return utility.PreconditionFailed("", err).WithCode(utility.ErrorCodeVersionMismatch)
+	}
+	return err
+}
+
+func (u *todoUsecase) Move(ctx context.Context, userID, idStr, beforeStr, afterStr string) (*model.Todo, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+
+	if (beforeStr == "") == (afterStr == "") {
+		err := errors.New("either before or after must be specified")
+		return nil, utility.BadRequest("", err)
+	}
+	anchorStr, placement := beforeStr, model.PlaceBefore
+	if afterStr != "" {
+		anchorStr, placement = afterStr, model.PlaceAfter
+	}
+	anchorID, err := strconv.Atoi(anchorStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", anchorStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	if anchorID == id {
+		err := errors.New("todo can't be moved relative to itself")
+		return nil, utility.BadRequest("", err)
+	}
+
+	todo, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.UserID != userID {
+		err := fmt.Errorf("todo with id %d can be moved only by its owner", id)
+		return nil, utility.Forbidden("", err)
+	}
+
+	if err := u.repo.Move(ctx, userID, id, anchorID, placement); err != nil {
+		return nil, err
+	}
+
+	return u.get(ctx, userID, id)
+}
+
+func (u *todoUsecase) Search(
+	ctx context.Context, userID string, params SearchTodoParams,
+) ([]*model.SearchHit, error) {
+	verr := &utility.ValidationError{}
+	terms, err := parseSearchTerms(params.Query)
+	verr.Add(err)
+	verr.Add(validateIntRange("limit", params.Limit, 1, listLimitMax))
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	limit := searchLimitDefault
+	if params.Limit != nil {
+		limit = *params.Limit
+	}
+
+	// todos are ranked by the repository, so that it loads only the ones returned
+	todos, err := u.repo.Search(ctx, userID, terms, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.fillTrackedTime(ctx, todos...); err != nil {
+		return nil, err
+	}
+	hits := make([]*model.SearchHit, 0, len(todos))
+	for _, todo := range todos {
+		hits = append(hits, &model.SearchHit{
+			Todo:    todo,
+			Score:   search.Score(todo.Title, todo.Description, terms),
+			Title:   search.Highlight(todo.Title, terms),
+			Snippet: search.Snippet(todo.Description, terms),
+		})
+	}
+	return hits, nil
+}
+
+func (u *todoUsecase) Summary(ctx context.Context, userID string) (*model.EffortReport, error) {
+	summaries, err := u.repo.Summarize(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewEffortReport(summaries), nil
+}
+
+func (u *todoUsecase) Snooze(ctx context.Context, userID, idStr, until string) (*model.Todo, error) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+
+	now := time.Now()
+	startAt, err := parseSnoozeUntil(until, now)
+	if err != nil {
+		return nil, utility.BadRequest("", err)
+	}
+	if err := validateSnoozeUntil(startAt, now); err != nil {
+		return nil, utility.BadRequest("", err)
+	}
+
+	todo, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.UserID != userID {
+		err := fmt.Errorf("todo with id %d can be snoozed only by its owner", id)
+		return nil, utility.Forbidden("", err)
 	}
 
+	todo.StartAt = &startAt
 	if err := u.repo.Update(ctx, todo); err != nil {
 		return nil, err
 	}
 
-	return u.repo.Get(ctx, userID, id)
+	return u.get(ctx, userID, id)
 }
 
-func (u *todoUsecase) Delete(ctx context.Context, userID, idStr string) error {
+// ToggleTask checks or unchecks a task list item in the description.
+// Assignee can toggle tasks as well as the owner, since it's the progress of the todo like its status.
+func (u *todoUsecase) ToggleTask(ctx context.Context, userID, idStr, indexStr string) (*model.Todo, error) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		return utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err)
+		return nil, utility.BadRequest(fmt.Sprintf("id must be integer, but %s", idStr), err).
+			WithCode(utility.ErrorCodeInvalidID)
+	}
+	index, err := strconv.Atoi(indexStr)
+	if err != nil {
+		return nil, utility.BadRequest(fmt.Sprintf("index must be integer, but %s", indexStr), err)
 	}
 
-	if _, err = u.repo.Get(ctx, userID, id); err != nil {
-		return err
+	todo, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	description, err := markdown.ToggleTask(todo.Description, index)
+	if err != nil {
+		err := utility.NewFieldError("index", utility.CodeOutOfRange, nil, "%s", err.Error())
+		return nil, utility.BadRequest("", err)
+	}
+	todo.Description = description
+	if err := u.repo.Update(ctx, todo); err != nil {
+		return nil, err
+	}
+
+	return u.get(ctx, userID, id)
+}
+
+// parseStartAt parses the start time of a todo. Empty string means the todo isn't snoozed.
+func parseStartAt(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := parseTimeOrDate("startAt", s, false)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// parseAssignee checks that the assignee exists. Empty assignee means nobody.
+func (u *todoUsecase) parseAssignee(ctx context.Context, assigneeID *string) (*string, error) {
+	if assigneeID == nil || *assigneeID == "" {
+		return nil, nil
+	}
+	exists, err := u.userRepo.Exists(ctx, *assigneeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		err := utility.NewFieldError("assigneeId", utility.CodeNotFound, nil, "assignee %s is not found", *assigneeID)
+		return nil, utility.BadRequest("", err)
+	}
+	return assigneeID, nil
+}
+
+// mergeCustomFields validates the values against the custom fields of the owner and applies them to a copy of current.
+func (u *todoUsecase) mergeCustomFields(
+	ctx context.Context, ownerID string, current model.CustomFieldValues, values map[string]interface{},
+) (model.CustomFieldValues, error) {
+	merged := make(model.CustomFieldValues, len(current)+len(values))
+	for name, v := range current {
+		merged[name] = v
+	}
+	if len(values) == 0 {
+		return merged, nil
 	}
 
-	return u.repo.Delete(ctx, id)
+	fields, err := u.fieldRepo.List(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	verr := &utility.ValidationError{}
+	for name, v := range values {
+		field := findCustomField(fields, name)
+		if field == nil {
+			verr.Add(utility.NewFieldError(
+				"customFields."+name, utility.CodeNotFound, nil, "custom field %s is not found", name,
+			))
+			continue
+		}
+		if v == nil {
+			delete(merged, name)
+			continue
+		}
+		value, err := parseCustomFieldValue(field, v)
+		if err != nil {
+			verr.Add(err)
+			continue
+		}
+		merged[name] = value
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// get returns the todo with its tracked time.
+func (u *todoUsecase) get(ctx context.Context, userID string, id int) (*model.Todo, error) {
+	todo, err := u.repo.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.fillTrackedTime(ctx, todo); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+func (u *todoUsecase) fillTrackedTime(ctx context.Context, todos ...*model.Todo) error {
+	ids := make([]int, 0, len(todos))
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+	}
+	durations, err := u.timeRepo.TotalDurations(ctx, ids, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, todo := range todos {
+		todo.TrackedTime = durations[todo.ID]
+	}
+	return nil
 }
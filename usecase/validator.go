@@ -1,24 +1,203 @@
 package usecase
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/seiro-ogasawara/golang-todo-api-sample/domain/model"
+	"github.com/seiro-ogasawara/golang-todo-api-sample/utility"
+)
 
 const (
-	titleMaxLength       = 50
-	descriptionMaxLength = 500
+	estimatePointsMax  = 1000
+	estimateMinutesMax = 60 * 24 * 365
+
+	customFieldOptionsMax      = 50
+	customFieldOptionMaxLength = 50
+	customFieldTextMaxLength   = 200
+
+	templateNameMaxLength     = 50
+	templateItemsMax          = 50
+	templateVariableMaxLength = 100
+
+	snoozeMax = 366 * 24 * time.Hour
+
+	listLimitMax = 100
+
+	searchLimitDefault  = 20
+	searchTermsMax      = 10
+	searchTermMaxLength = 100
+
+	batchOperationsMax = 500
 )
 
-func validateTitle(title string) error {
-	length := len(title)
-	if length < 1 || length > titleMaxLength {
-		return fmt.Errorf("length of title must be < %d, but %d", titleMaxLength, length)
+// Limits is the configurable limits of todos. Lengths are counted in Unicode characters.
+type Limits struct {
+	TitleMaxLength       int
+	DescriptionMaxLength int
+}
+
+// DefaultLimits is the limits used unless configured.
+var DefaultLimits = Limits{
+	TitleMaxLength:       50,
+	DescriptionMaxLength: 500,
+}
+
+// customFieldNamePattern keeps names usable as query parameters such as `cf[name]=value`.
+var customFieldNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,29}$`)
+
+// validateLength checks that the value isn't empty if required, and isn't longer than max characters.
+func validateLength(field, value string, required bool, max int) error {
+	length := utf8.RuneCountInString(value)
+	if required && length == 0 {
+		return utility.NewFieldError(field, utility.CodeRequired, nil, "%s must not be empty", field)
+	}
+	if length > max {
+		return utility.NewFieldError(
+			field, utility.CodeTooLong, map[string]interface{}{"max": max, "actual": length},
+			"length of %s must be <= %d, but %d", field, max, length,
+		)
+	}
+	return nil
+}
+
+func validateTitle(title string, limits Limits) error {
+	return validateLength("title", title, true, limits.TitleMaxLength)
+}
+
+func validateDescription(desc string, limits Limits) error {
+	return validateLength("description", desc, false, limits.DescriptionMaxLength)
+}
+
+// validateIntRange checks that the value is min to max if it's specified.
+func validateIntRange(field string, value *int, min, max int) error {
+	if value != nil && (*value < min || *value > max) {
+		return utility.NewFieldError(
+			field, utility.CodeOutOfRange, map[string]interface{}{"min": min, "max": max, "actual": *value},
+			"%s must be %d to %d, but %d", field, min, max, *value,
+		)
+	}
+	return nil
+}
+
+func validateEstimatePoints(points *int) error {
+	return validateIntRange("estimatePoints", points, 0, estimatePointsMax)
+}
+
+func validateEstimateMinutes(minutes *int) error {
+	return validateIntRange("estimateMinutes", minutes, 0, estimateMinutesMax)
+}
+
+// validateRange checks the range of query such as `minEstimatePoints` and `maxEstimatePoints`.
+func validateRange(name string, min, max *int) error {
+	if min != nil && max != nil && *min > *max {
+		field := "min" + strings.ToUpper(name[:1]) + name[1:]
+		return utility.NewFieldError(
+			field, utility.CodeOutOfRange, map[string]interface{}{"max": *max, "actual": *min},
+			"minimum of %s must not be greater than maximum, but %d > %d", name, *min, *max,
+		)
+	}
+	return nil
+}
+
+func validateCustomFieldName(name string) error {
+	if !customFieldNamePattern.MatchString(name) {
+		return utility.NewFieldError(
+			"name", utility.CodeInvalidFormat, map[string]interface{}{"format": customFieldNamePattern.String()},
+			"name of custom field must start with a letter and consist of up to 30 letters, digits or _, but %s", name,
+		)
 	}
 	return nil
 }
 
-func validateDescription(desc string) error {
-	length := len(desc)
-	if length > descriptionMaxLength {
-		return fmt.Errorf("length of description must be < %d, but %d", descriptionMaxLength, length)
+func validateCustomFieldOptions(fieldType model.CustomFieldType, options []string) error {
+	if fieldType != model.CustomFieldEnum {
+		if len(options) > 0 {
+			return utility.NewFieldError(
+				"options", utility.CodeNotAllowed, nil,
+				"options can be specified only for enum, but type is %s", fieldType,
+			)
+		}
+		return nil
+	}
+
+	if len(options) < 1 || len(options) > customFieldOptionsMax {
+		return utility.NewFieldError(
+			"options", utility.CodeOutOfRange,
+			map[string]interface{}{"min": 1, "max": customFieldOptionsMax, "actual": len(options)},
+			"number of options must be 1 to %d, but %d", customFieldOptionsMax, len(options),
+		)
+	}
+	verr := &utility.ValidationError{}
+	seen := make(map[string]bool, len(options))
+	for i, o := range options {
+		field := fmt.Sprintf("options[%d]", i)
+		verr.Add(validateLength(field, o, true, customFieldOptionMaxLength))
+		if seen[o] {
+			verr.Add(utility.NewFieldError(
+				field, utility.CodeDuplicated, map[string]interface{}{"value": o},
+				"options must be unique, but %s is duplicated", o,
+			))
+		}
+		seen[o] = true
+	}
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func validateTemplateName(name string) error {
+	return validateLength("name", name, true, templateNameMaxLength)
+}
+
+func validateTemplateVariable(name, value string) error {
+	return validateLength("variables."+name, value, false, templateVariableMaxLength)
+}
+
+func validateSnoozeUntil(until, now time.Time) error {
+	if !until.After(now) {
+		return utility.NewFieldError(
+			"until", utility.CodeOutOfRange, map[string]interface{}{"min": now.Format(time.RFC3339)},
+			"snooze time must be in the future, but %s", until.Format(time.RFC3339),
+		)
+	}
+	if until.Sub(now) > snoozeMax {
+		max := now.Add(snoozeMax)
+		return utility.NewFieldError(
+			"until", utility.CodeOutOfRange, map[string]interface{}{"max": max.Format(time.RFC3339)},
+			"snooze time must be within %d days, but %s", snoozeMax/(24*time.Hour), until.Format(time.RFC3339),
+		)
+	}
+	return nil
+}
+
+func validateTimeEntry(entry *model.TimeEntry, now time.Time) error {
+	verr := &utility.ValidationError{}
+	if entry.StartedAt.After(now) {
+		verr.Add(utility.NewFieldError(
+			"startedAt", utility.CodeOutOfRange, map[string]interface{}{"max": now.Format(time.RFC3339)},
+			"startedAt must not be in the future, but %s", entry.StartedAt.Format(time.RFC3339),
+		))
+	}
+	if entry.StoppedAt != nil && entry.StoppedAt.After(now) {
+		verr.Add(utility.NewFieldError(
+			"stoppedAt", utility.CodeOutOfRange, map[string]interface{}{"max": now.Format(time.RFC3339)},
+			"stoppedAt must not be in the future, but %s", entry.StoppedAt.Format(time.RFC3339),
+		))
+	}
+	if entry.StoppedAt != nil && !entry.StoppedAt.After(entry.StartedAt) {
+		verr.Add(utility.NewFieldError(
+			"stoppedAt", utility.CodeOutOfRange, map[string]interface{}{"min": entry.StartedAt.Format(time.RFC3339)},
+			"stoppedAt must be after startedAt %s, but %s",
+			entry.StartedAt.Format(time.RFC3339), entry.StoppedAt.Format(time.RFC3339),
+		))
+	}
+	if len(verr.Errors) > 0 {
+		return verr
 	}
 	return nil
 }
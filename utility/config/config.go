@@ -1 +1,53 @@
 package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config is the application settings read from environment variables.
+type Config struct {
+	// AutoTimeTracking starts a timer when a todo becomes Doing, and stops it when the todo leaves Doing.
+	AutoTimeTracking bool `envconfig:"AUTO_TIME_TRACKING" default:"false"`
+	// limits of todos, counted in Unicode characters.
+	TitleMaxLength       int `envconfig:"TITLE_MAX_LENGTH" default:"50"`
+	DescriptionMaxLength int `envconfig:"DESCRIPTION_MAX_LENGTH" default:"500"`
+	// IdempotencyKeyTTL is how long responses of requests with Idempotency-Key header are replayed.
+	IdempotencyKeyTTL time.Duration `envconfig:"IDEMPOTENCY_KEY_TTL" default:"24h"`
+	// the unprefixed paths such as `/todos` are deprecated aliases of `/v1`, which are removed at the sunset.
+	UnversionedAPIDeprecatedAt time.Time `envconfig:"UNVERSIONED_API_DEPRECATED_AT" default:"2026-10-18T00:00:00Z"`
+	UnversionedAPISunset       time.Time `envconfig:"UNVERSIONED_API_SUNSET" default:"2027-04-30T00:00:00Z"`
+	// OpenAPIValidation rejects requests which don't match the OpenAPI document served at `/openapi.json`.
+	OpenAPIValidation bool `envconfig:"OPENAPI_VALIDATION" default:"false"`
+	// GRPCAddress is the address such as `:9090` which the gRPC server listens on, next to the REST API.
+	// The gRPC server isn't started if it's empty.
+	GRPCAddress string `envconfig:"GRPC_ADDRESS" default:""`
+	// GRPCWatchInterval is how often WatchTodos looks for the changes of todos.
+	GRPCWatchInterval time.Duration `envconfig:"GRPC_WATCH_INTERVAL" default:"1s"`
+	// GRPCMaxWatchesPerUser is how many streams of WatchTodos a user can open at once.
+	GRPCMaxWatchesPerUser int `envconfig:"GRPC_MAX_WATCHES_PER_USER" default:"5"`
+}
+
+func GetConfigFromEnvironmentVariables() (*Config, error) {
+	var cfg Config
+	if err := envconfig.Process("", &cfg); err != nil {
+		return nil, err
+	}
+	// todos can't be created with the limits which aren't positive
+	if cfg.TitleMaxLength <= 0 {
+		return nil, fmt.Errorf("TITLE_MAX_LENGTH must be positive, but %d", cfg.TitleMaxLength)
+	}
+	if cfg.DescriptionMaxLength <= 0 {
+		return nil, fmt.Errorf("DESCRIPTION_MAX_LENGTH must be positive, but %d", cfg.DescriptionMaxLength)
+	}
+	// a ticker of WatchTodos panics with the interval which isn't positive
+	if cfg.GRPCWatchInterval <= 0 {
+		return nil, fmt.Errorf("GRPC_WATCH_INTERVAL must be positive, but %v", cfg.GRPCWatchInterval)
+	}
+	if cfg.GRPCMaxWatchesPerUser <= 0 {
+		return nil, fmt.Errorf("GRPC_MAX_WATCHES_PER_USER must be positive, but %d", cfg.GRPCMaxWatchesPerUser)
+	}
+	return &cfg, nil
+}
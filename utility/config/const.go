@@ -2,3 +2,4 @@ package config
 
 const UserIDKey = "UserID"
 const DBKey = "DB"
+const APIVersionKey = "APIVersion"
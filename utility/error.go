@@ -1,11 +1,15 @@
 package utility
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+)
 
 type HTTPError struct {
 	errCode int
 	message string
 	cause   error
+	code    ErrorCode
 }
 
 func (e *HTTPError) Error() string {
@@ -19,18 +23,47 @@ func (e *HTTPError) ErrCode() int {
 	return e.errCode
 }
 
+// Code returns the code given by WithCode. Otherwise it's the code of the HTTPError wrapped in the cause, or
+// the default code of the status.
+func (e *HTTPError) Code() ErrorCode {
+	if e.code != "" {
+		return e.code
+	}
+	var cause *HTTPError
+	if errors.As(e.cause, &cause) && cause.ErrCode() == e.errCode {
+		return cause.Code()
+	}
+	if e.errCode == http.StatusBadRequest && FieldErrors(e.cause) != nil {
+		return ErrorCodeValidationFailed
+	}
+	if code, ok := defaultErrorCodes[e.errCode]; ok {
+		return code
+	}
+	return ErrorCodeInternal
+}
+
+// WithCode sets the code specific to the case, such as ErrorCodeTodoNotFound.
+func (e *HTTPError) WithCode(code ErrorCode) *HTTPError {
+	e.code = code
+	return e
+}
+
 func (e *HTTPError) Unwrap() error {
 	return e.cause
 }
 
 func NewHTTPError(errCode int, message string, cause error) *HTTPError {
-	return &HTTPError{errCode, message, cause}
+	return &HTTPError{errCode: errCode, message: message, cause: cause}
 }
 
 func BadRequest(message string, cause error) *HTTPError {
 	return NewHTTPError(http.StatusBadRequest, message, cause)
 }
 
+func Forbidden(message string, cause error) *HTTPError {
+	return NewHTTPError(http.StatusForbidden, message, cause)
+}
+
 func NotFound(message string, cause error) *HTTPError {
 	return NewHTTPError(http.StatusNotFound, message, cause)
 }
@@ -39,6 +72,10 @@ func Conflict(message string, cause error) *HTTPError {
 	return NewHTTPError(http.StatusConflict, message, cause)
 }
 
+func PreconditionFailed(message string, cause error) *HTTPError {
+	return NewHTTPError(http.StatusPreconditionFailed, message, cause)
+}
+
 func InternalServerError(message string, cause error) *HTTPError {
 	return NewHTTPError(http.StatusInternalServerError, message, cause)
 }
@@ -0,0 +1,116 @@
+package utility
+
+import (
+	"net/http"
+	"sort"
+)
+
+// ErrorCode is the stable machine-readable code of an error, which clients can handle instead of messages.
+// Codes are never changed once released, and the type of problem+json responses is made from them.
+type ErrorCode string
+
+// codes of errors specific to the cases. Errors without them have the default code of the status.
+const (
+	ErrorCodeInvalidID                ErrorCode = "invalid_id"              // the id in the path isn't an integer
+	ErrorCodeInvalidPatch             ErrorCode = "invalid_patch"           // the patch document is malformed
+	ErrorCodeInvalidIdempotencyKey    ErrorCode = "invalid_idempotency_key" // Idempotency-Key is too long
+	ErrorCodeTodoNotFound             ErrorCode = "todo_not_found"          // also the todo isn't visible to the user
+	ErrorCodeTimeEntryNotFound        ErrorCode = "time_entry_not_found"
+	ErrorCodeCustomFieldNotFound      ErrorCode = "custom_field_not_found"
+	ErrorCodeTemplateNotFound         ErrorCode = "template_not_found"
+	ErrorCodeUserNotFound             ErrorCode = "user_not_found"
+	ErrorCodeConcurrentModification   ErrorCode = "concurrent_modification" // retry after reading the resource again
+	ErrorCodePatchConflict            ErrorCode = "patch_conflict"          // a test operation of JSON Patch failed
+	ErrorCodeTimerAlreadyRunning      ErrorCode = "timer_already_running"   // stop the running timer first
+	ErrorCodeTimerNotRunning          ErrorCode = "timer_not_running"
+	ErrorCodeCustomFieldAlreadyExists ErrorCode = "custom_field_already_exists"
+	ErrorCodeUserAlreadyExists        ErrorCode = "user_already_exists"
+	ErrorCodeVersionMismatch          ErrorCode = "version_mismatch"            // If-Match doesn't match the current version
+	ErrorCodeIdempotencyKeyReused     ErrorCode = "idempotency_key_reused"      // the key is used for another request
+	ErrorCodeIdempotencyKeyInProgress ErrorCode = "idempotency_key_in_progress" // the first request is still processed
+	ErrorCodeTooManyWatches           ErrorCode = "too_many_watches"            // close a stream of WatchTodos first
+)
+
+// default codes of statuses.
+const (
+	ErrorCodeInvalidRequest       ErrorCode = "invalid_request"   // 400 which isn't caused by fields
+	ErrorCodeValidationFailed     ErrorCode = "validation_failed" // 400 with the violations of fields
+	ErrorCodeUnauthorized         ErrorCode = "unauthorized"
+	ErrorCodeForbidden            ErrorCode = "forbidden"
+	ErrorCodeNotFound             ErrorCode = "not_found"
+	ErrorCodeConflict             ErrorCode = "conflict"
+	ErrorCodePreconditionFailed   ErrorCode = "precondition_failed"
+	ErrorCodeUnsupportedMediaType ErrorCode = "unsupported_media_type"
+	ErrorCodeUnprocessableEntity  ErrorCode = "unprocessable_entity"
+	ErrorCodeInternal             ErrorCode = "internal_error"
+)
+
+type errorCodeInfo struct {
+	status int
+	title  string
+}
+
+// errorCodes is the catalogue of the codes with their statuses and titles, which don't change by occurrence.
+var errorCodes = map[ErrorCode]errorCodeInfo{
+	ErrorCodeInvalidID:                {http.StatusBadRequest, "ID is not an integer"},
+	ErrorCodeInvalidPatch:             {http.StatusBadRequest, "Patch document is malformed"},
+	ErrorCodeInvalidIdempotencyKey:    {http.StatusBadRequest, "Idempotency-Key is invalid"},
+	ErrorCodeTodoNotFound:             {http.StatusNotFound, "Todo is not found"},
+	ErrorCodeTimeEntryNotFound:        {http.StatusNotFound, "Time entry is not found"},
+	ErrorCodeCustomFieldNotFound:      {http.StatusNotFound, "Custom field is not found"},
+	ErrorCodeTemplateNotFound:         {http.StatusNotFound, "Template is not found"},
+	ErrorCodeUserNotFound:             {http.StatusNotFound, "User is not found"},
+	ErrorCodeConcurrentModification:   {http.StatusConflict, "Resource has been modified by another request"},
+	ErrorCodePatchConflict:            {http.StatusConflict, "Patch can't be applied to the todo"},
+	ErrorCodeTimerAlreadyRunning:      {http.StatusConflict, "Timer is already running"},
+	ErrorCodeTimerNotRunning:          {http.StatusConflict, "Timer is not running"},
+	ErrorCodeCustomFieldAlreadyExists: {http.StatusConflict, "Custom field already exists"},
+	ErrorCodeUserAlreadyExists:        {http.StatusConflict, "User already exists"},
+	ErrorCodeVersionMismatch:          {http.StatusPreconditionFailed, "Version doesn't match If-Match"},
+	ErrorCodeIdempotencyKeyReused:     {http.StatusUnprocessableEntity, "Idempotency-Key is used for another request"},
+	ErrorCodeIdempotencyKeyInProgress: {http.StatusConflict, "Request with the Idempotency-Key is being processed"},
+	ErrorCodeTooManyWatches:           {http.StatusTooManyRequests, "User watches todos by too many streams"},
+
+	ErrorCodeInvalidRequest:       {http.StatusBadRequest, "Request is invalid"},
+	ErrorCodeValidationFailed:     {http.StatusBadRequest, "Request has invalid fields"},
+	ErrorCodeUnauthorized:         {http.StatusUnauthorized, "Authentication failed"},
+	ErrorCodeForbidden:            {http.StatusForbidden, "Operation is not allowed for the user"},
+	ErrorCodeNotFound:             {http.StatusNotFound, "Resource is not found"},
+	ErrorCodeConflict:             {http.StatusConflict, "Request conflicts with the current state"},
+	ErrorCodePreconditionFailed:   {http.StatusPreconditionFailed, "Precondition failed"},
+	ErrorCodeUnsupportedMediaType: {http.StatusUnsupportedMediaType, "Content-Type is not supported"},
+	ErrorCodeUnprocessableEntity:  {http.StatusUnprocessableEntity, "Request can't be processed"},
+	ErrorCodeInternal:             {http.StatusInternalServerError, "Internal server error"},
+}
+
+// defaultErrorCodes are the codes of errors without specific codes.
+var defaultErrorCodes = map[int]ErrorCode{
+	http.StatusBadRequest:           ErrorCodeInvalidRequest,
+	http.StatusUnauthorized:         ErrorCodeUnauthorized,
+	http.StatusForbidden:            ErrorCodeForbidden,
+	http.StatusNotFound:             ErrorCodeNotFound,
+	http.StatusConflict:             ErrorCodeConflict,
+	http.StatusPreconditionFailed:   ErrorCodePreconditionFailed,
+	http.StatusUnsupportedMediaType: ErrorCodeUnsupportedMediaType,
+	http.StatusUnprocessableEntity:  ErrorCodeUnprocessableEntity,
+}
+
+// Title returns the summary of the code, or empty string if the code isn't in the catalogue.
+func (c ErrorCode) Title() string {
+	return errorCodes[c].title
+}
+
+// Status returns the status code of the errors with the code, or 0 if the code isn't in the catalogue.
+func (c ErrorCode) Status() int {
+	return errorCodes[c].status
+}
+
+// ErrorCodes returns all codes in the catalogue in alphabetical order.
+func ErrorCodes() []ErrorCode {
+	ret := make([]ErrorCode, 0, len(errorCodes))
+	for code := range errorCodes {
+		ret = append(ret, code)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
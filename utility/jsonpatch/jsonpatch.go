@@ -0,0 +1,397 @@
+// Package jsonpatch applies JSON Merge Patch (RFC 7396) and JSON Patch (RFC 6902) to JSON documents.
+// Documents are values decoded by Decode, that is, maps, slices, strings, json.Number, bools and nil.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error is the reason why a patch can't be applied.
+type Error struct {
+	Index   int // index of the operation of JSON Patch, -1 for the whole patch
+	Message string
+	// Conflict reports whether the patch is well-formed, but doesn't fit the document,
+	// such as a failed test or a path which doesn't exist.
+	Conflict bool
+}
+
+func (e *Error) Error() string {
+	if e.Index < 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("operation %d: %s", e.Index, e.Message)
+}
+
+// Decode decodes a JSON document, keeping numbers as json.Number so that integers aren't rounded.
+func Decode(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid character after top-level value")
+	}
+	return v, nil
+}
+
+// MergePatch returns the document patched by JSON Merge Patch. Null in the patch removes the member,
+// objects are merged recursively, and any other value replaces the target. The document isn't modified.
+func MergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return clone(patch)
+	}
+	target, ok := doc.(map[string]interface{})
+	merged := make(map[string]interface{}, len(target)+len(p))
+	if ok {
+		for k, v := range target {
+			merged[k] = v
+		}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = MergePatch(merged[k], v)
+	}
+	return merged
+}
+
+// operation is an operation of JSON Patch.
+type operation struct {
+	op       string
+	path     []string
+	from     []string
+	value    interface{}
+	hasValue bool
+}
+
+// Apply returns the document patched by JSON Patch, whose operations are applied in order.
+// Either all operations are applied or none, and the document isn't modified.
+func Apply(doc, patch interface{}) (interface{}, error) {
+	ops, err := parseOperations(patch)
+	if err != nil {
+		return nil, err
+	}
+	doc = clone(doc)
+	for i, op := range ops {
+		doc, err = op.apply(doc)
+		if err != nil {
+			return nil, &Error{Index: i, Message: err.Error(), Conflict: true}
+		}
+	}
+	return doc, nil
+}
+
+// parseOperations checks that the patch is an array of operations with the members required by their op.
+func parseOperations(patch interface{}) ([]operation, error) {
+	items, ok := patch.([]interface{})
+	if !ok {
+		return nil, &Error{Index: -1, Message: "JSON Patch must be an array of operations"}
+	}
+	ops := make([]operation, 0, len(items))
+	for i, item := range items {
+		malformed := func(format string, args ...interface{}) error {
+			return &Error{Index: i, Message: fmt.Sprintf(format, args...)}
+		}
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, malformed("operation must be an object")
+		}
+		op := operation{}
+		if op.op, ok = m["op"].(string); !ok {
+			return nil, malformed("op must be a string")
+		}
+		path, ok := m["path"].(string)
+		if !ok {
+			return nil, malformed("path must be a string")
+		}
+		var err error
+		if op.path, err = parsePointer(path); err != nil {
+			return nil, malformed("path %s", err)
+		}
+		op.value, op.hasValue = m["value"]
+
+		switch op.op {
+		case "add", "replace", "test":
+			if !op.hasValue {
+				return nil, malformed("value is required for %s", op.op)
+			}
+		case "remove":
+		case "move", "copy":
+			from, ok := m["from"].(string)
+			if !ok {
+				return nil, malformed("from must be a string for %s", op.op)
+			}
+			if op.from, err = parsePointer(from); err != nil {
+				return nil, malformed("from %s", err)
+			}
+			if op.op == "move" && len(op.from) < len(op.path) && hasPrefix(op.path, op.from) {
+				return nil, malformed("can't move %s into its child %s", from, path)
+			}
+		default:
+			return nil, malformed("op must be one of add, remove, replace, move, copy or test, but %s", op.op)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	switch op.op {
+	case "add":
+		return add(doc, op.path, clone(op.value))
+	case "remove":
+		doc, _, err := remove(doc, op.path)
+		return doc, err
+	case "replace":
+		if _, err := get(doc, op.path); err != nil {
+			return nil, err
+		}
+		if len(op.path) > 0 {
+			doc, _, _ = remove(doc, op.path)
+		}
+		return add(doc, op.path, clone(op.value))
+	case "move":
+		doc, value, err := remove(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, value)
+	case "copy":
+		value, err := get(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, clone(value))
+	case "test":
+		value, err := get(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !Equal(value, op.value) {
+			return nil, fmt.Errorf("test failed, %s isn't the value", formatPointer(op.path))
+		}
+		return doc, nil
+	}
+	return nil, fmt.Errorf("unknown op %s", op.op)
+}
+
+// parsePointer parses JSON Pointer (RFC 6901) such as `/customFields/sprint` into reference tokens.
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("must be empty or start with /, but %s", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func formatPointer(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(t))
+	}
+	return b.String()
+}
+
+func hasPrefix(tokens, prefix []string) bool {
+	for i, t := range prefix {
+		if tokens[i] != t {
+			return false
+		}
+	}
+	return true
+}
+
+// index parses the token as an index of the array, where `-` is the end of the array if it's allowed.
+func index(token string, length int, end bool) (int, error) {
+	if token == "-" && end {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%s isn't an index of array", token)
+	}
+	max := length - 1
+	if end {
+		max = length
+	}
+	if i > max {
+		return 0, fmt.Errorf("index %d is out of the array of length %d", i, length)
+	}
+	return i, nil
+}
+
+// get returns the value referenced by the pointer.
+func get(doc interface{}, tokens []string) (interface{}, error) {
+	for i, t := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, fmt.Errorf("%s doesn't exist", formatPointer(tokens[:i+1]))
+			}
+			doc = v
+		case []interface{}:
+			idx, err := index(t, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[idx]
+		default:
+			return nil, fmt.Errorf("%s isn't an object or an array", formatPointer(tokens[:i]))
+		}
+	}
+	return doc, nil
+}
+
+// update replaces the container which holds the last token of the pointer with the result of fn,
+// and returns the new document.
+func update(
+	doc interface{}, tokens []string, fn func(parent interface{}, token string) (interface{}, error),
+) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	child, err := get(doc, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = update(child, tokens[1:], fn); err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[tokens[0]] = child
+	case []interface{}:
+		idx, _ := index(tokens[0], len(node), false)
+		node[idx] = child
+	}
+	return doc, nil
+}
+
+func add(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return update(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			idx, err := index(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[idx+1:], node[idx:])
+			node[idx] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("%s isn't an object or an array", formatPointer(tokens[:len(tokens)-1]))
+	})
+}
+
+// remove returns the document without the value referenced by the pointer, and the removed value.
+func remove(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("the whole document can't be removed")
+	}
+	var removed interface{}
+	doc, err := update(doc, tokens, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%s doesn't exist", formatPointer(tokens))
+			}
+			removed = v
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			idx, err := index(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[idx]
+			return append(node[:idx:idx], node[idx+1:]...), nil
+		}
+		return nil, fmt.Errorf("%s isn't an object or an array", formatPointer(tokens[:len(tokens)-1]))
+	})
+	return doc, removed, err
+}
+
+// Equal reports whether the values are equal as JSON, where numbers are compared by their values
+// and the order of members of objects doesn't matter.
+func Equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !Equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		f, err1 := x.Float64()
+		g, err2 := y.Float64()
+		return err1 == nil && err2 == nil && f == g
+	}
+	return a == b
+}
+
+// clone returns a deep copy of the value, so that patches don't modify the original document.
+func clone(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, v := range x {
+			m[k] = clone(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for i, v := range x {
+			s[i] = clone(v)
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// md renders CommonMark with GitHub Flavored Markdown extensions such as task lists.
+// Raw HTML in the source isn't rendered.
+var md = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// policy removes anything that can run scripts from the rendered HTML, but keeps checkboxes of task lists.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	return p
+}()
+
+// RenderHTML renders the Markdown source to sanitized HTML.
+func RenderHTML(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		return "", fmt.Errorf("can't render markdown: %w", err)
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// ToggleTask checks or unchecks the task at the index, counted from 0 in the order of appearance,
+// and returns the updated source.
+func ToggleTask(source string, index int) (string, error) {
+	src := []byte(source)
+	offsets := taskOffsets(src)
+	if index < 0 || index >= len(offsets) {
+		return "", fmt.Errorf("index of task must be 0 to %d, but %d", len(offsets)-1, index)
+	}
+
+	// offset points to the marker between brackets such as `[ ]` or `[x]`
+	offset := offsets[index]
+	if src[offset] == ' ' {
+		src[offset] = 'x'
+	} else {
+		src[offset] = ' '
+	}
+	return string(src), nil
+}
+
+// taskOffsets returns the offsets of the markers of task list items in the source.
+func taskOffsets(src []byte) []int {
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	var offsets []int
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Kind() != extast.KindTaskCheckBox {
+			return ast.WalkContinue, nil
+		}
+		// the checkbox is at the beginning of the first line of the block containing it
+		lines := n.Parent().Lines()
+		if lines.Len() > 0 {
+			start := lines.At(0).Start
+			if start+2 < len(src) && src[start] == '[' && src[start+2] == ']' {
+				offsets = append(offsets, start+1)
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return offsets
+}
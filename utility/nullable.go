@@ -0,0 +1,59 @@
+package utility
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Nullable is a field of a request which distinguishes null from absence, such as a field of JSON Merge Patch
+// (RFC 7396), where null clears the field while an absent field is left as it is.
+type Nullable[T any] struct {
+	Set   bool // the field is specified, including null
+	Valid bool // the field isn't null
+	Value T
+}
+
+// NullableFrom returns Nullable which is unset if p is nil, or p's value otherwise.
+func NullableFrom[T any](p *T) Nullable[T] {
+	if p == nil {
+		return Nullable[T]{}
+	}
+	return Nullable[T]{Set: true, Valid: true, Value: *p}
+}
+
+// Null returns Nullable which is set to null.
+func Null[T any]() Nullable[T] {
+	return Nullable[T]{Set: true}
+}
+
+// Ptr returns the pointer to the value, or nil if the field is null or unset.
+func (n Nullable[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.Value
+	return &v
+}
+
+// UnmarshalJSON is called only if the field is present, so that absent fields are left unset.
+func (n *Nullable[T]) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		n.Valid = false
+		var zero T
+		n.Value = zero
+		return nil
+	}
+	if err := json.Unmarshal(b, &n.Value); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Value)
+}
@@ -0,0 +1,138 @@
+// Package search provides matching, ranking and highlighting of full-text search of todos.
+// Text is matched by substrings ignoring case, so that text without spaces between words such as Japanese is
+// searched as well as English.
+package search
+
+import (
+	"html"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// TitleWeight is how many times an occurrence in title is more relevant than in description.
+	TitleWeight = 3.0
+	// snippetContext is the number of characters around the first match in a snippet.
+	snippetContext = 40
+
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	ellipsis       = "…"
+)
+
+// Fold returns the characters of the text in lower case. The number of characters is kept,
+// so that the index of a character in folded text is also the index in the original text.
+func Fold(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// Contains reports whether the text contains the term ignoring case.
+func Contains(text, term string) bool {
+	return strings.Contains(string(Fold(text)), string(Fold(term)))
+}
+
+// Score returns the relevance of a todo to the terms. It's higher when the terms occur more,
+// while repeated occurrences count less than the first.
+func Score(title, description string, terms []string) float64 {
+	score := 0.0
+	for _, term := range terms {
+		score += TitleWeight*math.Log1p(float64(len(matches(Fold(title), Fold(term))))) +
+			math.Log1p(float64(len(matches(Fold(description), Fold(term)))))
+	}
+	return score
+}
+
+// Highlight returns the HTML escaped text whose matches with the terms are enclosed in <mark>.
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return highlight(runes, marks(runes, terms), 0, len(runes))
+}
+
+// Snippet returns the part of the text around the first match with the terms, highlighted as Highlight.
+// The beginning of the text is returned if nothing matches.
+func Snippet(text string, terms []string) string {
+	runes := []rune(text)
+	marked := marks(runes, terms)
+	first := 0
+	for i, m := range marked {
+		if m {
+			first = i
+			break
+		}
+	}
+	start := first - snippetContext
+	if start < 0 {
+		start = 0
+	}
+	end := first + snippetContext
+	if end < start+2*snippetContext {
+		end = start + 2*snippetContext
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	snippet := highlight(runes, marked, start, end)
+	if start > 0 {
+		snippet = ellipsis + snippet
+	}
+	if end < len(runes) {
+		snippet += ellipsis
+	}
+	return snippet
+}
+
+// matches returns the indexes where the term occurs in the text, without overlaps.
+func matches(text, term []rune) []int {
+	if len(term) == 0 {
+		return nil
+	}
+	ret := []int{}
+	for i := 0; i+len(term) <= len(text); {
+		if string(text[i:i+len(term)]) == string(term) {
+			ret = append(ret, i)
+			i += len(term)
+			continue
+		}
+		i++
+	}
+	return ret
+}
+
+// marks returns whether each character of the text is a part of matches with the terms.
+func marks(text []rune, terms []string) []bool {
+	folded := Fold(string(text))
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		t := Fold(term)
+		for _, i := range matches(folded, t) {
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+		}
+	}
+	return marked
+}
+
+// highlight returns the HTML escaped text from start to end, whose marked characters are enclosed in <mark>.
+func highlight(text []rune, marked []bool, start, end int) string {
+	var b strings.Builder
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString(highlightStart + html.EscapeString(string(text[i:j])) + highlightEnd)
+		} else {
+			b.WriteString(html.EscapeString(string(text[i:j])))
+		}
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package utility
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// codes of FieldError, which clients can use to show their own messages.
+const (
+	CodeRequired      = "required"       // the field is missing or empty
+	CodeTooLong       = "too_long"       // params: max, actual
+	CodeTooShort      = "too_short"      // params: min, actual
+	CodeOutOfRange    = "out_of_range"   // params: min and/or max, actual
+	CodeInvalidFormat = "invalid_format" // params: format
+	CodeInvalidChoice = "invalid_choice" // params: choices
+	CodeInvalidType   = "invalid_type"   // params: expected
+	CodeDuplicated    = "duplicated"     // params: value
+	CodeNotFound      = "not_found"      // the referenced resource doesn't exist
+	CodeNotAllowed    = "not_allowed"    // the field can't be specified in this case
+	CodeInvalid       = "invalid"        // the value is invalid for other reasons
+)
+
+// FieldError is a violation of a field of a request.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+	Params  map[string]interface{}
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// NewFieldError returns a FieldError whose message is formatted with format and args.
+func NewFieldError(field, code string, params map[string]interface{}, format string, args ...interface{}) *FieldError {
+	return &FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...), Params: params}
+}
+
+// ValidationError collects all violations of a request instead of stopping at the first one.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		messages = append(messages, fe.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add adds the violations in err. Nil err is ignored, and err which isn't caused by validation is added as
+// a violation without field.
+func (e *ValidationError) Add(err error) {
+	if err := e.Collect(err); err != nil {
+		e.Errors = append(e.Errors, &FieldError{Code: CodeInvalid, Message: err.Error()})
+	}
+}
+
+// Collect adds the violations in err, and returns err as it is if it isn't caused by validation,
+// such as a failure of a repository.
+func (e *ValidationError) Collect(err error) error {
+	if err == nil {
+		return nil
+	}
+	errs := FieldErrors(err)
+	if errs == nil {
+		return err
+	}
+	e.Errors = append(e.Errors, errs...)
+	return nil
+}
+
+// Err returns BadRequest with the violations, or nil if there is no violation.
+func (e *ValidationError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return BadRequest("", e)
+}
+
+// FieldErrors returns the violations in err, or nil if err isn't caused by validation.
+func FieldErrors(err error) []*FieldError {
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return ve.Errors
+	}
+	var fe *FieldError
+	if errors.As(err, &fe) {
+		return []*FieldError{fe}
+	}
+	return nil
+}
+
+// WithPrefix returns err whose fields are prefixed, such as `items[0].` for the fields of an element.
+func WithPrefix(prefix string, err error) error {
+	errs := FieldErrors(err)
+	if errs == nil {
+		return err
+	}
+	ret := &ValidationError{}
+	for _, fe := range errs {
+		prefixed := *fe
+		prefixed.Field = prefix + fe.Field
+		ret.Errors = append(ret.Errors, &prefixed)
+	}
+	return ret
+}